@@ -0,0 +1,63 @@
+// audit-verify проверяет целостность цепочки журнала аудита и сообщает о первом нарушении.
+// Использует конфигурацию сервера: файл, переменные окружения и флаги.
+//
+//	audit-verify -config config/config.yaml
+//
+// Код завершения: 0 - цепочка цела, 1 - цепочка нарушена, 2 - ошибка проверки.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/config"
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+func main() {
+	// В stderr пишутся только предупреждения и ошибки, результат выводится в stdout
+	log := logger.New(logger.Options{
+		Service: "audit-verify",
+		Format:  logger.FormatText,
+		Output:  os.Stderr,
+		Levels:  logger.NewLevels(slog.LevelWarn),
+	})
+
+	cfg, err := config.NewLoader(config.ComponentServer, os.Args[1:]).Load()
+	if err != nil {
+		log.Error("Ошибка загрузки конфигурации", logger.Err(err))
+		os.Exit(2)
+	}
+
+	db, err := database.Connect(cfg.Database.DSN(), log)
+	if err != nil {
+		log.Error("Ошибка подключения к базе данных", logger.Err(err))
+		os.Exit(2)
+	}
+	defer func() {
+		_ = database.Close(db)
+	}()
+
+	result, err := audit.Verify(context.Background(), repository.NewAuditRepository(db), []byte(cfg.Audit.CheckpointKey))
+	if err != nil {
+		log.Error("Ошибка проверки журнала аудита", logger.Err(err))
+		os.Exit(2)
+	}
+
+	fmt.Printf("Проверено записей: %d (ID %d-%d), контрольных точек: %d\n",
+		result.EventsChecked, result.FirstEventID, result.LastEventID, result.CheckpointsChecked)
+	if !result.SignaturesVerified {
+		fmt.Println("Ключ audit.checkpoint_key не задан: подписи контрольных точек не проверены")
+	}
+	if !result.Valid {
+		fmt.Printf("Цепочка нарушена на записи %d: %s\n", result.BrokenEventID, result.Reason)
+		_ = database.Close(db)
+		os.Exit(1)
+	}
+	fmt.Println("Цепочка журнала аудита цела")
+}
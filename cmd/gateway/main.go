@@ -2,382 +2,204 @@ package main
 
 import (
 	"context"
-	"encoding/json"
-	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
-	"strconv"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
-	"github.com/gorilla/mux"
-	"github.com/sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
 
+	"k8s-go-grpc-react/internal/accesslog"
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/config"
+	"k8s-go-grpc-react/internal/gateway"
 	"k8s-go-grpc-react/internal/logger"
-	pb "k8s-go-grpc-react/proto"
+	"k8s-go-grpc-react/internal/loglevel"
+	"k8s-go-grpc-react/internal/ratelimit"
+	"k8s-go-grpc-react/internal/requestid"
+	"k8s-go-grpc-react/internal/shutdown"
+	"k8s-go-grpc-react/internal/tlsutil"
 )
 
-const (
-	httpPort = ":8081"
-)
-
-var (
-	// Настройка логера с Graylog
-	log = logger.SetupGraylogLogger("http-gateway")
-)
+// dialGRPCServer создает соединение с gRPC сервером с учетом настроек TLS/mTLS
+func dialGRPCServer(cfg *config.Config, log *slog.Logger) (*grpc.ClientConn, error) {
+	grpcAddr := cfg.Gateway.GRPCServerAddr
+	log = logger.Component(log, "gateway-init")
 
-func getGRPCServerAddr() string {
-	if addr := os.Getenv("GRPC_SERVER_ADDR"); addr != "" {
-		return addr
-	}
-	return "localhost:8080"
-}
-
-type Gateway struct {
-	client pb.UserServiceClient
-}
+	log.Info("Подключение к gRPC серверу", "grpc_addr", grpcAddr)
 
-func NewGateway() (*Gateway, error) {
-	grpcAddr := getGRPCServerAddr()
-
-	log.WithFields(logrus.Fields{
-		"component": "gateway-init",
-		"grpc_addr": grpcAddr,
-	}).Info("Подключение к gRPC серверу")
-
-	conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	// Настраиваем TLS/mTLS подключения к gRPC серверу
+	creds, reloader, err := tlsutil.ClientCredentials(tlsutil.ClientOptions{
+		Enabled:    cfg.GRPCClientTLS.Enabled,
+		CAFile:     cfg.GRPCClientTLS.CAFile,
+		CertFile:   cfg.GRPCClientTLS.CertFile,
+		KeyFile:    cfg.GRPCClientTLS.KeyFile,
+		ServerName: cfg.GRPCClientTLS.ServerName,
+	})
 	if err != nil {
-		log.WithError(err).Error("Не удалось подключиться к gRPC серверу")
-		return nil, fmt.Errorf("не удалось подключиться к gRPC серверу: %v", err)
-	}
-
-	client := pb.NewUserServiceClient(conn)
-
-	log.WithField("component", "gateway-init").Info("Успешно подключились к gRPC серверу")
-
-	return &Gateway{client: client}, nil
-}
-
-func (g *Gateway) enableCORS(w http.ResponseWriter) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		return nil, err
+	}
+	if reloader != nil {
+		tlsLog := logger.Component(log, "gateway-tls")
+		go reloader.Watch(context.Background(), tlsutil.DefaultReloadInterval,
+			func() {
+				tlsLog.Info("Клиентские сертификаты перезагружены")
+			},
+			func(err error) {
+				tlsLog.Error("Ошибка перезагрузки клиентских сертификатов", logger.Err(err))
+			},
+		)
+	}
+
+	log.Info("Параметры транспорта gRPC",
+		"tls", cfg.GRPCClientTLS.Enabled,
+		"mtls", cfg.GRPCClientTLS.CertFile != "",
+	)
+
+	return grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(creds))
 }
 
-func (g *Gateway) handleOptions(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
-	w.WriteHeader(http.StatusOK)
-}
-
-func (g *Gateway) extractToken(r *http.Request) string {
-	authHeader := r.Header.Get("Authorization")
-	if authHeader == "" {
-		return ""
-	}
+func main() {
+	// До загрузки конфигурации логи пишутся только в stdout
+	log := logger.New(logger.Options{Service: "http-gateway"})
 
-	parts := strings.Split(authHeader, " ")
-	if len(parts) != 2 || parts[0] != "Bearer" {
-		return ""
+	loader := config.NewLoader(config.ComponentGateway, os.Args[1:])
+	cfg, err := loader.Load()
+	if err != nil {
+		logger.Fatal(log, "Ошибка загрузки конфигурации", logger.Err(err))
 	}
 
-	return parts[1]
-}
-
-func (g *Gateway) createAuthContext(ctx context.Context, token string) context.Context {
-	if token == "" {
-		return ctx
+	// Уровни компонентов меняются при перезагрузке конфигурации,
+	// персональные данные и секреты скрываются до отправки в Graylog
+	levels := logger.NewLevels(slog.LevelInfo)
+	if err := levels.Update(cfg.Logging.Level, cfg.Logging.Levels); err != nil {
+		logger.Fatal(log, "Ошибка настройки уровней логирования", logger.Err(err))
 	}
-
-	md := metadata.Pairs("authorization", "Bearer "+token)
-	return metadata.NewOutgoingContext(ctx, md)
-}
-
-func (g *Gateway) getUser(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
-
-	vars := mux.Vars(r)
-	idStr := vars["id"]
-
-	log.WithFields(logrus.Fields{
-		"component": "get-user",
-		"user_id":   idStr,
-		"method":    r.Method,
-		"path":      r.URL.Path,
-	}).Info("Запрос получения пользователя")
-
-	id, err := strconv.Atoi(idStr)
+	redactor, err := logger.NewRedactor(cfg.Logging.Redaction.HashKey, cfg.Logging.Redaction.Rules())
 	if err != nil {
-		log.WithError(err).WithField("user_id", idStr).Error("Неверный ID пользователя")
-		http.Error(w, "Неверный ID пользователя", http.StatusBadRequest)
-		return
+		logger.Fatal(log, "Ошибка настройки скрытия данных в логах", logger.Err(err))
 	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Добавляем токен в контекст
-	token := g.extractToken(r)
-	ctx = g.createAuthContext(ctx, token)
-
-	resp, err := g.client.GetUser(ctx, &pb.GetUserRequest{Id: int32(id)})
+	graylogOpts, err := cfg.Logging.GraylogOptions()
 	if err != nil {
-		log.WithError(err).WithField("user_id", id).Error("Ошибка получения пользователя")
-		http.Error(w, fmt.Sprintf("Ошибка получения пользователя: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	log.WithFields(logrus.Fields{
-		"component": "get-user",
-		"user_id":   id,
-		"user_name": resp.User.Name,
-	}).Info("Пользователь успешно получен")
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.WithError(err).Error("Ошибка кодирования ответа")
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
-	}
-}
-
-func (g *Gateway) createUser(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
-
-	var req struct {
-		Name  string `json:"name"`
-		Email string `json:"email"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.WithError(err).Error("Неверный JSON в запросе создания пользователя")
-		http.Error(w, "Неверный JSON", http.StatusBadRequest)
-		return
-	}
-
-	log.WithFields(logrus.Fields{
-		"component":  "create-user",
-		"user_name":  req.Name,
-		"user_email": req.Email,
-	}).Info("Запрос создания пользователя")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Добавляем токен в контекст
-	token := g.extractToken(r)
-	ctx = g.createAuthContext(ctx, token)
-
-	resp, err := g.client.CreateUser(ctx, &pb.CreateUserRequest{
-		Name:  req.Name,
-		Email: req.Email,
+		logger.Fatal(log, "Ошибка настройки отправки логов в Graylog", logger.Err(err))
+	}
+	log, graylogWriter := logger.Setup(logger.Options{
+		Service:  "http-gateway",
+		Format:   cfg.Logging.Format,
+		Levels:   levels,
+		Redactor: redactor,
+	}, graylogOpts)
+
+	// Изменяемые параметры применяются при изменении файла конфигурации и по SIGHUP
+	watcher := config.NewWatcher(loader, cfg, log)
+	prometheus.MustRegister(watcher.Collectors()...)
+	prometheus.MustRegister(graylogWriter.Collectors()...)
+
+	limiter := ratelimit.New(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+	accessLog := accesslog.New(log, cfg.AccessLog.Options())
+	watcher.Subscribe(func(cfg *config.Config) {
+		if err := levels.Update(cfg.Logging.Level, cfg.Logging.Levels); err != nil {
+			log.Error("Уровни логирования не применены", logger.Err(err))
+		}
+		limiter.Update(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+		accessLog.Update(cfg.AccessLog.Options())
+		if err := redactor.Update(cfg.Logging.Redaction.Rules()); err != nil {
+			log.Error("Правила скрытия данных в логах не применены", logger.Err(err))
+		}
 	})
-	if err != nil {
-		log.WithError(err).WithFields(logrus.Fields{
-			"user_name":  req.Name,
-			"user_email": req.Email,
-		}).Error("Ошибка создания пользователя")
-		http.Error(w, fmt.Sprintf("Ошибка создания пользователя: %v", err), http.StatusInternalServerError)
-		return
-	}
+	go watcher.Run(context.Background(), config.DefaultWatchInterval)
 
-	log.WithFields(logrus.Fields{
-		"component": "create-user",
-		"user_id":   resp.User.Id,
-		"user_name": resp.User.Name,
-	}).Info("Пользователь успешно создан")
+	logger.Component(log, "startup").Info("Запуск HTTP Gateway...", "environment", cfg.Environment)
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.WithError(err).Error("Ошибка кодирования ответа")
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	conn, err := dialGRPCServer(cfg, log)
+	if err != nil {
+		logger.Fatal(log, "Не удалось подключиться к gRPC серверу", logger.Err(err))
 	}
-}
 
-func (g *Gateway) listUsers(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
-
-	log.WithFields(logrus.Fields{
-		"component": "list-users",
-		"method":    r.Method,
-		"path":      r.URL.Path,
-	}).Info("Запрос списка пользователей")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	// Добавляем токен в контекст
-	token := g.extractToken(r)
-	ctx = g.createAuthContext(ctx, token)
-
-	resp, err := g.client.ListUsers(ctx, &pb.Empty{})
+	// Тот же gateway, что встроен в cmd/server, маршруты генерируются из proto
+	gw, err := gateway.New(context.Background(), conn, gateway.OptionsFromConfig(watcher))
 	if err != nil {
-		log.WithError(err).Error("Ошибка получения списка пользователей")
-		http.Error(w, fmt.Sprintf("Ошибка получения списка пользователей: %v", err), http.StatusInternalServerError)
-		return
+		logger.Fatal(log, "Не удалось создать gateway", logger.Err(err))
 	}
 
-	log.WithFields(logrus.Fields{
-		"component":   "list-users",
-		"users_count": len(resp.Users),
-	}).Info("Список пользователей успешно получен")
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.WithError(err).Error("Ошибка кодирования ответа")
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
-	}
-}
+	// Готовность снимается первой при остановке, чтобы балансировщики перестали слать трафик
+	readiness := shutdown.NewReadiness()
 
-func (g *Gateway) register(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
+	mux := http.NewServeMux()
+	mux.Handle(gateway.Prefix+"/", limiter.Middleware(gw))
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.Handle("/ready", readiness.Handler())
 
-	var req struct {
-		Name     string `json:"name"`
-		Email    string `json:"email"`
-		Password string `json:"password"`
+	// Уровни логирования меняются администратором без перезапуска. Токены проверяются
+	// общим с сервером секретом JWT; с секретом по умолчанию в production API отключен.
+	if cfg.IsProduction() && cfg.JWT.Secret == config.DefaultJWTSecret {
+		log.Warn("API уровней логирования отключен: не задан секрет JWT", "path", loglevel.Path)
+	} else {
+		authMiddleware := auth.NewAuthMiddleware(auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.Expiration()), log)
+		mux.Handle(loglevel.Path, authMiddleware.RequireAuth(authMiddleware.RequireRole("admin")(loglevel.New(levels, log))))
 	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.WithError(err).Error("Неверный JSON в запросе регистрации")
-		http.Error(w, "Неверный JSON", http.StatusBadRequest)
-		return
-	}
-
-	log.WithFields(logrus.Fields{
-		"component":  "register",
-		"user_name":  req.Name,
-		"user_email": req.Email,
-	}).Info("Запрос регистрации пользователя")
-
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
-
-	resp, err := g.client.Register(ctx, &pb.RegisterRequest{
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: req.Password,
+	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte("OK")); err != nil {
+			log.Error("Ошибка записи ответа health check", logger.Err(err))
+		}
 	})
-	if err != nil {
-		log.WithError(err).WithFields(logrus.Fields{
-			"user_name":  req.Name,
-			"user_email": req.Email,
-		}).Error("Ошибка регистрации пользователя")
-		http.Error(w, fmt.Sprintf("Ошибка регистрации: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	log.WithFields(logrus.Fields{
-		"component": "register",
-		"user_id":   resp.User.Id,
-		"user_name": resp.User.Name,
-	}).Info("Пользователь успешно зарегистрирован")
 
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.WithError(err).Error("Ошибка кодирования ответа")
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	// Идентификатор запроса назначается до журнала и ограничения частоты,
+	// чтобы он был в записях журнала и в ответах 429
+	server := &http.Server{
+		Addr:    ":" + cfg.Listen.HTTPPort,
+		Handler: requestid.Middleware(accessLog.Middleware(mux)),
 	}
-}
 
-func (g *Gateway) login(w http.ResponseWriter, r *http.Request) {
-	g.enableCORS(w)
+	go func() {
+		serverLog := logger.Component(log, "http-server")
+		serverLog.Info("HTTP Gateway запущен и готов к приему запросов", "port", cfg.Listen.HTTPPort)
 
-	var req struct {
-		Email    string `json:"email"`
-		Password string `json:"password"`
-	}
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal(serverLog, "HTTP сервер остановлен", logger.Err(err))
+		}
+	}()
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		log.WithError(err).Error("Неверный JSON в запросе входа")
-		http.Error(w, "Неверный JSON", http.StatusBadRequest)
-		return
-	}
+	// Ожидаем сигнал завершения
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	sig := <-sigChan
 
-	log.WithFields(logrus.Fields{
-		"component":  "login",
-		"user_email": req.Email,
-	}).Info("Запрос входа пользователя")
+	shutdownLog := logger.Component(log, "shutdown").With("signal", sig.String())
+	shutdownLog.Info("Получен сигнал завершения, останавливаем HTTP Gateway")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+	// 1. Снимаем готовность и ждем, пока под исключат из балансировки
+	readiness.SetNotReady()
+	shutdownLog.Info("Готовность снята, ожидание перед остановкой сервера", "drain_delay", cfg.Shutdown.DrainDelay().String())
+	time.Sleep(cfg.Shutdown.DrainDelay())
 
-	resp, err := g.client.Login(ctx, &pb.LoginRequest{
-		Email:    req.Email,
-		Password: req.Password,
-	})
-	if err != nil {
-		log.WithError(err).WithFields(logrus.Fields{
-			"user_email": req.Email,
-		}).Error("Ошибка входа пользователя")
-		http.Error(w, "Неверные учетные данные", http.StatusUnauthorized)
-		return
+	// 2. Останавливаем HTTP сервер, дожидаясь активных запросов
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout())
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		shutdownLog.Warn("HTTP сервер не успел завершить запросы", logger.Err(err))
+	} else {
+		shutdownLog.Info("HTTP сервер остановлен")
 	}
 
-	log.WithFields(logrus.Fields{
-		"component": "login",
-		"user_id":   resp.User.Id,
-		"user_name": resp.User.Name,
-	}).Info("Пользователь успешно вошел в систему")
-
-	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(resp); err != nil {
-		log.WithError(err).Error("Ошибка кодирования ответа")
-		http.Error(w, "Ошибка сервера", http.StatusInternalServerError)
+	// 3. Закрываем соединение с gRPC сервером
+	if err := conn.Close(); err != nil {
+		shutdownLog.Error("Ошибка закрытия соединения с gRPC сервером", logger.Err(err))
+	} else {
+		shutdownLog.Info("Соединение с gRPC сервером закрыто")
 	}
-}
 
-func main() {
-	log.WithField("component", "startup").Info("Запуск HTTP Gateway...")
+	shutdownLog.Info("HTTP Gateway остановлен")
 
-	gateway, err := NewGateway()
-	if err != nil {
-		log.WithError(err).Fatal("Не удалось создать gateway")
+	// 4. Отправляем накопленные логи в Graylog, записи после этого выводятся только в stdout
+	logCtx, cancelLog := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout())
+	defer cancelLog()
+	if err := graylogWriter.Close(logCtx); err != nil {
+		shutdownLog.Warn("Не все логи отправлены в Graylog", logger.Err(err))
 	}
-
-	r := mux.NewRouter()
-
-	// API routes
-	api := r.PathPrefix("/api").Subrouter()
-
-	// v1 API routes
-	v1 := api.PathPrefix("/v1").Subrouter()
-
-	// Auth routes
-	v1.HandleFunc("/auth/register", gateway.register).Methods("POST")
-	v1.HandleFunc("/auth/login", gateway.login).Methods("POST")
-
-	// User routes
-	v1.HandleFunc("/users/{id:[0-9]+}", gateway.getUser).Methods("GET")
-	v1.HandleFunc("/users", gateway.createUser).Methods("POST")
-	v1.HandleFunc("/users", gateway.listUsers).Methods("GET")
-
-	// Legacy API routes (for backward compatibility)
-	api.HandleFunc("/users/{id:[0-9]+}", gateway.getUser).Methods("GET")
-	api.HandleFunc("/users", gateway.createUser).Methods("POST")
-	api.HandleFunc("/users", gateway.listUsers).Methods("GET")
-
-	// CORS preflight
-	api.PathPrefix("/").HandlerFunc(gateway.handleOptions).Methods("OPTIONS")
-
-	// Health check
-	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		log.WithFields(logrus.Fields{
-			"component": "health-check",
-			"method":    r.Method,
-			"path":      r.URL.Path,
-		}).Info("Health check запрос")
-		w.WriteHeader(http.StatusOK)
-		if _, err := w.Write([]byte("OK")); err != nil {
-			log.WithError(err).Error("Ошибка записи ответа health check")
-		}
-	})
-
-	log.WithFields(logrus.Fields{
-		"component": "http-server",
-		"port":      httpPort,
-	}).Info("HTTP Gateway запущен и готов к приему запросов")
-
-	log.WithError(http.ListenAndServe(httpPort, r)).Fatal("HTTP сервер остановлен")
 }
@@ -0,0 +1,29 @@
+// openapi-gen генерирует спецификацию OpenAPI REST API из proto/user.proto.
+// Запускается через go generate ./internal/openapi из корня репозитория.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"path/filepath"
+
+	"k8s-go-grpc-react/internal/openapi/generator"
+)
+
+func main() {
+	root := flag.String("root", ".", "корень репозитория")
+	out := flag.String("out", "internal/openapi/openapi.json", "путь к выходному файлу")
+	flag.Parse()
+
+	data, err := generator.Generate(context.Background(), generator.DefaultOptions(*root))
+	if err != nil {
+		log.Fatalf("Ошибка генерации OpenAPI: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Clean(*out), data, 0o644); err != nil {
+		log.Fatalf("Ошибка записи %s: %v", *out, err)
+	}
+	log.Printf("Спецификация OpenAPI записана в %s", *out)
+}
@@ -3,46 +3,97 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/reflection"
 
+	"k8s-go-grpc-react/internal/accesslog"
+	"k8s-go-grpc-react/internal/audit"
 	"k8s-go-grpc-react/internal/auth"
 	"k8s-go-grpc-react/internal/config"
 	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/gateway"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/loglevel"
+	"k8s-go-grpc-react/internal/oidc"
+	"k8s-go-grpc-react/internal/ratelimit"
 	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/requestid"
 	"k8s-go-grpc-react/internal/service"
+	"k8s-go-grpc-react/internal/shutdown"
+	"k8s-go-grpc-react/internal/tlsutil"
+	"k8s-go-grpc-react/internal/webhook"
 	pb "k8s-go-grpc-react/proto"
 )
 
 func main() {
-	// Загружаем конфигурацию
-	cfg := config.Load()
+	// До загрузки конфигурации логи пишутся только в stdout
+	log := logger.New(logger.Options{Service: "grpc-server"})
 
-	// Подключаемся к базе данных используя отдельные переменные окружения
-	dbConfig := database.GetConfigFromEnv()
-	db, err := database.NewPostgresDB(dbConfig)
+	// Загружаем конфигурацию: файл, переменные окружения и флаги
+	loader := config.NewLoader(config.ComponentServer, os.Args[1:])
+	cfg, err := loader.Load()
 	if err != nil {
-		log.Fatalf("Ошибка подключения к базе данных: %v", err)
+		logger.Fatal(log, "Ошибка загрузки конфигурации", logger.Err(err))
+	}
+
+	// Логер сервера, уровни компонентов меняются при перезагрузке конфигурации.
+	// Записи отправляются в Graylog, чтобы их можно было связать с логами gateway по request_id.
+	// Персональные данные и секреты скрываются до отправки в Graylog.
+	levels := logger.NewLevels(slog.LevelInfo)
+	if err := levels.Update(cfg.Logging.Level, cfg.Logging.Levels); err != nil {
+		logger.Fatal(log, "Ошибка настройки уровней логирования", logger.Err(err))
+	}
+	redactor, err := logger.NewRedactor(cfg.Logging.Redaction.HashKey, cfg.Logging.Redaction.Rules())
+	if err != nil {
+		logger.Fatal(log, "Ошибка настройки скрытия данных в логах", logger.Err(err))
+	}
+	graylogOpts, err := cfg.Logging.GraylogOptions()
+	if err != nil {
+		logger.Fatal(log, "Ошибка настройки отправки логов в Graylog", logger.Err(err))
+	}
+	log, graylogWriter := logger.Setup(logger.Options{
+		Service:  "grpc-server",
+		Format:   cfg.Logging.Format,
+		Levels:   levels,
+		Redactor: redactor,
+	}, graylogOpts)
+
+	for _, warning := range cfg.InsecureDefaults(config.ComponentServer) {
+		log.Warn("Небезопасная конфигурация, недопустимо в production", "warning", warning)
+	}
+	log.Info("Конфигурация загружена", "environment", cfg.Environment)
+
+	// Подключаемся к базе данных
+	db, err := database.Connect(cfg.Database.DSN(), log)
+	if err != nil {
+		logger.Fatal(log, "Ошибка подключения к базе данных", logger.Err(err))
 	}
 
 	// Выполняем миграции
 	if err := database.Migrate(db); err != nil {
-		log.Fatalf("Ошибка выполнения миграций: %v", err)
+		logger.Fatal(log, "Ошибка выполнения миграций", logger.Err(err))
 	}
 
-	// Создаем репозиторий
+	// Создаем репозитории; с DATABASE_URL=memory:// пользователи хранятся в памяти процесса,
+	// а остальные таблицы - в SQLite в памяти
+	memoryStorage := database.Driver(cfg.Database.DSN()) == database.DriverMemory
 	userRepo := repository.NewUserRepository(db)
+	if memoryStorage {
+		userRepo = repository.NewMemoryUserRepository(repository.NewUserDataDeleter(db))
+		log.Warn("Данные хранятся в памяти и будут потеряны при перезапуске")
+	}
+	auditRepo := repository.NewAuditRepository(db)
 
 	// Создаем метрики Prometheus
 	requestsTotal := prometheus.NewCounterVec(
@@ -69,18 +120,180 @@ func main() {
 		},
 	)
 
+	// Изменяемые параметры применяются при изменении файла конфигурации и по SIGHUP
+	watcher := config.NewWatcher(loader, cfg, log)
+
 	// Регистрируем метрики
 	prometheus.MustRegister(requestsTotal, requestDuration, usersCount)
+	prometheus.MustRegister(watcher.Collectors()...)
+	prometheus.MustRegister(graylogWriter.Collectors()...)
+
+	// Создаем JWT сервис
+	jwtService := auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.Expiration())
+
+	// Создаем сервис с метриками и журналом аудита; изменение пользователя и запись
+	// аудита о нем фиксируются одной транзакцией. Пользователи в памяти в транзакциях
+	// не участвуют, поэтому с memory:// операции выполняются без них.
+	checkpointKey := []byte(cfg.Audit.CheckpointKey)
+	oauthRepo := repository.NewOAuthRepository(db)
+	userService := service.NewUserServiceWithMetrics(userRepo, jwtService, requestsTotal, requestDuration, usersCount).
+		WithAudit(audit.NewRecorder(auditRepo, checkpointKey, log)).
+		WithOAuth(oauthRepo).
+		WithLogger(log)
+	if !memoryStorage {
+		userService.WithTx(repository.NewTxManager(db))
+	}
+
+	// События пользователей записываются в outbox вместе с изменением и публикуются в фоне:
+	// в брокер, выбранный в events.publisher, и по подпискам на webhook
+	var publishers []events.Publisher
+	if cfg.Events.Enabled() {
+		publisher, err := newEventPublisher(cfg.Events, log)
+		if err != nil {
+			logger.Fatal(log, "Ошибка настройки публикации событий", logger.Err(err))
+		}
+		publishers = append(publishers, publisher)
+		log.Info("Публикация событий пользователей включена", "publisher", cfg.Events.Publisher)
+	}
 
-	// Создаем сервис с метриками
-	userService := service.NewUserServiceWithMetrics(userRepo, requestsTotal, requestDuration, usersCount)
+	// Webhook доставляются в фоне; Publisher только создает доставки в журнале
+	var webhookDispatcher *webhook.Dispatcher
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if cfg.Webhooks.Enabled {
+		webhookRepo := repository.NewWebhookRepository(db)
+		userService.WithWebhooks(webhookRepo, cfg.Webhooks.AllowPrivateNetworks)
+		publishers = append(publishers, webhook.NewPublisher(webhookRepo))
+
+		webhookDispatcher = webhook.NewDispatcher(webhookRepo, webhook.Options{
+			Timeout:              cfg.Webhooks.Timeout(),
+			MaxAttempts:          cfg.Webhooks.MaxAttempts,
+			DisableAfterFailures: cfg.Webhooks.DisableAfterFailures,
+			Retention:            cfg.Webhooks.Retention(),
+			AllowPrivateNetworks: cfg.Webhooks.AllowPrivateNetworks,
+		}, log)
+		prometheus.MustRegister(webhookDispatcher.Collectors()...)
+		go webhookDispatcher.Run(workersCtx, cfg.Webhooks.DispatchInterval())
+		log.Info("Webhook включены")
+	}
+
+	var eventPublisher events.Publisher
+	var eventRelay *events.Relay
+	if len(publishers) > 0 {
+		eventPublisher = events.Multi(publishers...)
+		outboxRepo := repository.NewOutboxRepository(db)
+		userService.WithEvents(events.NewOutbox(outboxRepo))
+
+		eventRelay = events.NewRelay(outboxRepo, eventPublisher, cfg.Events.MaxAttempts, cfg.Events.Retention(), log)
+		prometheus.MustRegister(eventRelay.Collectors()...)
+		go eventRelay.Run(workersCtx, cfg.Events.RelayInterval())
+	}
+
+	// События аудита старше срока хранения удаляются в фоне
+	auditRetention := audit.NewRetention(auditRepo, cfg.Audit.Retention(), checkpointKey, log)
+	go auditRetention.Run(workersCtx, audit.DefaultCleanupInterval)
+
+	// Удаленные пользователи по истечении срока хранения удаляются окончательно
+	deletedUsersRetention := service.NewDeletedUsersRetention(userRepo, cfg.Users.DeletedRetention(), log)
+	go deletedUsersRetention.Run(workersCtx, service.DefaultPurgeInterval)
+
+	// Цепочка записей аудита периодически заверяется подписанными контрольными точками
+	if len(checkpointKey) > 0 {
+		go audit.NewCheckpointer(auditRepo, checkpointKey, log).Run(workersCtx, cfg.Audit.CheckpointInterval())
+	} else {
+		log.Warn("Ключ подписи контрольных точек аудита не задан, контрольные точки не создаются")
+	}
 
 	// Создаем middleware для аутентификации
-	authMiddleware := auth.NewAuthMiddleware()
+	authMiddleware := auth.NewAuthMiddleware(jwtService, log)
+
+	// Ограничение частоты запросов; HTTP API проходит через тот же gRPC interceptor
+	limiter := ratelimit.New(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+
+	// Журнал HTTP и gRPC запросов
+	accessLog := accesslog.New(log, cfg.AccessLog.Options())
+
+	watcher.Subscribe(func(cfg *config.Config) {
+		if err := levels.Update(cfg.Logging.Level, cfg.Logging.Levels); err != nil {
+			log.Error("Уровни логирования не применены", logger.Err(err))
+		}
+		limiter.Update(cfg.RateLimit.RequestsPerSecond, cfg.RateLimit.Burst)
+		authMiddleware.SetPublicMethods(cfg.Auth.PublicMethods)
+		accessLog.Update(cfg.AccessLog.Options())
+		auditRetention.Update(cfg.Audit.Retention())
+		deletedUsersRetention.Update(cfg.Users.DeletedRetention())
+		if eventRelay != nil {
+			eventRelay.UpdateRetention(cfg.Events.Retention())
+		}
+		if webhookDispatcher != nil {
+			webhookDispatcher.UpdateRetention(cfg.Webhooks.Retention())
+		}
+		if err := redactor.Update(cfg.Logging.Redaction.Rules()); err != nil {
+			log.Error("Правила скрытия данных в логах не применены", logger.Err(err))
+		}
+	})
+	go watcher.Run(context.Background(), config.DefaultWatchInterval)
+
+	// Настраиваем TLS/mTLS gRPC сервера
+	serverCreds, serverTLS, err := tlsutil.ServerCredentials(tlsutil.ServerOptions{
+		CertFile:     cfg.TLS.CertFile,
+		KeyFile:      cfg.TLS.KeyFile,
+		ClientCAFile: cfg.TLS.ClientCAFile,
+		ClientAuth:   cfg.TLS.ClientAuth,
+	})
+	if err != nil {
+		logger.Fatal(log, "Ошибка настройки TLS gRPC сервера", logger.Err(err))
+	}
+	if serverTLS != nil {
+		go serverTLS.Watch(context.Background(), tlsutil.DefaultReloadInterval,
+			func() { log.Info("Сертификаты gRPC сервера перезагружены") },
+			func(err error) {
+				log.Error("Ошибка перезагрузки сертификатов gRPC сервера", logger.Err(err))
+			},
+		)
+		log.Info("TLS gRPC сервера включен", "client_auth", cfg.TLS.ClientAuth)
+	}
+
+	// Настраиваем TLS подключения встроенного gRPC-Gateway к gRPC серверу
+	clientCreds, clientTLS, err := tlsutil.ClientCredentials(tlsutil.ClientOptions{
+		Enabled:    cfg.GRPCClientTLS.Enabled,
+		CAFile:     cfg.GRPCClientTLS.CAFile,
+		CertFile:   cfg.GRPCClientTLS.CertFile,
+		KeyFile:    cfg.GRPCClientTLS.KeyFile,
+		ServerName: cfg.GRPCClientTLS.ServerName,
+	})
+	if err != nil {
+		logger.Fatal(log, "Ошибка настройки TLS клиента gRPC-Gateway", logger.Err(err))
+	}
+	if clientTLS != nil {
+		go clientTLS.Watch(context.Background(), tlsutil.DefaultReloadInterval,
+			func() {
+				log.Info("Клиентские сертификаты gRPC-Gateway перезагружены")
+			},
+			func(err error) {
+				log.Error("Ошибка перезагрузки клиентских сертификатов gRPC-Gateway", logger.Err(err))
+			},
+		)
+	}
+
+	// Собираем цепочку interceptors: идентификатор запроса, журнал запросов, ограничение частоты,
+	// проверка сервиса по сертификату, затем JWT. Идентификатор запроса первым,
+	// чтобы он попал в журнал и ошибки остальных interceptors.
+	interceptors := []grpc.UnaryServerInterceptor{
+		requestid.UnaryServerInterceptor(log),
+		accessLog.UnaryServerInterceptor,
+		limiter.UnaryInterceptor,
+	}
+	if len(cfg.TLS.AllowedClientSANs) > 0 {
+		interceptors = append(interceptors, tlsutil.NewSANAuthorizer(cfg.TLS.AllowedClientSANs).UnaryInterceptor)
+		log.Info("Авторизация сервисов по SAN включена", "allowed_sans", cfg.TLS.AllowedClientSANs)
+	}
+	interceptors = append(interceptors, authMiddleware.UnaryInterceptor)
 
 	// Создаем gRPC сервер с middleware
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(authMiddleware.UnaryInterceptor),
+		grpc.Creds(serverCreds),
+		grpc.ChainUnaryInterceptor(interceptors...),
 	)
 
 	// Регистрируем сервис
@@ -89,84 +302,160 @@ func main() {
 	// Включаем рефлексию для отладки
 	reflection.Register(grpcServer)
 
+	// Создаем OIDC провайдер для входа во внутренние приложения через учетные записи платформы
+	var oidcProvider *oidc.Provider
+	if cfg.OIDC.Enabled {
+		oidcConfig := oidc.DefaultConfig(cfg.OIDC.Issuer)
+		oidcConfig.LoginURL = cfg.OIDC.LoginURL
+		oidcConfig.SigningKeyEncryptionKey = cfg.OIDC.SigningKeyEncryptionKey
+
+		oidcProvider = oidc.NewProvider(oidcConfig, oauthRepo, userRepo, jwtService, log)
+		if err := oidcProvider.Start(workersCtx); err != nil {
+			logger.Fatal(log, "Ошибка запуска OIDC провайдера", logger.Err(err))
+		}
+		log.Info("OIDC провайдер включен", "issuer", cfg.OIDC.Issuer)
+	}
+
 	// Запускаем gRPC сервер
 	go func() {
-		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.GRPCPort))
+		lis, err := net.Listen("tcp", fmt.Sprintf(":%s", cfg.Listen.GRPCPort))
 		if err != nil {
-			log.Fatalf("Ошибка создания TCP слушателя: %v", err)
+			logger.Fatal(log, "Ошибка создания TCP слушателя", logger.Err(err))
 		}
 
-		log.Printf("gRPC сервер запущен на порту %s", cfg.GRPCPort)
+		log.Info("gRPC сервер запущен", "port", cfg.Listen.GRPCPort)
 		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatalf("Ошибка запуска gRPC сервера: %v", err)
+			logger.Fatal(log, "Ошибка запуска gRPC сервера", logger.Err(err))
 		}
 	}()
 
-	// Создаем HTTP сервер для gRPC-Gateway
-	go func() {
-		ctx := context.Background()
-		ctx, cancel := context.WithCancel(ctx)
-		defer cancel()
-
-		// Создаем gRPC-Gateway mux
-		mux := runtime.NewServeMux()
-
-		// Подключаемся к gRPC серверу
-		opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
-		err := pb.RegisterUserServiceHandlerFromEndpoint(
-			ctx,
-			mux,
-			fmt.Sprintf("localhost:%s", cfg.GRPCPort),
-			opts,
+	// Создаем HTTP роутер
+	httpMux := http.NewServeMux()
+
+	// Встроенный REST gateway подключается к gRPC серверу этого же процесса
+	var gatewayConn *grpc.ClientConn
+	if cfg.Gateway.Embedded {
+		gatewayConn, err = grpc.NewClient(
+			fmt.Sprintf("localhost:%s", cfg.Listen.GRPCPort),
+			grpc.WithTransportCredentials(clientCreds),
 		)
 		if err != nil {
-			log.Fatalf("Ошибка регистрации gRPC-Gateway: %v", err)
+			logger.Fatal(log, "Ошибка подключения gRPC-Gateway", logger.Err(err))
 		}
 
-		// Создаем HTTP роутер
-		httpMux := http.NewServeMux()
-
-		// Добавляем CORS middleware
-		corsHandler := func(h http.Handler) http.Handler {
-			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-				w.Header().Set("Access-Control-Allow-Origin", "*")
-				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-				w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		gw, err := gateway.New(context.Background(), gatewayConn, gateway.OptionsFromConfig(watcher))
+		if err != nil {
+			logger.Fatal(log, "Ошибка создания gRPC-Gateway", logger.Err(err))
+		}
+		httpMux.Handle(gateway.Prefix+"/", gw)
+		log.Info("Встроенный gRPC-Gateway включен", "prefix", gateway.Prefix+"/")
+	}
 
-				if r.Method == "OPTIONS" {
-					w.WriteHeader(http.StatusOK)
-					return
-				}
+	// Готовность снимается первой при остановке, чтобы балансировщики перестали слать трафик
+	readiness := shutdown.NewReadiness()
 
-				h.ServeHTTP(w, r)
-			})
+	// Регистрируем маршруты
+	httpMux.Handle("/metrics", promhttp.Handler())
+	if oidcProvider != nil {
+		oidcProvider.RegisterRoutes(httpMux)
+	}
+	httpMux.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		if _, err := w.Write([]byte("OK")); err != nil {
+			log.Error("Ошибка записи ответа health check", logger.Err(err))
 		}
+	}))
+	httpMux.Handle("/ready", readiness.Handler())
 
-		// Регистрируем маршруты
-		httpMux.Handle("/api/", http.StripPrefix("/api", corsHandler(mux)))
-		httpMux.Handle("/metrics", promhttp.Handler())
-		httpMux.Handle("/health", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			w.WriteHeader(http.StatusOK)
-			if _, err := w.Write([]byte("OK")); err != nil {
-				log.Printf("Ошибка записи ответа health check: %v", err)
-			}
-		}))
-
-		log.Printf("HTTP сервер запущен на порту %s", cfg.HTTPPort)
-		if err := http.ListenAndServe(fmt.Sprintf(":%s", cfg.HTTPPort), httpMux); err != nil {
-			log.Fatalf("Ошибка запуска HTTP сервера: %v", err)
+	// Уровни логирования меняются администратором без перезапуска
+	httpMux.Handle(loglevel.Path, authMiddleware.RequireAuth(authMiddleware.RequireRole("admin")(loglevel.New(levels, log))))
+
+	// Создаем HTTP сервер для gRPC-Gateway, метрик и OIDC
+	httpServer := &http.Server{
+		Addr:    fmt.Sprintf(":%s", cfg.Listen.HTTPPort),
+		Handler: requestid.Middleware(accessLog.Middleware(httpMux)),
+	}
+
+	go func() {
+		log.Info("HTTP сервер запущен", "port", cfg.Listen.HTTPPort)
+		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Fatal(log, "Ошибка запуска HTTP сервера", logger.Err(err))
 		}
 	}()
 
 	// Ожидаем сигнал завершения
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
+	sig := <-sigChan
+
+	shutdownLog := logger.Component(log, "shutdown").With("signal", sig.String())
+	shutdownLog.Info("Получен сигнал завершения, останавливаем сервер")
 
-	log.Println("Получен сигнал завершения, останавливаем сервер...")
+	// 1. Снимаем готовность и ждем, пока под исключат из балансировки
+	readiness.SetNotReady()
+	shutdownLog.Info("Готовность снята, ожидание перед остановкой серверов", "drain_delay", cfg.Shutdown.DrainDelay().String())
+	time.Sleep(cfg.Shutdown.DrainDelay())
 
-	// Graceful shutdown
-	grpcServer.GracefulStop()
+	// 2. Останавливаем HTTP сервер, дожидаясь активных запросов
+	httpCtx, cancelHTTP := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout())
+	if err := httpServer.Shutdown(httpCtx); err != nil {
+		shutdownLog.Warn("HTTP сервер не успел завершить запросы", "timeout", cfg.Shutdown.Timeout().String(), logger.Err(err))
+	} else {
+		shutdownLog.Info("HTTP сервер остановлен")
+	}
+	cancelHTTP()
+
+	// 3. Останавливаем gRPC сервер, принудительно при превышении времени
+	grpcCtx, cancelGRPC := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout())
+	if shutdown.StopGRPC(grpcCtx, grpcServer) {
+		shutdownLog.Warn("gRPC сервер остановлен принудительно: вызовы не завершились вовремя", "timeout", cfg.Shutdown.Timeout().String())
+	} else {
+		shutdownLog.Info("gRPC сервер остановлен")
+	}
+	cancelGRPC()
 
-	log.Println("Сервер остановлен")
+	// 4. Закрываем соединение gRPC-Gateway и пул соединений с базой данных
+	if gatewayConn != nil {
+		if err := gatewayConn.Close(); err != nil {
+			shutdownLog.Error("Ошибка закрытия соединения gRPC-Gateway", logger.Err(err))
+		} else {
+			shutdownLog.Info("Соединение gRPC-Gateway закрыто")
+		}
+	}
+	// Неопубликованные события и недоставленные webhook останутся в базе данных
+	// и будут отправлены после запуска
+	stopWorkers()
+	if eventPublisher != nil {
+		if err := eventPublisher.Close(); err != nil {
+			shutdownLog.Error("Ошибка закрытия публикации событий", logger.Err(err))
+		}
+	}
+	if err := database.Close(db); err != nil {
+		shutdownLog.Error("Ошибка закрытия соединений с базой данных", logger.Err(err))
+	} else {
+		shutdownLog.Info("Соединения с базой данных закрыты")
+	}
+
+	shutdownLog.Info("Сервер остановлен")
+
+	// 5. Отправляем накопленные логи в Graylog, записи после этого выводятся только в stdout
+	logCtx, cancelLog := context.WithTimeout(context.Background(), cfg.Shutdown.Timeout())
+	defer cancelLog()
+	if err := graylogWriter.Close(logCtx); err != nil {
+		shutdownLog.Warn("Не все логи отправлены в Graylog", logger.Err(err))
+	}
+}
+
+// newEventPublisher создает публикацию событий, выбранную в конфигурации
+func newEventPublisher(cfg config.EventsConfig, log *slog.Logger) (events.Publisher, error) {
+	switch cfg.Publisher {
+	case "file":
+		return events.NewFilePublisher(cfg.File)
+	case "nats":
+		return events.NewNATSPublisher(cfg.NATSURL, cfg.SubjectPrefix)
+	case "kafka":
+		return events.NewKafkaPublisher(cfg.KafkaBrokers, cfg.KafkaTopic)
+	default:
+		return events.NewLogPublisher(log), nil
+	}
 }
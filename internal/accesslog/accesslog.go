@@ -0,0 +1,170 @@
+// Package accesslog пишет структурированный журнал запросов HTTP и gRPC:
+// метод, маршрут, статус, время выполнения, размер ответа, IP клиента,
+// идентификаторы пользователя и запроса. Настройки можно менять на лету.
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"math/rand/v2"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Options настройки журнала запросов
+type Options struct {
+	// Disabled отключает журнал
+	Disabled bool
+	// Exclude пути HTTP и полные имена gRPC методов, которые не записываются
+	Exclude []string
+	// Sample доля записываемых успешных запросов по префиксу маршрута;
+	// ошибки записываются всегда
+	Sample []Sample
+	// TrustedProxies адреса прокси, которым разрешено передавать IP клиента
+	// в X-Forwarded-For
+	TrustedProxies []netip.Prefix
+}
+
+// Sample доля записываемых запросов для маршрутов с префиксом Prefix
+type Sample struct {
+	Prefix  string
+	Percent int
+}
+
+// Logger журнал запросов
+type Logger struct {
+	log  *slog.Logger
+	opts atomic.Pointer[Options]
+}
+
+// New создает журнал запросов, записи пишутся в log
+func New(log *slog.Logger, opts Options) *Logger {
+	l := &Logger{log: log}
+	l.Update(opts)
+	return l
+}
+
+// Update атомарно заменяет настройки журнала
+func (l *Logger) Update(opts Options) {
+	l.opts.Store(&opts)
+}
+
+// skip возвращает true, если запрос с маршрутом route и без ошибки не нужно записывать
+func (l *Logger) skip(route string, failed bool) bool {
+	opts := l.opts.Load()
+	if opts.Disabled {
+		return true
+	}
+	for _, excluded := range opts.Exclude {
+		if route == excluded {
+			return true
+		}
+	}
+	if failed {
+		return false
+	}
+
+	// Применяется правило с самым длинным подходящим префиксом
+	percent, matched := 100, -1
+	for _, sample := range opts.Sample {
+		if strings.HasPrefix(route, sample.Prefix) && len(sample.Prefix) > matched {
+			percent, matched = sample.Percent, len(sample.Prefix)
+		}
+	}
+	return percent < 100 && rand.IntN(100) >= percent
+}
+
+// clientIP определяет IP клиента. X-Forwarded-For учитывается, только если
+// запрос пришел от доверенного прокси; цепочка просматривается справа налево
+// до первого недоверенного адреса.
+func (l *Logger) clientIP(remote string, forwardedFor []string) string {
+	addr, err := netip.ParseAddrPort(remote)
+	if err != nil {
+		return remote
+	}
+
+	ip := addr.Addr().Unmap()
+	trusted := l.opts.Load().TrustedProxies
+	var hops []string
+	for _, header := range forwardedFor {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0 && isTrusted(trusted, ip); i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			break
+		}
+		ip = hop.Unmap()
+	}
+	return ip.String()
+}
+
+// isTrusted проверяет, входит ли адрес в список доверенных прокси
+func isTrusted(trusted []netip.Prefix, ip netip.Addr) bool {
+	for _, prefix := range trusted {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// requestInfo данные запроса, общие для журнала и внутренних обработчиков
+type requestInfo struct {
+	clientIP string // Задается до вызова обработчиков и не меняется
+
+	mu     sync.Mutex
+	userID string
+}
+
+type contextKey struct{}
+
+// withRequestInfo добавляет в контекст IP клиента и место для данных запроса
+func withRequestInfo(ctx context.Context, clientIP string) (context.Context, *requestInfo) {
+	info := &requestInfo{clientIP: clientIP}
+	return context.WithValue(ctx, contextKey{}, info), info
+}
+
+// ClientIP возвращает IP клиента, определенный журналом с учетом доверенных прокси,
+// или пустую строку, если запрос прошел мимо журнала
+func ClientIP(ctx context.Context) string {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		return info.clientIP
+	}
+	return ""
+}
+
+// SetUserID сообщает журналу идентификатор аутентифицированного пользователя.
+// Вызывается из middleware аутентификации, без журнала в контексте ничего не делает.
+func SetUserID(ctx context.Context, userID uint) {
+	SetUserIDString(ctx, strconv.FormatUint(uint64(userID), 10))
+}
+
+// SetUserIDString как SetUserID, для идентификатора, полученного в виде строки
+func SetUserIDString(ctx context.Context, userID string) {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		info.mu.Lock()
+		info.userID = userID
+		info.mu.Unlock()
+	}
+}
+
+// getUserID возвращает идентификатор пользователя, сохраненный SetUserID
+func (i *requestInfo) getUserID() string {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.userID
+}
+
+// entry возвращает общие атрибуты записей HTTP и gRPC
+func entry(info *requestInfo) []slog.Attr {
+	attrs := []slog.Attr{slog.String("client_ip", info.clientIP)}
+	if userID := info.getUserID(); userID != "" {
+		attrs = append(attrs, slog.String("user_id", userID))
+	}
+	return attrs
+}
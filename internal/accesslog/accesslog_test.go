@@ -0,0 +1,135 @@
+package accesslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/requestid"
+)
+
+// captureLogger возвращает логер и функцию, разбирающую записанные им строки JSON
+func captureLogger(t *testing.T) (*slog.Logger, func() []map[string]interface{}) {
+	t.Helper()
+	out := &bytes.Buffer{}
+	log := slog.New(slog.NewJSONHandler(out, nil))
+	return log, func() []map[string]interface{} {
+		var entries []map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(out.Bytes()))
+		for decoder.More() {
+			var entry map[string]interface{}
+			require.NoError(t, decoder.Decode(&entry))
+			entries = append(entries, entry)
+		}
+		return entries
+	}
+}
+
+func TestClientIP(t *testing.T) {
+	l := New(logger.Discard(), Options{
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+
+	// Недоверенный клиент не может подменить IP заголовком
+	assert.Equal(t, "203.0.113.7", l.clientIP("203.0.113.7:5000", []string{"1.2.3.4"}))
+
+	// Через доверенные прокси берется первый недоверенный адрес справа
+	assert.Equal(t, "198.51.100.1", l.clientIP("10.0.0.2:5000", []string{"1.2.3.4, 198.51.100.1", "10.0.0.5"}))
+
+	// Без заголовка остается адрес соединения
+	assert.Equal(t, "10.0.0.2", l.clientIP("10.0.0.2:5000", nil))
+}
+
+func TestSkip(t *testing.T) {
+	l := New(logger.Discard(), Options{
+		Exclude: []string{"/health"},
+		Sample: []Sample{
+			{Prefix: "/api/", Percent: 100},
+			{Prefix: "/api/v1/users", Percent: 0},
+		},
+	})
+
+	assert.True(t, l.skip("/health", true))
+	assert.True(t, l.skip("/api/v1/users/1", false))
+	assert.False(t, l.skip("/api/v1/users/1", true), "ошибки записываются всегда")
+	assert.False(t, l.skip("/api/v1/auth/login", false))
+
+	l.Update(Options{Disabled: true})
+	assert.True(t, l.skip("/api/v1/auth/login", true))
+}
+
+func TestMiddleware(t *testing.T) {
+	log, entries := captureLogger(t)
+	l := New(log, Options{Exclude: []string{"/metrics"}})
+
+	h := requestid.Middleware(l.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		SetUserID(r.Context(), 7)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "boom", http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte("hello"))
+	})))
+
+	req := httptest.NewRequest(http.MethodGet, "/ok", nil)
+	req.Header.Set(requestid.Header, "req-1")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	require.Len(t, entries(), 1)
+	e := entries()[0]
+	assert.Equal(t, "INFO", e["level"])
+	assert.Equal(t, "http-access", e["component"])
+	assert.EqualValues(t, http.StatusOK, e["status"])
+	assert.EqualValues(t, 5, e["bytes"])
+	assert.Equal(t, "7", e["user_id"])
+	assert.Equal(t, "req-1", e[requestid.LogField])
+	assert.Equal(t, "192.0.2.1", e["client_ip"])
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/fail", nil))
+	require.Len(t, entries(), 2)
+	assert.Equal(t, "ERROR", entries()[1]["level"])
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Len(t, entries(), 2)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	log, entries := captureLogger(t)
+	l := New(log, Options{})
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.10"), Port: 4000}})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-forwarded-for", "198.51.100.1"))
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUser"}
+
+	_, err := l.UnaryServerInterceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		SetUserID(ctx, 3)
+		return nil, status.Error(codes.NotFound, "не найдено")
+	})
+	require.Error(t, err)
+
+	require.Len(t, entries(), 1)
+	e := entries()[0]
+	assert.Equal(t, "WARN", e["level"])
+	assert.Equal(t, "NotFound", e["code"])
+	assert.Equal(t, "/user.UserService/GetUser", e["method"])
+	assert.Equal(t, "3", e["user_id"])
+	// Адрес соединения не доверенный, поэтому x-forwarded-for игнорируется
+	assert.Equal(t, "192.0.2.10", e["client_ip"])
+
+	assert.Equal(t, slog.LevelError, grpcLevel(codes.Internal))
+}
@@ -0,0 +1,74 @@
+package accesslog
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// UserIDMetadataKey заголовок gRPC ответа с идентификатором пользователя.
+// Gateway записывает его в свой журнал и не передает HTTP клиенту.
+const UserIDMetadataKey = "x-user-id"
+
+// UnaryServerInterceptor записывает gRPC вызовы в журнал. Должен стоять после
+// interceptor requestid, чтобы записи содержали идентификатор запроса.
+func (l *Logger) UnaryServerInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	remote := ""
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		remote = p.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx, ri := withRequestInfo(ctx, l.clientIP(remote, md.Get("x-forwarded-for")))
+
+	resp, err := handler(ctx, req)
+
+	userID := ri.getUserID()
+	if userID != "" {
+		_ = grpc.SetHeader(ctx, metadata.Pairs(UserIDMetadataKey, userID))
+	}
+
+	code := status.Code(err)
+	if l.skip(info.FullMethod, code != codes.OK) {
+		return resp, err
+	}
+
+	bytes := 0
+	if msg, ok := resp.(proto.Message); ok && err == nil {
+		bytes = proto.Size(msg)
+	}
+
+	attrs := append([]slog.Attr{
+		slog.String("method", info.FullMethod),
+		slog.String("code", code.String()),
+		slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+		slog.Int("bytes", bytes),
+	}, entry(ri)...)
+	logger.Component(logger.FromContext(ctx, l.log), "grpc-access").LogAttrs(ctx, grpcLevel(code), "gRPC вызов", attrs...)
+
+	return resp, err
+}
+
+// grpcLevel уровень записи по коду ответа: ошибки клиента - предупреждения,
+// ошибки сервера - ошибки
+func grpcLevel(code codes.Code) slog.Level {
+	switch code {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.FailedPrecondition,
+		codes.OutOfRange, codes.ResourceExhausted, codes.Aborted:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
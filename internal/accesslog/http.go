@@ -0,0 +1,91 @@
+package accesslog
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/requestid"
+)
+
+// responseRecorder запоминает статус и размер ответа
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.bytes += n
+	return n, err
+}
+
+// Flush передает сброс буфера исходному ResponseWriter
+func (r *responseRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap открывает исходный ResponseWriter для http.ResponseController
+func (r *responseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// Middleware записывает HTTP запросы в журнал
+func (l *Logger) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, info := withRequestInfo(r.Context(), l.clientIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For")))
+		rec := &responseRecorder{ResponseWriter: w}
+
+		next.ServeHTTP(rec, r.WithContext(ctx))
+
+		if rec.status == 0 {
+			rec.status = http.StatusOK
+		}
+		if l.skip(r.URL.Path, rec.status >= http.StatusBadRequest) {
+			return
+		}
+
+		// Идентификатор запроса берется из ответа: middleware requestid может стоять внутри
+		id := requestid.FromContext(r.Context())
+		if id == "" {
+			id = rec.Header().Get(requestid.Header)
+		}
+
+		attrs := append([]slog.Attr{
+			slog.String("method", r.Method),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", rec.status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.Int("bytes", rec.bytes),
+			slog.String(requestid.LogField, id),
+		}, entry(info)...)
+		logger.Component(l.log, "http-access").LogAttrs(r.Context(), httpLevel(rec.status), "HTTP запрос", attrs...)
+	})
+}
+
+// httpLevel уровень записи по статусу ответа
+func httpLevel(status int) slog.Level {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return slog.LevelError
+	case status >= http.StatusBadRequest:
+		return slog.LevelWarn
+	default:
+		return slog.LevelInfo
+	}
+}
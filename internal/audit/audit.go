@@ -0,0 +1,227 @@
+// Package audit ведет журнал аудита событий безопасности: входы, неудачные попытки
+// входа, создание пользователей, смену ролей и блокировку аккаунтов. В отличие от
+// логов, записи хранятся в базе данных и доступны через API администраторам и аудиторам.
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"strconv"
+
+	"google.golang.org/grpc/metadata"
+
+	"k8s-go-grpc-react/internal/accesslog"
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/requestid"
+)
+
+// Действия журнала аудита
+const (
+	ActionLogin          = "auth.login"
+	ActionLoginFailed    = "auth.login_failed"
+	ActionUserRegister   = "user.register"
+	ActionUserCreate     = "user.create"
+	ActionUserUpdate     = "user.update"
+	ActionUserRoleChange = "user.role_change"
+	ActionUserDeactivate = "user.deactivate"
+	ActionUserActivate   = "user.activate"
+	ActionUserDelete     = "user.delete"
+	ActionUserRestore    = "user.restore"
+	ActionUserPurge      = "user.purge"
+	ActionUserExport     = "user.export"
+	ActionUserErase      = "user.erase"
+
+	ActionWebhookCreate    = "webhook.create"
+	ActionWebhookUpdate    = "webhook.update"
+	ActionWebhookDelete    = "webhook.delete"
+	ActionWebhookRedeliver = "webhook.redeliver"
+)
+
+// Причины неудачного входа
+const (
+	ReasonUserNotFound    = "user_not_found"
+	ReasonInactive        = "inactive"
+	ReasonInvalidPassword = "invalid_password"
+)
+
+// Recorder записывает события в журнал аудита
+type Recorder struct {
+	repo          repository.AuditRepository
+	checkpointKey []byte
+	log           *slog.Logger
+}
+
+// NewRecorder создает Recorder; checkpointKey ключ подписи контрольных точек
+// для проверки цепочки. Ошибки записи пишутся в log с компонентом audit.
+func NewRecorder(repo repository.AuditRepository, checkpointKey []byte, log *slog.Logger) *Recorder {
+	return &Recorder{repo: repo, checkpointKey: checkpointKey, log: log}
+}
+
+// Record дополняет событие данными запроса из контекста и сохраняет его.
+// Незаполненные инициатор, IP, User-Agent и идентификатор запроса берутся из контекста.
+// Событие, которое не удалось сохранить, целиком записывается в лог с уровнем error,
+// чтобы оно не потерялось; действие пользователя при этом не прерывается.
+func (r *Recorder) Record(ctx context.Context, event *models.AuditEvent) {
+	if event.ActorID == nil {
+		event.ActorID, event.ActorEmail = actor(ctx)
+	}
+	if event.IP == "" {
+		event.IP = accesslog.ClientIP(ctx)
+	}
+	if event.UserAgent == "" {
+		event.UserAgent = userAgent(ctx)
+	}
+	if event.RequestID == "" {
+		event.RequestID = requestid.FromContext(ctx)
+	}
+
+	if err := r.repo.Create(ctx, event); err != nil {
+		r.logFor(ctx).Error("Ошибка записи события аудита", logger.Err(err),
+			"action", event.Action,
+			"actor_id", optionalID(event.ActorID),
+			"target_id", optionalID(event.TargetID),
+			"ip", event.IP,
+			"reason", event.Reason,
+			"changes", event.Changes,
+		)
+	}
+}
+
+// List возвращает события журнала по фильтру, начиная с новых
+func (r *Recorder) List(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEvent, error) {
+	return r.repo.List(ctx, filter)
+}
+
+// Verify проверяет цепочку журнала (см. Verify)
+func (r *Recorder) Verify(ctx context.Context) (*VerifyResult, error) {
+	return Verify(ctx, r.repo, r.checkpointKey)
+}
+
+// logFor возвращает логер компонента audit с атрибутами запроса из контекста
+func (r *Recorder) logFor(ctx context.Context) *slog.Logger {
+	return logger.Component(logger.FromContext(ctx, r.log), "audit")
+}
+
+// UserChanges возвращает изменения отслеживаемых полей пользователя.
+// before равен nil при создании пользователя. Хеш пароля не записывается.
+func UserChanges(before, after *models.User) []models.AuditChange {
+	old, updated := userFields(before), userFields(after)
+
+	var changes []models.AuditChange
+	for i, field := range userFieldNames {
+		if old[i] != updated[i] {
+			changes = append(changes, models.AuditChange{Field: field, Old: old[i], New: updated[i]})
+		}
+	}
+	return changes
+}
+
+// userFieldNames отслеживаемые поля пользователя в порядке userFields
+var userFieldNames = []string{"name", "email", "role", "is_active"}
+
+// userFields значения отслеживаемых полей; для nil все значения пустые
+func userFields(user *models.User) []string {
+	if user == nil {
+		return make([]string, len(userFieldNames))
+	}
+	return []string{user.Name, user.Email, user.Role, strconv.FormatBool(user.IsActive)}
+}
+
+// UserUpdateAction выбирает действие для изменения пользователя: блокировка
+// и смена роли выделяются, чтобы их можно было найти фильтром по действию
+func UserUpdateAction(changes []models.AuditChange) string {
+	action := ActionUserUpdate
+	for _, change := range changes {
+		switch change.Field {
+		case "is_active":
+			if change.New == "false" {
+				return ActionUserDeactivate
+			}
+			action = ActionUserActivate
+		case "role":
+			if action == ActionUserUpdate {
+				action = ActionUserRoleChange
+			}
+		}
+	}
+	return action
+}
+
+// WebhookChanges возвращает изменения подписки на webhook. before равен nil при
+// создании, after - при удалении. Секрет не записывается, отмечается только его смена.
+func WebhookChanges(before, after *models.WebhookSubscription) []models.AuditChange {
+	old, updated := webhookFields(before), webhookFields(after)
+
+	var changes []models.AuditChange
+	for i, field := range webhookFieldNames {
+		if old[i] != updated[i] {
+			changes = append(changes, models.AuditChange{Field: field, Old: old[i], New: updated[i]})
+		}
+	}
+	if before != nil && after != nil && before.Secret != after.Secret {
+		changes = append(changes, models.AuditChange{Field: "secret", Old: "***", New: "***"})
+	}
+	return changes
+}
+
+// webhookFieldNames отслеживаемые поля подписки в порядке webhookFields
+var webhookFieldNames = []string{"id", "url", "event_types", "enabled"}
+
+// webhookFields значения отслеживаемых полей; для nil все значения пустые
+func webhookFields(subscription *models.WebhookSubscription) []string {
+	if subscription == nil {
+		return make([]string, len(webhookFieldNames))
+	}
+	return []string{
+		strconv.FormatUint(uint64(subscription.ID), 10),
+		subscription.URL,
+		subscription.EventTypes,
+		strconv.FormatBool(subscription.Enabled),
+	}
+}
+
+// actor возвращает аутентифицированного пользователя из контекста HTTP или gRPC запроса
+func actor(ctx context.Context) (*uint, string) {
+	if claims, ok := auth.GetUserFromContext(ctx); ok {
+		id := claims.UserID
+		return &id, claims.Email
+	}
+	if id, ok := ctx.Value("user_id").(uint); ok {
+		email, _ := ctx.Value("user_email").(string)
+		return &id, email
+	}
+	return nil, ""
+}
+
+// userAgent возвращает User-Agent клиента. Для запросов через gateway это
+// заголовок HTTP клиента, который gateway передает с префиксом grpcgateway-.
+func userAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 {
+			return truncate(values[0], 512)
+		}
+	}
+	return ""
+}
+
+// optionalID возвращает идентификатор для лога, 0 если он не задан
+func optionalID(id *uint) uint {
+	if id == nil {
+		return 0
+	}
+	return *id
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,341 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/requestid"
+)
+
+// memoryRepository журнал аудита в памяти с цепочкой записей как в базе данных
+type memoryRepository struct {
+	events      []*models.AuditEvent
+	checkpoints []*models.AuditCheckpoint
+	head        *models.AuditChainHead
+	anchor      *models.AuditRetentionAnchor
+	createErr   error
+	before      time.Time
+}
+
+// chainStart время первой записи журнала: записи создаются с интервалом в минуту
+var chainStart = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (r *memoryRepository) Create(_ context.Context, event *models.AuditEvent) error {
+	if r.createErr != nil {
+		return r.createErr
+	}
+	if r.head == nil {
+		r.head = &models.AuditChainHead{ID: 1}
+	}
+	event.ID = r.head.EventID + 1
+	event.CreatedAt = chainStart.Add(time.Duration(event.ID) * time.Minute)
+	event.PrevHash = r.head.Hash
+	event.Hash = event.ComputeHash()
+	r.events = append(r.events, event)
+	r.head.EventID, r.head.Hash = event.ID, event.Hash
+	return nil
+}
+
+func (r *memoryRepository) List(context.Context, repository.AuditFilter) ([]*models.AuditEvent, error) {
+	return r.events, nil
+}
+
+func (r *memoryRepository) Scan(_ context.Context, afterID uint, limit int) ([]*models.AuditEvent, error) {
+	var events []*models.AuditEvent
+	for _, event := range r.events {
+		if event.ID > afterID && len(events) < limit {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (r *memoryRepository) DeleteBefore(_ context.Context, before time.Time, key []byte) (int64, error) {
+	r.before = before
+	var deleted int
+	for deleted < len(r.events) && r.events[deleted].CreatedAt.Before(before) {
+		deleted++
+	}
+	if deleted == 0 {
+		return 0, nil
+	}
+
+	last := r.events[deleted-1]
+	r.anchor = &models.AuditRetentionAnchor{ID: 1, EventID: last.ID, Hash: last.Hash}
+	r.anchor.Signature = r.anchor.Sign(key)
+	r.events = r.events[deleted:]
+	var checkpoints []*models.AuditCheckpoint
+	for _, checkpoint := range r.checkpoints {
+		if checkpoint.EventID > last.ID {
+			checkpoints = append(checkpoints, checkpoint)
+		}
+	}
+	r.checkpoints = checkpoints
+	return int64(deleted), nil
+}
+
+func (r *memoryRepository) Anchor(context.Context) (*models.AuditRetentionAnchor, error) {
+	return r.anchor, nil
+}
+
+func (r *memoryRepository) Head(context.Context) (*models.AuditChainHead, error) {
+	return r.head, nil
+}
+
+func (r *memoryRepository) CreateCheckpoint(_ context.Context, checkpoint *models.AuditCheckpoint) error {
+	checkpoint.ID = uint(len(r.checkpoints) + 1)
+	r.checkpoints = append(r.checkpoints, checkpoint)
+	return nil
+}
+
+func (r *memoryRepository) LastCheckpoint(context.Context) (*models.AuditCheckpoint, error) {
+	if len(r.checkpoints) == 0 {
+		return nil, nil
+	}
+	return r.checkpoints[len(r.checkpoints)-1], nil
+}
+
+func (r *memoryRepository) ListCheckpoints(context.Context) ([]*models.AuditCheckpoint, error) {
+	return r.checkpoints, nil
+}
+
+func TestRecorder_FillsRequestData(t *testing.T) {
+	repo := &memoryRepository{}
+	recorder := NewRecorder(repo, nil, logger.Discard())
+
+	ctx := context.WithValue(context.Background(), "user_id", uint(7))
+	ctx = context.WithValue(ctx, "user_email", "admin@example.com")
+	ctx = requestid.NewContext(ctx, "req-1")
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("grpcgateway-user-agent", "Mozilla/5.0", "user-agent", "grpc-go/1.73"))
+
+	recorder.Record(ctx, &models.AuditEvent{Action: ActionUserCreate})
+
+	require.Len(t, repo.events, 1)
+	event := repo.events[0]
+	require.NotNil(t, event.ActorID)
+	assert.Equal(t, uint(7), *event.ActorID)
+	assert.Equal(t, "admin@example.com", event.ActorEmail)
+	assert.Equal(t, "req-1", event.RequestID)
+	assert.Equal(t, "Mozilla/5.0", event.UserAgent)
+
+	// Ошибка записи не прерывает действие
+	repo.createErr = errors.New("db down")
+	recorder.Record(context.Background(), &models.AuditEvent{Action: ActionLoginFailed})
+	assert.Len(t, repo.events, 1)
+}
+
+func TestUserChanges(t *testing.T) {
+	before := &models.User{ID: 1, Name: "Ann", Email: "ann@example.com", Role: "user", IsActive: true}
+
+	created := UserChanges(nil, before)
+	assert.Equal(t, []models.AuditChange{
+		{Field: "name", New: "Ann"},
+		{Field: "email", New: "ann@example.com"},
+		{Field: "role", New: "user"},
+		{Field: "is_active", New: "true"},
+	}, created)
+
+	after := *before
+	after.Role = "admin"
+	changes := UserChanges(before, &after)
+	assert.Equal(t, []models.AuditChange{{Field: "role", Old: "user", New: "admin"}}, changes)
+	assert.Equal(t, ActionUserRoleChange, UserUpdateAction(changes))
+
+	after.IsActive = false
+	assert.Equal(t, ActionUserDeactivate, UserUpdateAction(UserChanges(before, &after)))
+
+	after = *before
+	after.Name = "Anna"
+	assert.Equal(t, ActionUserUpdate, UserUpdateAction(UserChanges(before, &after)))
+}
+
+func TestRetention_Cleanup(t *testing.T) {
+	key := []byte("checkpoint-key")
+	repo := newChain(t, key, 5)
+	retention := NewRetention(repo, 0, key, logger.Discard())
+	now := chainStart.Add(30*24*time.Hour + 3*time.Minute + time.Second)
+
+	// Нулевой срок хранения отключает удаление
+	deleted, err := retention.Cleanup(context.Background(), now)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+	assert.True(t, repo.before.IsZero())
+
+	retention.Update(30 * 24 * time.Hour)
+	deleted, err = retention.Cleanup(context.Background(), now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.Equal(t, chainStart.Add(3*time.Minute+time.Second), repo.before)
+
+	// Якорь ссылается на последнюю удаленную запись, цепочка остается целой
+	require.NotNil(t, repo.anchor)
+	assert.Equal(t, uint(3), repo.anchor.EventID)
+	result, err := Verify(context.Background(), repo, key)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, uint(4), result.FirstEventID)
+}
+
+// newChain создает журнал из n записей с контрольной точкой на последней
+func newChain(t *testing.T, key []byte, n int) *memoryRepository {
+	t.Helper()
+	repo := &memoryRepository{}
+	recorder := NewRecorder(repo, key, logger.Discard())
+	for i := 0; i < n; i++ {
+		recorder.Record(context.Background(), &models.AuditEvent{Action: ActionLogin, TargetEmail: "user@example.com"})
+	}
+	_, err := NewCheckpointer(repo, key, logger.Discard()).Checkpoint(context.Background())
+	require.NoError(t, err)
+	return repo
+}
+
+func TestVerify(t *testing.T) {
+	key := []byte("checkpoint-key")
+	ctx := context.Background()
+
+	repo := newChain(t, key, 5)
+	result, err := Verify(ctx, repo, key)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, int64(5), result.EventsChecked)
+	assert.Equal(t, 1, result.CheckpointsChecked)
+	assert.True(t, result.SignaturesVerified)
+
+	// Повторная контрольная точка без новых записей не создается
+	checkpoint, err := NewCheckpointer(repo, key, logger.Discard()).Checkpoint(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, checkpoint)
+
+	t.Run("изменение записи", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		repo.events[2].Action = ActionUserCreate
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+	})
+
+	t.Run("удаление записи из середины", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		repo.events = append(repo.events[:1], repo.events[2:]...)
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+	})
+
+	t.Run("удаление последних записей", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		repo.events = repo.events[:3]
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(5), result.BrokenEventID)
+	})
+
+	t.Run("пересчет хешей без ключа", func(t *testing.T) {
+		repo := newChain(t, key, 3)
+		repo.events[0].Action = ActionUserCreate
+		prev := ""
+		for _, event := range repo.events {
+			event.PrevHash = prev
+			event.Hash = event.ComputeHash()
+			prev = event.Hash
+		}
+		repo.head.Hash = prev
+		repo.checkpoints[0].Hash = prev
+
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Contains(t, result.Reason, "подпись")
+	})
+
+	t.Run("старые записи удалены по сроку хранения", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		_, err := repo.DeleteBefore(ctx, chainStart.Add(3*time.Minute), key)
+		require.NoError(t, err)
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.True(t, result.Valid, result.Reason)
+		assert.Equal(t, uint(3), result.FirstEventID)
+	})
+
+	t.Run("удалены все записи по сроку хранения", func(t *testing.T) {
+		repo := newChain(t, key, 3)
+		_, err := repo.DeleteBefore(ctx, chainStart.Add(time.Hour), key)
+		require.NoError(t, err)
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.True(t, result.Valid, result.Reason)
+	})
+
+	t.Run("удаление первых записей в обход срока хранения", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		repo.events = repo.events[2:]
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+		assert.Contains(t, result.Reason, "якорем")
+	})
+
+	t.Run("удаление первых записей после удаления по сроку хранения", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		_, err := repo.DeleteBefore(ctx, chainStart.Add(2*time.Minute), key)
+		require.NoError(t, err)
+		repo.events = repo.events[2:]
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(4), result.BrokenEventID)
+	})
+
+	t.Run("удаление всех записей в обход срока хранения", func(t *testing.T) {
+		repo := newChain(t, key, 3)
+		repo.events = nil
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+	})
+
+	t.Run("поддельный якорь", func(t *testing.T) {
+		repo := newChain(t, key, 5)
+		repo.events = repo.events[2:]
+		repo.anchor = &models.AuditRetentionAnchor{ID: 1, EventID: 2, Hash: repo.events[0].PrevHash}
+		repo.anchor.Signature = repo.anchor.Sign([]byte("другой ключ"))
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Contains(t, result.Reason, "подпись якоря")
+	})
+
+	t.Run("контрольная точка на удаленную запись", func(t *testing.T) {
+		repo := newChain(t, key, 3)
+		recorder := NewRecorder(repo, key, logger.Discard())
+		recorder.Record(ctx, &models.AuditEvent{Action: ActionLogin})
+		_, err := NewCheckpointer(repo, key, logger.Discard()).Checkpoint(ctx)
+		require.NoError(t, err)
+
+		// Записи до первой контрольной точки удалены, а сама точка осталась
+		_, err = repo.DeleteBefore(ctx, chainStart.Add(4*time.Minute), key)
+		require.NoError(t, err)
+		repo.checkpoints = append([]*models.AuditCheckpoint{{ID: 1, EventID: 3, Hash: repo.anchor.Hash}}, repo.checkpoints...)
+		repo.checkpoints[0].Signature = repo.checkpoints[0].Sign(key)
+		result, err := Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+		assert.Contains(t, result.Reason, "отсутствует")
+	})
+}
@@ -0,0 +1,222 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// DefaultCheckpointInterval период создания контрольных точек цепочки
+const DefaultCheckpointInterval = time.Hour
+
+// verifyBatchSize количество событий, читаемых за один запрос при проверке
+const verifyBatchSize = 1000
+
+// VerifyResult результат проверки цепочки журнала аудита
+type VerifyResult struct {
+	Valid              bool
+	EventsChecked      int64
+	CheckpointsChecked int
+	// FirstEventID и LastEventID границы проверенной части цепочки
+	FirstEventID uint
+	LastEventID  uint
+	// BrokenEventID первая запись, на которой цепочка нарушена
+	BrokenEventID uint
+	Reason        string
+	// SignaturesVerified false, если ключ подписи не задан и проверены только хеши
+	SignaturesVerified bool
+}
+
+// Verify проверяет цепочку журнала от самой старой записи до последней и
+// возвращает первое нарушение. Проверяется, что хеш каждой записи соответствует
+// ее содержимому, PrevHash совпадает с хешем предыдущей записи, контрольные точки
+// подписаны ключом key и указывают на существующие записи с тем же хешем,
+// а последняя запись совпадает с audit_chain_head.
+//
+// Записи старше срока хранения удаляются вместе с началом цепочки, а хеш последней
+// удаленной записи сохраняется в подписанном якоре: первая сохранившаяся запись
+// должна ссылаться на него, а без якоря - начинать цепочку. Так удаление начала
+// журнала в обход срока хранения тоже обнаруживается. Записи без хеша в начале
+// журнала созданы до включения цепочки и пропускаются. При пустом key подписи
+// не проверяются.
+func Verify(ctx context.Context, repo repository.AuditRepository, key []byte) (*VerifyResult, error) {
+	result := &VerifyResult{SignaturesVerified: len(key) > 0}
+
+	// Контрольные точки читаются до последней записи, а записи - не дальше нее,
+	// поэтому события, добавленные во время проверки, не считаются нарушением
+	checkpointList, err := repo.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head, err := repo.Head(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if head == nil || head.EventID == 0 {
+		result.Valid = true
+		return result, nil
+	}
+
+	anchor, err := repo.Anchor(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make(map[uint]*models.AuditCheckpoint, len(checkpointList))
+	for _, checkpoint := range checkpointList {
+		checkpoints[checkpoint.EventID] = checkpoint
+	}
+	checked := make(map[uint]bool, len(checkpointList))
+
+	broken := func(id uint, format string, args ...interface{}) (*VerifyResult, error) {
+		result.BrokenEventID = id
+		result.Reason = fmt.Sprintf(format, args...)
+		return result, nil
+	}
+
+	// Без якоря записи не удалялись и первая запись начинает цепочку
+	var prevHash string
+	if anchor != nil {
+		if len(key) > 0 && !anchor.VerifySignature(key) {
+			return broken(anchor.EventID, "неверная подпись якоря начала цепочки на запись %d", anchor.EventID)
+		}
+		prevHash = anchor.Hash
+	}
+
+	var afterID uint
+scan:
+	for afterID < head.EventID {
+		events, err := repo.Scan(ctx, afterID, verifyBatchSize)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range events {
+			if event.ID > head.EventID {
+				break scan
+			}
+			afterID = event.ID
+			if result.FirstEventID == 0 {
+				if event.Hash == "" {
+					continue
+				}
+				result.FirstEventID = event.ID
+				if event.PrevHash != prevHash {
+					return broken(event.ID, "prev_hash первой записи %d не совпадает с якорем начала цепочки: начало журнала удалено в обход срока хранения", event.ID)
+				}
+			} else if event.PrevHash != prevHash {
+				return broken(event.ID, "prev_hash записи %d не совпадает с хешем записи %d: запись удалена или изменена", event.ID, result.LastEventID)
+			}
+			if event.Hash != event.ComputeHash() {
+				return broken(event.ID, "хеш записи %d не соответствует содержимому: запись изменена", event.ID)
+			}
+
+			if checkpoint, ok := checkpoints[event.ID]; ok {
+				if checkpoint.Hash != event.Hash {
+					return broken(event.ID, "хеш записи %d не совпадает с контрольной точкой %d", event.ID, checkpoint.ID)
+				}
+				if len(key) > 0 && !checkpoint.VerifySignature(key) {
+					return broken(event.ID, "неверная подпись контрольной точки %d", checkpoint.ID)
+				}
+				checked[checkpoint.EventID] = true
+				result.CheckpointsChecked++
+			}
+
+			prevHash = event.Hash
+			result.LastEventID = event.ID
+			result.EventsChecked++
+		}
+
+		if len(events) < verifyBatchSize {
+			break
+		}
+	}
+
+	// Пустой журнал допустим, только если все записи удалены по сроку хранения
+	if result.EventsChecked == 0 {
+		if anchor == nil || anchor.EventID != head.EventID || anchor.Hash != head.Hash {
+			return broken(head.EventID, "записи цепочки до %d отсутствуют: журнал удален в обход срока хранения", head.EventID)
+		}
+		result.Valid = true
+		return result, nil
+	}
+
+	// Контрольные точки на удаленные по сроку хранения записи удаляются вместе с ними,
+	// поэтому контрольная точка на отсутствующую запись означает удаление записей
+	for _, checkpoint := range checkpointList {
+		if checked[checkpoint.EventID] {
+			continue
+		}
+		if checkpoint.EventID > result.LastEventID {
+			return broken(checkpoint.EventID, "запись %d контрольной точки %d отсутствует: последние записи удалены", checkpoint.EventID, checkpoint.ID)
+		}
+		return broken(checkpoint.EventID, "запись %d контрольной точки %d отсутствует: запись удалена в обход срока хранения", checkpoint.EventID, checkpoint.ID)
+	}
+
+	if head.EventID != result.LastEventID || head.Hash != prevHash {
+		return broken(head.EventID, "последняя запись цепочки %d не совпадает с audit_chain_head (%d): последние записи удалены или изменены", result.LastEventID, head.EventID)
+	}
+
+	result.Valid = true
+	return result, nil
+}
+
+// Checkpointer периодически создает подписанные контрольные точки цепочки.
+// Контрольная точка фиксирует хеш последней записи: чтобы незаметно изменить
+// записи до нее, нужно знать ключ подписи.
+type Checkpointer struct {
+	repo repository.AuditRepository
+	key  []byte
+	log  *slog.Logger
+}
+
+// NewCheckpointer создает Checkpointer, подписывающий контрольные точки ключом key
+func NewCheckpointer(repo repository.AuditRepository, key []byte, log *slog.Logger) *Checkpointer {
+	return &Checkpointer{repo: repo, key: key, log: logger.Component(log, "audit")}
+}
+
+// Run создает контрольную точку каждые interval до отмены ctx
+func (c *Checkpointer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Checkpoint(ctx); err != nil {
+				c.log.Warn("Ошибка создания контрольной точки аудита", logger.Err(err))
+			}
+		}
+	}
+}
+
+// Checkpoint подписывает последнюю запись цепочки. Если новых записей
+// с прошлой контрольной точки не было, возвращает nil.
+func (c *Checkpointer) Checkpoint(ctx context.Context) (*models.AuditCheckpoint, error) {
+	head, err := c.repo.Head(ctx)
+	if err != nil || head == nil || head.EventID == 0 {
+		return nil, err
+	}
+	last, err := c.repo.LastCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if last != nil && last.EventID == head.EventID {
+		return nil, nil
+	}
+
+	checkpoint := &models.AuditCheckpoint{EventID: head.EventID, Hash: head.Hash}
+	checkpoint.Signature = checkpoint.Sign(c.key)
+	if err := c.repo.CreateCheckpoint(ctx, checkpoint); err != nil {
+		return nil, err
+	}
+	c.log.Info("Создана контрольная точка аудита", "event_id", checkpoint.EventID)
+	return checkpoint, nil
+}
@@ -0,0 +1,71 @@
+package audit
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// DefaultCleanupInterval период удаления устаревших событий
+const DefaultCleanupInterval = time.Hour
+
+// Retention периодически удаляет события старше срока хранения.
+// Срок можно менять на лету; нулевой срок отключает удаление.
+type Retention struct {
+	repo   repository.AuditRepository
+	key    []byte
+	log    *slog.Logger
+	period atomic.Int64
+}
+
+// NewRetention создает задачу удаления событий старше period. Якорь начала
+// цепочки после удаления подписывается ключом контрольных точек key.
+func NewRetention(repo repository.AuditRepository, period time.Duration, key []byte, log *slog.Logger) *Retention {
+	r := &Retention{repo: repo, key: key, log: logger.Component(log, "audit")}
+	r.Update(period)
+	return r
+}
+
+// Update атомарно заменяет срок хранения
+func (r *Retention) Update(period time.Duration) {
+	r.period.Store(int64(period))
+}
+
+// Run удаляет устаревшие события сразу и затем каждые interval до отмены ctx
+func (r *Retention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Cleanup(ctx, time.Now()); err != nil {
+			r.log.Warn("Ошибка удаления устаревших событий аудита", logger.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cleanup удаляет события, созданные раньше now минус срок хранения
+func (r *Retention) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	period := time.Duration(r.period.Load())
+	if period <= 0 {
+		return 0, nil
+	}
+
+	deleted, err := r.repo.DeleteBefore(ctx, now.Add(-period), r.key)
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		r.log.Info("Удалены устаревшие события аудита", "deleted", deleted, "retention", period.String())
+	}
+	return deleted, nil
+}
@@ -3,8 +3,6 @@ package auth
 import (
 	"errors"
 	"fmt"
-	"os"
-	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -34,13 +32,10 @@ type jwtService struct {
 }
 
 // NewJWTService создает новый экземпляр JWT сервиса
-func NewJWTService() JWTService {
-	secretKey := getJWTSecret()
-	expiration := getTokenExpiration()
-
+func NewJWTService(secretKey string, tokenExpiration time.Duration) JWTService {
 	return &jwtService{
 		secretKey:       []byte(secretKey),
-		tokenExpiration: expiration,
+		tokenExpiration: tokenExpiration,
 	}
 }
 
@@ -107,28 +102,3 @@ func (j *jwtService) CheckPassword(hashedPassword, password string) error {
 	}
 	return nil
 }
-
-// getJWTSecret получает секретный ключ из переменных окружения
-func getJWTSecret() string {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		// В production должен быть установлен через переменные окружения
-		secret = "your-super-secret-jwt-key-change-in-production"
-	}
-	return secret
-}
-
-// getTokenExpiration получает время жизни токена из переменных окружения
-func getTokenExpiration() time.Duration {
-	expStr := os.Getenv("JWT_EXPIRATION_HOURS")
-	if expStr == "" {
-		return 24 * time.Hour // По умолчанию 24 часа
-	}
-
-	hours, err := strconv.Atoi(expStr)
-	if err != nil {
-		return 24 * time.Hour
-	}
-
-	return time.Duration(hours) * time.Hour
-}
@@ -2,14 +2,18 @@ package auth
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 	"strings"
+	"sync/atomic"
 
-	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/accesslog"
+	"k8s-go-grpc-react/internal/logger"
 )
 
 // ContextKey тип для ключей контекста
@@ -18,58 +22,72 @@ type ContextKey string
 const (
 	// UserContextKey ключ для пользователя в контексте
 	UserContextKey ContextKey = "user"
+	// SessionCookie имя cookie с JWT токеном платформы. Gateway устанавливает его
+	// в ответах на вход и регистрацию, OIDC провайдер по нему узнает пользователя браузера.
+	SessionCookie = "auth_token"
 )
 
+// DefaultPublicMethods gRPC методы, не требующие аутентификации, по умолчанию
+var DefaultPublicMethods = []string{
+	"/user.UserService/Register",
+	"/user.UserService/Login",
+}
+
 // AuthMiddleware middleware для проверки JWT токенов
 type AuthMiddleware struct {
-	jwtService JWTService
-	logger     *logrus.Logger
+	jwtService    JWTService
+	log           *slog.Logger
+	publicMethods atomic.Pointer[map[string]bool]
 }
 
-// NewAuthMiddleware создает новый экземпляр middleware
-func NewAuthMiddleware() *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtService: NewJWTService(),
-		logger:     logrus.New(),
+// NewAuthMiddleware создает новый экземпляр middleware; записи пишутся в log с компонентом auth
+func NewAuthMiddleware(jwtService JWTService, log *slog.Logger) *AuthMiddleware {
+	m := &AuthMiddleware{
+		jwtService: jwtService,
+		log:        log,
 	}
+	m.SetPublicMethods(DefaultPublicMethods)
+	return m
 }
 
-// NewAuthMiddlewareWithDeps создает новый экземпляр AuthMiddleware с зависимостями
-func NewAuthMiddlewareWithDeps(jwtService JWTService, logger *logrus.Logger) *AuthMiddleware {
-	return &AuthMiddleware{
-		jwtService: jwtService,
-		logger:     logger,
+// SetPublicMethods атомарно заменяет список публичных gRPC методов.
+// Безопасно вызывать во время обработки запросов.
+func (m *AuthMiddleware) SetPublicMethods(methods []string) {
+	publicMethods := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		publicMethods[method] = true
 	}
+	m.publicMethods.Store(&publicMethods)
+}
+
+// logFor возвращает логер компонента auth с атрибутами запроса из контекста
+func (m *AuthMiddleware) logFor(ctx context.Context) *slog.Logger {
+	return logger.Component(logger.FromContext(ctx, m.log), "auth")
 }
 
 // UnaryInterceptor возвращает unary interceptor для аутентификации
 func (m *AuthMiddleware) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-	// Публичные методы, не требующие аутентификации
-	publicMethods := map[string]bool{
-		"/user.UserService/Register": true,
-		"/user.UserService/Login":    true,
-	}
-
 	// Если метод публичный, пропускаем аутентификацию
-	if publicMethods[info.FullMethod] {
+	if (*m.publicMethods.Load())[info.FullMethod] {
 		return handler(ctx, req)
 	}
 
 	// Извлекаем токен из метаданных
 	token, err := m.extractTokenFromMetadata(ctx)
 	if err != nil {
-		m.logger.WithError(err).Warn("Ошибка извлечения токена")
+		m.logFor(ctx).Warn("Ошибка извлечения токена", logger.Err(err))
 		return nil, status.Error(codes.Unauthenticated, "Токен не предоставлен")
 	}
 
 	// Валидируем токен
 	claims, err := m.jwtService.ValidateToken(token)
 	if err != nil {
-		m.logger.WithError(err).Warn("Недействительный токен")
+		m.logFor(ctx).Warn("Недействительный токен", logger.Err(err))
 		return nil, status.Error(codes.Unauthenticated, "Недействительный токен")
 	}
 
 	// Добавляем информацию о пользователе в контекст
+	accesslog.SetUserID(ctx, claims.UserID)
 	ctx = context.WithValue(ctx, "user_id", claims.UserID)
 	ctx = context.WithValue(ctx, "user_email", claims.Email)
 	ctx = context.WithValue(ctx, "user_role", claims.Role)
@@ -125,6 +143,7 @@ func RequireAuth(jwtService JWTService) grpc.UnaryServerInterceptor {
 		}
 
 		// Добавляем информацию о пользователе в контекст
+		accesslog.SetUserID(ctx, claims.UserID)
 		ctx = context.WithValue(ctx, "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
 		ctx = context.WithValue(ctx, "user_role", claims.Role)
@@ -161,6 +180,7 @@ func OptionalAuth(jwtService JWTService) grpc.UnaryServerInterceptor {
 		}
 
 		// Добавляем информацию о пользователе в контекст
+		accesslog.SetUserID(ctx, claims.UserID)
 		ctx = context.WithValue(ctx, "user_id", claims.UserID)
 		ctx = context.WithValue(ctx, "user_email", claims.Email)
 		ctx = context.WithValue(ctx, "user_role", claims.Role)
@@ -196,19 +216,20 @@ func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		token := m.extractToken(r)
 		if token == "" {
-			m.logger.WithField("path", r.URL.Path).Warn("Missing authorization token")
+			m.logFor(r.Context()).Warn("Missing authorization token", "path", r.URL.Path)
 			http.Error(w, "Authorization token required", http.StatusUnauthorized)
 			return
 		}
 
 		claims, err := m.jwtService.ValidateToken(token)
 		if err != nil {
-			m.logger.WithError(err).WithField("path", r.URL.Path).Warn("Invalid token")
+			m.logFor(r.Context()).Warn("Invalid token", "path", r.URL.Path, logger.Err(err))
 			http.Error(w, "Invalid token", http.StatusUnauthorized)
 			return
 		}
 
 		// Добавляем информацию о пользователе в контекст
+		accesslog.SetUserID(r.Context(), claims.UserID)
 		ctx := context.WithValue(r.Context(), UserContextKey, claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
@@ -222,6 +243,7 @@ func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
 			claims, err := m.jwtService.ValidateToken(token)
 			if err == nil {
 				// Если токен валидный, добавляем пользователя в контекст
+				accesslog.SetUserID(r.Context(), claims.UserID)
 				ctx := context.WithValue(r.Context(), UserContextKey, claims)
 				r = r.WithContext(ctx)
 			}
@@ -236,7 +258,7 @@ func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Ha
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			claims, ok := r.Context().Value(UserContextKey).(*Claims)
 			if !ok {
-				m.logger.WithField("path", r.URL.Path).Warn("User not authenticated")
+				m.logFor(r.Context()).Warn("User not authenticated", "path", r.URL.Path)
 				http.Error(w, "Authentication required", http.StatusUnauthorized)
 				return
 			}
@@ -251,12 +273,12 @@ func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Ha
 			}
 
 			if !hasRole {
-				m.logger.WithFields(logrus.Fields{
-					"user_id":        claims.UserID,
-					"user_role":      claims.Role,
-					"required_roles": roles,
-					"path":           r.URL.Path,
-				}).Warn("Insufficient permissions")
+				m.logFor(r.Context()).Warn("Insufficient permissions",
+					"user_id", claims.UserID,
+					"user_role", claims.Role,
+					"required_roles", roles,
+					"path", r.URL.Path,
+				)
 				http.Error(w, "Insufficient permissions", http.StatusForbidden)
 				return
 			}
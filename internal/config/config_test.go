@@ -0,0 +1,166 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+listen:
+  grpc_port: "9000"
+  http_port: "9001"
+database:
+  host: db.internal
+jwt:
+  expiration_hours: 12
+`)
+
+	// Переменная окружения переопределяет файл, флаг переопределяет переменную окружения
+	t.Setenv("HTTP_PORT", "9101")
+	t.Setenv("GRPC_PORT", "9100")
+
+	cfg, err := Load(ComponentServer, []string{"-config", path, "-grpc-port", "9200"})
+	require.NoError(t, err)
+
+	assert.Equal(t, "9200", cfg.Listen.GRPCPort)
+	assert.Equal(t, "9101", cfg.Listen.HTTPPort)
+	assert.Equal(t, "db.internal", cfg.Database.Host)
+	assert.Equal(t, 12, cfg.JWT.ExpirationHours)
+	assert.Equal(t, "k8s_grpc_db", cfg.Database.Name)
+}
+
+func TestLoad_ExampleFile(t *testing.T) {
+	cfg, err := Load(ComponentServer, []string{"-config", "../../config/config.example.yaml"})
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_TOML(t *testing.T) {
+	path := writeConfigFile(t, "config.toml", `
+environment = "staging"
+
+[gateway]
+grpc_server_addr = "grpc-server:8080"
+`)
+
+	cfg, err := Load(ComponentGateway, []string{"-config", path})
+	require.NoError(t, err)
+
+	assert.Equal(t, EnvStaging, cfg.Environment)
+	assert.Equal(t, "grpc-server:8080", cfg.Gateway.GRPCServerAddr)
+	assert.Equal(t, DefaultStandaloneGatewayPort, cfg.Listen.HTTPPort)
+}
+
+func TestLoad_UnknownKeysRejected(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "listen:\n  grcp_port: \"9000\"\n")
+
+	_, err := Load(ComponentServer, []string{"-config", path})
+	assert.Error(t, err)
+}
+
+func TestLoad_SecretFromFile(t *testing.T) {
+	secretFile := writeConfigFile(t, "jwt_secret", "secret-from-file-0123456789abcdef\n")
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+
+	cfg, err := Load(ComponentServer, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-from-file-0123456789abcdef", cfg.JWT.Secret)
+
+	// Одновременное указание значения и файла считается ошибкой
+	t.Setenv("JWT_SECRET", "inline")
+	_, err = Load(ComponentServer, nil)
+	assert.Error(t, err)
+}
+
+func TestLoad_ProductionRejectsDefaults(t *testing.T) {
+	t.Setenv("APP_ENV", EnvProduction)
+
+	_, err := Load(ComponentServer, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "секрет JWT по умолчанию")
+	assert.Contains(t, err.Error(), "пароль базы данных по умолчанию")
+	assert.Contains(t, err.Error(), "cors.allowed_origins")
+	assert.Contains(t, err.Error(), "logging.redaction.hash_key")
+	assert.Contains(t, err.Error(), "audit.checkpoint_key")
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com")
+	t.Setenv("LOG_REDACT_HASH_KEY", "shared-redaction-key")
+	t.Setenv("AUDIT_CHECKPOINT_KEY", "audit-checkpoint-key")
+
+	// Пароль по умолчанию в DATABASE_URL тоже отклоняется
+	t.Setenv("JWT_SECRET", "a-very-long-production-secret-value-0123")
+	t.Setenv("DATABASE_URL", "postgres://user:password@db:5432/app")
+	_, err = Load(ComponentServer, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "пароль базы данных по умолчанию")
+
+	t.Setenv("DATABASE_URL", "postgres://user:s3cret@db:5432/app")
+	cfg, err := Load(ComponentServer, nil)
+	require.NoError(t, err)
+	assert.True(t, cfg.IsProduction())
+
+	t.Setenv("WEBHOOKS_ALLOW_PRIVATE_NETWORKS", "true")
+	_, err = Load(ComponentServer, nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "webhooks.allow_private_networks")
+	t.Setenv("WEBHOOKS_ALLOW_PRIVATE_NETWORKS", "false")
+
+	// Gateway не использует секреты сервера, поэтому их значения для него не проверяются
+	_, err = Load(ComponentGateway, nil)
+	assert.NoError(t, err)
+}
+
+func TestValidate_CORS(t *testing.T) {
+	cfg := Default()
+	cfg.CORS.AllowedOrigins = []string{"https://*.example.com", "http://localhost:3000"}
+	cfg.CORS.AllowCredentials = true
+	assert.NoError(t, cfg.Validate(ComponentServer))
+
+	cfg.CORS.AllowedOrigins = []string{"*"}
+	err := cfg.Validate(ComponentServer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "allow_credentials")
+
+	cfg.CORS.AllowCredentials = false
+	cfg.CORS.AllowedOrigins = []string{"app.example.com", "https://*.*.example.com", "https://app.example.com/"}
+	err = cfg.Validate(ComponentServer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `неверный origin "app.example.com"`)
+	assert.Contains(t, err.Error(), "больше одного")
+	assert.Contains(t, err.Error(), `неверный origin "https://app.example.com/"`)
+}
+
+func TestValidate_OIDCRequiresKeyEncryption(t *testing.T) {
+	cfg := Default()
+	cfg.OIDC.Enabled = true
+	err := cfg.Validate(ComponentServer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "oidc.signing_key_encryption_key")
+
+	cfg.OIDC.SigningKeyEncryptionKey = "key"
+	assert.NoError(t, cfg.Validate(ComponentServer))
+}
+
+func TestValidate_KafkaPublisher(t *testing.T) {
+	cfg := Default()
+	cfg.Events.Publisher = "kafka"
+	cfg.Events.KafkaTopic = ""
+	err := cfg.Validate(ComponentServer)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "events.kafka_brokers")
+	assert.Contains(t, err.Error(), "events.kafka_topic")
+
+	cfg.Events.KafkaBrokers = []string{"kafka-0:9092", "kafka-1:9092"}
+	cfg.Events.KafkaTopic = "user-events"
+	assert.NoError(t, cfg.Validate(ComponentServer))
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// maskedValue значение секретного поля в логах
+const maskedValue = "******"
+
+// Change изменение одного параметра конфигурации
+type Change struct {
+	Path       string
+	Old        string
+	New        string
+	Reloadable bool
+}
+
+// String возвращает описание изменения для логов
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff возвращает список изменений между двумя конфигурациями.
+// Значения секретных полей маскируются.
+func Diff(oldCfg, newCfg *Config) []Change {
+	var changes []Change
+	var walk func(prefix string, oldV, newV reflect.Value)
+	walk = func(prefix string, oldV, newV reflect.Value) {
+		t := oldV.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			path := fieldPath(prefix, sf)
+
+			if sf.Type.Kind() == reflect.Struct {
+				walk(path, oldV.Field(i), newV.Field(i))
+				continue
+			}
+
+			oldField, newField := oldV.Field(i), newV.Field(i)
+			if reflect.DeepEqual(oldField.Interface(), newField.Interface()) {
+				continue
+			}
+
+			change := Change{
+				Path:       path,
+				Old:        formatField(oldField),
+				New:        formatField(newField),
+				Reloadable: sf.Tag.Get("reload") == "true",
+			}
+			if sf.Tag.Get("secret") == "true" {
+				change.Old, change.New = maskedValue, maskedValue
+			}
+			changes = append(changes, change)
+		}
+	}
+	walk("", reflect.ValueOf(oldCfg).Elem(), reflect.ValueOf(newCfg).Elem())
+	return changes
+}
+
+// revertNonReloadable возвращает в newCfg значения полей, которые нельзя менять без перезапуска
+func revertNonReloadable(oldCfg, newCfg *Config) {
+	var walk func(oldV, newV reflect.Value)
+	walk = func(oldV, newV reflect.Value) {
+		t := oldV.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(oldV.Field(i), newV.Field(i))
+				continue
+			}
+			if sf.Tag.Get("reload") != "true" {
+				newV.Field(i).Set(oldV.Field(i))
+			}
+		}
+	}
+	walk(reflect.ValueOf(oldCfg).Elem(), reflect.ValueOf(newCfg).Elem())
+}
+
+// fieldPath возвращает путь к полю в формате файла конфигурации
+func fieldPath(prefix string, sf reflect.StructField) string {
+	name := strings.Split(sf.Tag.Get("yaml"), ",")[0]
+	if name == "" {
+		name = strings.ToLower(sf.Name)
+	}
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
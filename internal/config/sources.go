@@ -0,0 +1,162 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// fileEnvSuffix суффикс переменной окружения, значение которой читается из файла
+const fileEnvSuffix = "_FILE"
+
+// field поле конфигурации, доступное для переопределения
+type field struct {
+	value reflect.Value
+	env   string
+	flag  string
+}
+
+// fields возвращает все листовые поля конфигурации с тегами env или flag
+func fields(cfg *Config) []field {
+	var result []field
+	var walk func(v reflect.Value)
+	walk = func(v reflect.Value) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			fv := v.Field(i)
+			if sf.Type.Kind() == reflect.Struct {
+				walk(fv)
+				continue
+			}
+			env, flagName := sf.Tag.Get("env"), sf.Tag.Get("flag")
+			if env != "" || flagName != "" {
+				result = append(result, field{value: fv, env: env, flag: flagName})
+			}
+		}
+	}
+	walk(reflect.ValueOf(cfg).Elem())
+	return result
+}
+
+// applyEnv переопределяет конфигурацию переменными окружения
+func applyEnv(cfg *Config) error {
+	for _, f := range fields(cfg) {
+		if f.env == "" {
+			continue
+		}
+
+		raw, ok, err := lookupEnv(f.env)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+
+		if err := setField(f.value, raw); err != nil {
+			return fmt.Errorf("неверное значение %s: %w", f.env, err)
+		}
+	}
+	return nil
+}
+
+// lookupEnv читает переменную окружения или файл, указанный в переменной с суффиксом _FILE
+func lookupEnv(key string) (string, bool, error) {
+	value, hasValue := os.LookupEnv(key)
+	path, hasFile := os.LookupEnv(key + fileEnvSuffix)
+
+	switch {
+	case hasFile && path != "":
+		if hasValue && value != "" {
+			return "", false, fmt.Errorf("заданы одновременно %s и %s%s", key, key, fileEnvSuffix)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("ошибка чтения %s%s: %w", key, fileEnvSuffix, err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), true, nil
+	case hasValue && value != "":
+		return value, true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// registerFlags регистрирует флаги командной строки для полей с тегом flag
+func registerFlags(fs *flag.FlagSet, defaults *Config) map[string]*string {
+	values := make(map[string]*string)
+	for _, f := range fields(defaults) {
+		if f.flag == "" {
+			continue
+		}
+		usage := "значение параметра конфигурации"
+		if f.env != "" {
+			usage = "переопределяет " + f.env
+		}
+		values[f.flag] = fs.String(f.flag, formatField(f.value), usage)
+	}
+	return values
+}
+
+// applyFlags переопределяет конфигурацию явно заданными флагами
+func applyFlags(cfg *Config, fs *flag.FlagSet, values map[string]*string) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	for _, f := range fields(cfg) {
+		if f.flag == "" || !set[f.flag] {
+			continue
+		}
+		if err := setField(f.value, *values[f.flag]); err != nil {
+			return fmt.Errorf("неверное значение флага -%s: %w", f.flag, err)
+		}
+	}
+	return nil
+}
+
+// setField присваивает полю значение из строки
+func setField(v reflect.Value, raw string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		v.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("неподдерживаемый тип %s", v.Type())
+	}
+	return nil
+}
+
+// formatField возвращает строковое представление значения поля
+func formatField(v reflect.Value) string {
+	if v.Kind() == reflect.Slice {
+		items := make([]string, v.Len())
+		for i := range items {
+			items[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(items, ",")
+	}
+	return fmt.Sprint(v.Interface())
+}
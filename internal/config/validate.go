@@ -0,0 +1,348 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// Validate проверяет конфигурацию компонента и возвращает все найденные ошибки.
+// В production запуск с секретами по умолчанию запрещен.
+func (c *Config) Validate(component Component) error {
+	var errs []error
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	switch c.Environment {
+	case EnvDevelopment, EnvStaging, EnvProduction:
+	default:
+		addErr("environment: неизвестное окружение %q", c.Environment)
+	}
+
+	if !validPort(c.Listen.HTTPPort) {
+		addErr("listen.http_port: неверный порт %q", c.Listen.HTTPPort)
+	}
+
+	switch component {
+	case ComponentServer:
+		if !validPort(c.Listen.GRPCPort) {
+			addErr("listen.grpc_port: неверный порт %q", c.Listen.GRPCPort)
+		}
+		if c.JWT.Secret == "" {
+			addErr("jwt.secret: секрет не задан")
+		}
+		if c.JWT.ExpirationHours <= 0 {
+			addErr("jwt.expiration_hours: должно быть больше нуля")
+		}
+		if c.Database.URL == "" && c.Database.Host == "" {
+			addErr("database: не задан ни url, ни host")
+		}
+		errs = append(errs, c.TLS.validate()...)
+		if c.OIDC.Enabled {
+			if u, err := url.Parse(c.OIDC.Issuer); err != nil || u.Host == "" {
+				addErr("oidc.issuer: неверный URL %q", c.OIDC.Issuer)
+			} else if c.IsProduction() && u.Scheme != "https" {
+				addErr("oidc.issuer: в production требуется https")
+			}
+			// Без шифрования ключи подписи ID токенов лежат в базе данных открытым текстом
+			if c.OIDC.SigningKeyEncryptionKey == "" {
+				addErr("oidc.signing_key_encryption_key: ключ шифрования ключей подписи не задан")
+			}
+		}
+	case ComponentGateway:
+		if _, _, err := net.SplitHostPort(c.Gateway.GRPCServerAddr); err != nil {
+			addErr("gateway.grpc_server_addr: неверный адрес %q", c.Gateway.GRPCServerAddr)
+		}
+	}
+
+	for i, rewrite := range c.Gateway.Rewrites {
+		if !strings.HasPrefix(rewrite.From, "/api/") || !strings.HasPrefix(rewrite.To, "/api/") {
+			addErr("gateway.rewrites[%d]: пути должны начинаться с /api/", i)
+		}
+		if strings.TrimSuffix(rewrite.From, "/") == strings.TrimSuffix(rewrite.To, "/") {
+			addErr("gateway.rewrites[%d]: from и to совпадают", i)
+		}
+	}
+
+	if _, err := logger.ParseLevel(c.Logging.Level); err != nil {
+		addErr("logging.level: неизвестный уровень %q", c.Logging.Level)
+	} else if _, _, err := logger.ParseLevels(c.Logging.Level, c.Logging.Levels); err != nil {
+		addErr("logging.levels: %v", err)
+	}
+	if c.Logging.Format != logger.FormatJSON && c.Logging.Format != logger.FormatText {
+		addErr("logging.format: неизвестный формат %q", c.Logging.Format)
+	}
+	if c.RateLimit.RequestsPerSecond < 0 || c.RateLimit.Burst < 0 {
+		addErr("rate_limit: значения не могут быть отрицательными")
+	}
+	if c.RateLimit.RequestsPerSecond > 0 && c.RateLimit.Burst == 0 {
+		addErr("rate_limit.burst: должен быть больше нуля при включенном ограничении")
+	}
+
+	switch c.Logging.GraylogTransport {
+	case logger.TransportUDP, logger.TransportTCP, logger.TransportTLS:
+	default:
+		addErr("logging.graylog_transport: неизвестный транспорт %q", c.Logging.GraylogTransport)
+	}
+	if c.Logging.GraylogCAFile != "" && c.Logging.GraylogTransport != logger.TransportTLS {
+		addErr("logging.graylog_ca_file: требует graylog_transport=tls")
+	}
+	if c.Logging.GraylogBufferSize <= 0 {
+		addErr("logging.graylog_buffer_size: должно быть больше нуля")
+	}
+	switch logger.OverflowPolicy(c.Logging.GraylogOverflow) {
+	case logger.OverflowDropOldest, logger.OverflowBlock:
+	default:
+		addErr("logging.graylog_overflow: неизвестное поведение %q", c.Logging.GraylogOverflow)
+	}
+
+	if err := logger.ValidatePatterns(c.Logging.Redaction.MaskPatterns); err != nil {
+		addErr("logging.redaction.mask_patterns: %v", err)
+	}
+	if err := logger.ValidatePatterns(c.Logging.Redaction.HashPatterns); err != nil {
+		addErr("logging.redaction.hash_patterns: %v", err)
+	}
+
+	errs = append(errs, c.CORS.validate()...)
+
+	for _, proxy := range c.AccessLog.TrustedProxies {
+		if _, err := parsePrefix(proxy); err != nil {
+			addErr("access_log.trusted_proxies: неверный адрес %q", proxy)
+		}
+	}
+	for i, sample := range c.AccessLog.Sample {
+		if sample.Prefix == "" {
+			addErr("access_log.sample[%d]: не задан prefix", i)
+		}
+		if sample.Percent < 0 || sample.Percent > 100 {
+			addErr("access_log.sample[%d]: percent должен быть от 0 до 100", i)
+		}
+	}
+
+	if c.Shutdown.DrainDelaySeconds < 0 {
+		addErr("shutdown.drain_delay_seconds: не может быть отрицательным")
+	}
+	if c.Shutdown.TimeoutSeconds <= 0 {
+		addErr("shutdown.timeout_seconds: должно быть больше нуля")
+	}
+
+	if c.Audit.RetentionDays < 0 {
+		addErr("audit.retention_days: не может быть отрицательным")
+	}
+	if c.Audit.CheckpointIntervalMinutes <= 0 {
+		addErr("audit.checkpoint_interval_minutes: должно быть больше нуля")
+	}
+
+	switch c.Events.Publisher {
+	case "", "none", "log":
+	case "file":
+		if c.Events.File == "" {
+			addErr("events.file: не задан для publisher: file")
+		}
+	case "nats":
+		if c.Events.NATSURL == "" {
+			addErr("events.nats_url: не задан для publisher: nats")
+		}
+		if c.Events.SubjectPrefix == "" {
+			addErr("events.subject_prefix: не задан для publisher: nats")
+		}
+	case "kafka":
+		if len(c.Events.KafkaBrokers) == 0 {
+			addErr("events.kafka_brokers: не заданы для publisher: kafka")
+		}
+		if c.Events.KafkaTopic == "" {
+			addErr("events.kafka_topic: не задан для publisher: kafka")
+		}
+	default:
+		addErr("events.publisher: неизвестный способ публикации %q", c.Events.Publisher)
+	}
+	if c.Events.RelayIntervalSeconds <= 0 {
+		addErr("events.relay_interval_seconds: должно быть больше нуля")
+	}
+	if c.Events.MaxAttempts <= 0 {
+		addErr("events.max_attempts: должно быть больше нуля")
+	}
+	if c.Events.RetentionHours < 0 {
+		addErr("events.retention_hours: не может быть отрицательным")
+	}
+
+	if c.Webhooks.TimeoutSeconds <= 0 {
+		addErr("webhooks.timeout_seconds: должно быть больше нуля")
+	}
+	if c.Webhooks.MaxAttempts <= 0 {
+		addErr("webhooks.max_attempts: должно быть больше нуля")
+	}
+	if c.Webhooks.DisableAfterFailures < 0 {
+		addErr("webhooks.disable_after_failures: не может быть отрицательным")
+	}
+	if c.Webhooks.DispatchIntervalSeconds <= 0 {
+		addErr("webhooks.dispatch_interval_seconds: должно быть больше нуля")
+	}
+	if c.Webhooks.RetentionDays < 0 {
+		addErr("webhooks.retention_days: не может быть отрицательным")
+	}
+
+	if c.Users.DeletedRetentionDays < 0 {
+		addErr("users.deleted_retention_days: не может быть отрицательным")
+	}
+
+	if (c.GRPCClientTLS.CertFile == "") != (c.GRPCClientTLS.KeyFile == "") {
+		addErr("grpc_client_tls: cert_file и key_file должны быть заданы вместе")
+	}
+
+	if c.IsProduction() {
+		errs = append(errs, c.productionErrors(component)...)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("неверная конфигурация: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// InsecureDefaults возвращает список небезопасных значений по умолчанию,
+// используемых компонентом. В production такие значения приводят к ошибке Validate.
+func (c *Config) InsecureDefaults(component Component) []string {
+	if component != ComponentServer {
+		return nil
+	}
+
+	var warnings []string
+	if c.JWT.Secret == DefaultJWTSecret {
+		warnings = append(warnings, "используется секрет JWT по умолчанию")
+	}
+	if databasePassword(c.Database) == DefaultDBPassword {
+		warnings = append(warnings, "используется пароль базы данных по умолчанию")
+	}
+	return warnings
+}
+
+// productionErrors проверяет требования безопасности для production
+func (c *Config) productionErrors(component Component) []error {
+	var errs []error
+	for _, warning := range c.InsecureDefaults(component) {
+		errs = append(errs, fmt.Errorf("production: %s", warning))
+	}
+
+	// Без общего ключа хеши одного значения в логах разных процессов не совпадают
+	if c.Logging.Redaction.HashKey == "" {
+		errs = append(errs, errors.New("production: logging.redaction.hash_key не задан"))
+	}
+
+	// Данные в памяти теряются при перезапуске и не видны другим репликам
+	if component == ComponentServer && strings.HasPrefix(c.Database.URL, "memory://") {
+		errs = append(errs, errors.New("production: database.url memory:// недопустим"))
+	}
+
+	// Без ключа контрольные точки журнала аудита не создаются
+	if component == ComponentServer && c.Audit.CheckpointKey == "" {
+		errs = append(errs, errors.New("production: audit.checkpoint_key не задан"))
+	}
+
+	// Подписка на внутренний адрес открывает внутренние сервисы через доставку webhook
+	if component == ComponentServer && c.Webhooks.AllowPrivateNetworks {
+		errs = append(errs, errors.New("production: webhooks.allow_private_networks недопустим"))
+	}
+
+	// В production список origin задается явно для каждого окружения
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			errs = append(errs, errors.New("production: cors.allowed_origins не может содержать \"*\""))
+		}
+	}
+
+	if component == ComponentServer && c.JWT.Secret != DefaultJWTSecret && len(c.JWT.Secret) < minJWTSecretLength {
+		errs = append(errs, fmt.Errorf("production: секрет JWT короче %d байт", minJWTSecretLength))
+	}
+	return errs
+}
+
+// validate проверяет настройки TLS сервера
+func (c TLSConfig) validate() []error {
+	var errs []error
+
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, errors.New("tls: cert_file и key_file должны быть заданы вместе"))
+	}
+
+	switch c.ClientAuth {
+	case "", "none":
+	case "request", "require":
+		if c.ClientCAFile == "" {
+			errs = append(errs, errors.New("tls.client_ca_file: обязателен для проверки клиентских сертификатов"))
+		}
+		if c.CertFile == "" {
+			errs = append(errs, errors.New("tls: проверка клиентских сертификатов требует TLS на сервере"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tls.client_auth: неизвестный режим %q", c.ClientAuth))
+	}
+
+	if len(c.AllowedClientSANs) > 0 && c.ClientAuth != "require" {
+		errs = append(errs, errors.New("tls.allowed_client_sans: требует client_auth=require"))
+	}
+
+	return errs
+}
+
+// validate проверяет настройки CORS
+func (c CORSConfig) validate() []error {
+	var errs []error
+
+	for _, origin := range c.AllowedOrigins {
+		if origin == "*" {
+			if c.AllowCredentials {
+				errs = append(errs, errors.New("cors: \"*\" в allowed_origins несовместим с allow_credentials"))
+			}
+			continue
+		}
+		if strings.Count(origin, "*") > 1 {
+			errs = append(errs, fmt.Errorf("cors.allowed_origins: шаблон %q содержит больше одного \"*\"", origin))
+			continue
+		}
+		u, err := url.Parse(strings.Replace(origin, "*", "wildcard", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || u.Path != "" {
+			errs = append(errs, fmt.Errorf("cors.allowed_origins: неверный origin %q", origin))
+		}
+	}
+
+	if len(c.AllowedMethods) == 0 {
+		errs = append(errs, errors.New("cors.allowed_methods: список не может быть пустым"))
+	}
+	if c.MaxAgeSeconds < 0 {
+		errs = append(errs, errors.New("cors.max_age_seconds: не может быть отрицательным"))
+	}
+	return errs
+}
+
+// databasePassword извлекает пароль из URL или параметров подключения
+func databasePassword(c DatabaseConfig) string {
+	if c.URL == "" {
+		return c.Password
+	}
+
+	if u, err := url.Parse(c.URL); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		return password
+	}
+
+	// Формат key=value: host=... password=...
+	for _, part := range strings.Fields(c.URL) {
+		if value, ok := strings.CutPrefix(part, "password="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+// validPort проверяет номер порта
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
+}
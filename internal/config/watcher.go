@@ -0,0 +1,192 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// DefaultWatchInterval интервал проверки файла конфигурации на изменения.
+// ConfigMap в Kubernetes обновляется в поде с задержкой до минуты.
+const DefaultWatchInterval = 10 * time.Second
+
+// Watcher следит за файлом конфигурации и сигналом SIGHUP и на лету
+// применяет параметры с тегом reload:"true". Изменения остальных параметров
+// отклоняются с предупреждением и вступают в силу только после перезапуска.
+type Watcher struct {
+	loader *Loader
+	log    *slog.Logger
+
+	current atomic.Pointer[Config]
+	version atomic.Uint64
+
+	mu          sync.Mutex // Сериализует перезагрузки и подписки
+	subscribers []func(*Config)
+	fileData    []byte
+
+	versionGauge    prometheus.Gauge
+	reloadsTotal    *prometheus.CounterVec
+	rejectedChanges prometheus.Counter
+}
+
+// NewWatcher создает Watcher с уже загруженной начальной конфигурацией
+func NewWatcher(loader *Loader, initial *Config, log *slog.Logger) *Watcher {
+	labels := prometheus.Labels{"component": string(loader.Component())}
+
+	w := &Watcher{
+		loader: loader,
+		log:    logger.Component(log, "config"),
+		versionGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "config_version",
+			Help:        "Версия активной конфигурации, увеличивается при каждой успешной перезагрузке",
+			ConstLabels: labels,
+		}),
+		reloadsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "config_reloads_total",
+			Help:        "Количество перезагрузок конфигурации",
+			ConstLabels: labels,
+		}, []string{"result"}),
+		rejectedChanges: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "config_rejected_changes_total",
+			Help:        "Количество отклоненных изменений параметров, требующих перезапуска",
+			ConstLabels: labels,
+		}),
+	}
+
+	w.current.Store(initial)
+	w.version.Store(1)
+	w.versionGauge.Set(1)
+
+	if path, err := loader.ConfigFile(); err == nil && path != "" {
+		w.fileData, _ = os.ReadFile(path)
+	}
+
+	return w
+}
+
+// Collectors возвращает метрики Watcher для регистрации в Prometheus
+func (w *Watcher) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{w.versionGauge, w.reloadsTotal, w.rejectedChanges}
+}
+
+// Current возвращает активную конфигурацию. Возвращаемое значение нельзя изменять.
+func (w *Watcher) Current() *Config {
+	return w.current.Load()
+}
+
+// Version возвращает версию активной конфигурации
+func (w *Watcher) Version() uint64 {
+	return w.version.Load()
+}
+
+// Subscribe регистрирует функцию, которая вызывается сразу с текущей
+// конфигурацией и затем после каждой успешной перезагрузки
+func (w *Watcher) Subscribe(fn func(*Config)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.subscribers = append(w.subscribers, fn)
+	fn(w.current.Load())
+}
+
+// Run следит за изменениями до отмены контекста
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+	defer signal.Stop(sighup)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sighup:
+			_ = w.Reload("SIGHUP")
+		case <-ticker.C:
+			if w.fileChanged() {
+				_ = w.Reload("file")
+			}
+		}
+	}
+}
+
+// fileChanged проверяет, изменилось ли содержимое файла конфигурации
+func (w *Watcher) fileChanged() bool {
+	path, err := w.loader.ConfigFile()
+	if err != nil || path == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		w.log.Warn("Ошибка чтения файла конфигурации", "path", path, logger.Err(err))
+		return false
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !bytes.Equal(data, w.fileData)
+}
+
+// Reload перечитывает конфигурацию и применяет изменения параметров,
+// допускающих перезагрузку. При ошибке продолжает действовать текущая конфигурация.
+func (w *Watcher) Reload(reason string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	log := w.log.With("reason", reason)
+
+	if path, err := w.loader.ConfigFile(); err == nil && path != "" {
+		w.fileData, _ = os.ReadFile(path)
+	}
+
+	newCfg, err := w.loader.Load()
+	if err != nil {
+		w.reloadsTotal.WithLabelValues("failure").Inc()
+		log.Error("Ошибка перезагрузки конфигурации, продолжает действовать текущая", logger.Err(err))
+		return err
+	}
+
+	oldCfg := w.current.Load()
+	var applied []string
+	for _, change := range Diff(oldCfg, newCfg) {
+		if !change.Reloadable {
+			w.rejectedChanges.Inc()
+			log.Warn("Параметр нельзя изменить без перезапуска, изменение отклонено", "change", change.String())
+			continue
+		}
+		applied = append(applied, change.String())
+	}
+
+	if len(applied) == 0 {
+		w.reloadsTotal.WithLabelValues("unchanged").Inc()
+		log.Info("Изменяемые параметры конфигурации не изменились")
+		return nil
+	}
+
+	revertNonReloadable(oldCfg, newCfg)
+	w.current.Store(newCfg)
+	version := w.version.Add(1)
+	w.versionGauge.Set(float64(version))
+	w.reloadsTotal.WithLabelValues("success").Inc()
+
+	log.Info("Конфигурация перезагружена", "version", version, "changes", applied)
+
+	for _, fn := range w.subscribers {
+		fn(newCfg)
+	}
+
+	return nil
+}
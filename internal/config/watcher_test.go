@@ -0,0 +1,57 @@
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+func TestWatcher_Reload(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "logging:\n  level: info\n")
+	loader := NewLoader(ComponentGateway, []string{"-config", path})
+
+	initial, err := loader.Load()
+	require.NoError(t, err)
+
+	watcher := NewWatcher(loader, initial, logger.Discard())
+
+	var applied []*Config
+	watcher.Subscribe(func(cfg *Config) { applied = append(applied, cfg) })
+	require.Len(t, applied, 1)
+
+	// Изменяемый параметр применяется, неизменяемый отклоняется
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: debug\nlisten:\n  http_port: \"9999\"\n"), 0o600))
+	require.True(t, watcher.fileChanged())
+	require.NoError(t, watcher.Reload("test"))
+
+	assert.Equal(t, uint64(2), watcher.Version())
+	assert.Equal(t, "debug", watcher.Current().Logging.Level)
+	assert.Equal(t, DefaultStandaloneGatewayPort, watcher.Current().Listen.HTTPPort)
+	assert.Len(t, applied, 2)
+	assert.False(t, watcher.fileChanged())
+
+	// Неверная конфигурация не применяется
+	require.NoError(t, os.WriteFile(path, []byte("logging:\n  level: loud\n"), 0o600))
+	assert.Error(t, watcher.Reload("test"))
+	assert.Equal(t, "debug", watcher.Current().Logging.Level)
+	assert.Equal(t, uint64(2), watcher.Version())
+	assert.Len(t, applied, 2)
+}
+
+func TestDiff_MasksSecrets(t *testing.T) {
+	oldCfg, newCfg := Default(), Default()
+	newCfg.JWT.Secret = "another-secret"
+	newCfg.CORS.AllowedOrigins = []string{"https://app.example.com"}
+
+	changes := Diff(oldCfg, newCfg)
+	require.Len(t, changes, 2)
+
+	assert.Equal(t, Change{Path: "jwt.secret", Old: maskedValue, New: maskedValue}, changes[0])
+	assert.Equal(t, "cors.allowed_origins", changes[1].Path)
+	assert.Equal(t, "https://app.example.com", changes[1].New)
+	assert.True(t, changes[1].Reloadable)
+}
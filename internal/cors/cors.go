@@ -0,0 +1,177 @@
+// Package cors реализует политику CORS для HTTP API.
+// Политика читается при каждом запросе, поэтому ее можно менять без перезапуска.
+package cors
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy политика CORS
+type Policy struct {
+	// AllowedOrigins разрешенные origin: точное значение, шаблон с одним "*"
+	// (например https://*.example.com) или "*" для любого origin
+	AllowedOrigins []string
+	// AllowedMethods методы, разрешенные в preflight запросах
+	AllowedMethods []string
+	// AllowedHeaders заголовки запроса, разрешенные в preflight запросах
+	AllowedHeaders []string
+	// ExposedHeaders заголовки ответа, доступные скриптам браузера
+	ExposedHeaders []string
+	// AllowCredentials разрешает запросы с cookie и заголовком Authorization из браузера
+	AllowCredentials bool
+	// MaxAge время кеширования результата preflight запроса браузером
+	MaxAge time.Duration
+}
+
+// AllowsOrigin проверяет, разрешен ли origin политикой
+func (p Policy) AllowsOrigin(origin string) bool {
+	for _, allowed := range p.AllowedOrigins {
+		if matchOrigin(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsWildcard возвращает true, если разрешен любой origin
+func (p Policy) allowsWildcard() bool {
+	for _, allowed := range p.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware применяет политику, возвращаемую policy, к каждому запросу.
+// Запросы с неразрешенного origin отклоняются со статусом 403,
+// preflight запросы обрабатываются без вызова next.
+func Middleware(policy func() Policy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			origin := r.Header.Get("Origin")
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+
+			// Ответ зависит от Origin, кеши должны это учитывать
+			w.Header().Add("Vary", "Origin")
+			if preflight {
+				w.Header().Add("Vary", "Access-Control-Request-Method")
+				w.Header().Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			// Запросы не из браузера и запросы с той же страницы не требуют CORS
+			if origin == "" || sameOrigin(r, origin) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			p := policy()
+			if !p.AllowsOrigin(origin) {
+				http.Error(w, "CORS: origin not allowed", http.StatusForbidden)
+				return
+			}
+
+			if preflight {
+				handlePreflight(w, r, p, origin)
+				return
+			}
+
+			setOriginHeaders(w, p, origin)
+			if len(p.ExposedHeaders) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.ExposedHeaders, ", "))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handlePreflight отвечает на preflight запрос
+func handlePreflight(w http.ResponseWriter, r *http.Request, p Policy, origin string) {
+	method := r.Header.Get("Access-Control-Request-Method")
+	if !containsFold(p.AllowedMethods, method) {
+		http.Error(w, "CORS: method not allowed", http.StatusForbidden)
+		return
+	}
+
+	requested := splitHeaderList(r.Header.Get("Access-Control-Request-Headers"))
+	for _, header := range requested {
+		if !containsFold(p.AllowedHeaders, header) {
+			http.Error(w, "CORS: header not allowed: "+header, http.StatusForbidden)
+			return
+		}
+	}
+
+	setOriginHeaders(w, p, origin)
+	w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.AllowedMethods, ", "))
+	if len(requested) > 0 {
+		w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.AllowedHeaders, ", "))
+	}
+	if p.MaxAge > 0 {
+		w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setOriginHeaders устанавливает разрешенный origin и признак передачи учетных данных.
+// С учетными данными браузеры не принимают "*", поэтому origin возвращается явно.
+func setOriginHeaders(w http.ResponseWriter, p Policy, origin string) {
+	if p.allowsWildcard() && !p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+	}
+	if p.AllowCredentials {
+		w.Header().Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// matchOrigin сравнивает origin с разрешенным значением или шаблоном с одним "*".
+// "*" в шаблоне соответствует одному или нескольким поддоменам.
+func matchOrigin(pattern, origin string) bool {
+	if pattern == "*" {
+		return true
+	}
+
+	prefix, suffix, wildcard := strings.Cut(strings.ToLower(pattern), "*")
+	origin = strings.ToLower(origin)
+	if !wildcard {
+		return prefix == origin
+	}
+
+	if len(origin) <= len(prefix)+len(suffix) || !strings.HasPrefix(origin, prefix) || !strings.HasSuffix(origin, suffix) {
+		return false
+	}
+	middle := origin[len(prefix) : len(origin)-len(suffix)]
+	return !strings.ContainsAny(middle, "/:")
+}
+
+// sameOrigin проверяет, что Origin совпадает с хостом запроса
+func sameOrigin(r *http.Request, origin string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, r.Host)
+}
+
+// splitHeaderList разбирает список заголовков через запятую
+func splitHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, header)
+		}
+	}
+	return headers
+}
+
+// containsFold проверяет наличие значения в списке без учета регистра
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestHandler(p Policy) http.Handler {
+	return Middleware(func() Policy { return p })(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+}
+
+func serve(h http.Handler, method, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "http://api.example.com/api/v1/users", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestMatchOrigin(t *testing.T) {
+	assert.True(t, matchOrigin("*", "https://any.example.org"))
+	assert.True(t, matchOrigin("https://app.example.com", "https://APP.example.com"))
+	assert.False(t, matchOrigin("https://app.example.com", "http://app.example.com"))
+
+	assert.True(t, matchOrigin("https://*.example.com", "https://app.example.com"))
+	assert.True(t, matchOrigin("https://*.example.com", "https://a.b.example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "https://example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "https://evil.com/.example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "https://evil.com:443.example.com"))
+	assert.False(t, matchOrigin("https://*.example.com", "https://app.example.com.evil.com"))
+}
+
+func TestMiddleware_Preflight(t *testing.T) {
+	h := newTestHandler(Policy{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	rec := serve(h, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "POST",
+		"Access-Control-Request-Headers": "authorization, content-type",
+	})
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rec.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type, Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+
+	// Неразрешенные origin, метод и заголовок
+	rec = serve(h, http.MethodOptions, "https://evil.com", map[string]string{"Access-Control-Request-Method": "POST"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(h, http.MethodOptions, "https://app.example.com", map[string]string{"Access-Control-Request-Method": "DELETE"})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	rec = serve(h, http.MethodOptions, "https://app.example.com", map[string]string{
+		"Access-Control-Request-Method":  "GET",
+		"Access-Control-Request-Headers": "X-Custom",
+	})
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	// OPTIONS без Access-Control-Request-Method не является preflight и передается дальше
+	rec = serve(h, http.MethodOptions, "https://app.example.com", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+}
+
+func TestMiddleware_ActualRequest(t *testing.T) {
+	h := newTestHandler(Policy{
+		AllowedOrigins: []string{"*"},
+		AllowedMethods: []string{"GET"},
+		ExposedHeaders: []string{"X-Request-Id"},
+	})
+
+	rec := serve(h, http.MethodGet, "https://any.example.org", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-Id", rec.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Credentials"))
+
+	// Запросы без Origin и с того же origin обрабатываются без CORS заголовков
+	h = newTestHandler(Policy{AllowedOrigins: []string{"https://app.example.com"}})
+	rec = serve(h, http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+
+	rec = serve(h, http.MethodPost, "http://api.example.com", nil)
+	assert.Equal(t, http.StatusTeapot, rec.Code)
+
+	rec = serve(h, http.MethodGet, "https://evil.com", nil)
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
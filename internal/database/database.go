@@ -2,50 +2,62 @@ package database
 
 import (
 	"fmt"
-	"os"
-	"strconv"
+	"log/slog"
+	"strings"
 	"time"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
 
 	"k8s-go-grpc-react/internal/models"
 )
 
-// Config содержит конфигурацию базы данных
-type Config struct {
-	Host     string
-	Port     int
-	User     string
-	Password string
-	DBName   string
-	SSLMode  string
-}
+// Драйверы базы данных, выбираются по схеме DSN
+const (
+	// DriverPostgres URL postgres:// или postgresql:// либо строка параметров host=... dbname=...
+	DriverPostgres = "postgres"
+	// DriverSQLite sqlite://путь/к/файлу.db или sqlite://:memory:, без CGO
+	DriverSQLite = "sqlite"
+	// DriverMemory memory:// - пользователи хранятся в памяти процесса
+	// (repository.NewMemoryUserRepository), остальные таблицы - в SQLite в памяти,
+	// как sqlite://:memory:. Данные теряются при перезапуске.
+	DriverMemory = "memory"
+)
 
-// GetConfigFromEnv загружает конфигурацию базы данных из переменных окружения
-func GetConfigFromEnv() *Config {
-	port := 5432
-	if portStr := getEnv("DB_PORT", "5432"); portStr != "" {
-		if p, err := strconv.Atoi(portStr); err == nil {
-			port = p
-		}
-	}
+const (
+	sqliteScheme = "sqlite://"
+	memoryScheme = "memory://"
+)
 
-	return &Config{
-		Host:     getEnv("DB_HOST", "localhost"),
-		Port:     port,
-		User:     getEnv("DB_USER", "user"),
-		Password: getEnv("DB_PASSWORD", "password"),
-		DBName:   getEnv("DB_NAME", "k8s_grpc_db"),
-		SSLMode:  getEnv("DB_SSLMODE", "disable"),
+// Driver возвращает драйвер для DSN по его схеме
+func Driver(dsn string) string {
+	switch {
+	case strings.HasPrefix(dsn, sqliteScheme):
+		return DriverSQLite
+	case strings.HasPrefix(dsn, memoryScheme):
+		return DriverMemory
+	default:
+		return DriverPostgres
 	}
 }
 
-// Connect подключается к базе данных по URL
-func Connect(databaseURL string) (*gorm.DB, error) {
-	db, err := gorm.Open(postgres.Open(databaseURL), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
+// Connect подключается к базе данных по URL или строке параметров (DSN).
+// Драйвер выбирается по схеме DSN (см. Driver).
+// Запросы и ошибки GORM записываются в log с компонентом database.
+func Connect(databaseURL string, log *slog.Logger) (*gorm.DB, error) {
+	var dialector gorm.Dialector
+	switch Driver(databaseURL) {
+	case DriverSQLite:
+		dialector = sqlite.Open(strings.TrimPrefix(databaseURL, sqliteScheme))
+	case DriverMemory:
+		dialector = sqlite.Open(":memory:")
+	default:
+		dialector = postgres.Open(databaseURL)
+	}
+
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: NewLogger(log),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("ошибка подключения к базе данных: %w", err)
@@ -57,35 +69,75 @@ func Connect(databaseURL string) (*gorm.DB, error) {
 		return nil, fmt.Errorf("ошибка получения SQL DB: %w", err)
 	}
 
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	if Driver(databaseURL) == DriverPostgres {
+		sqlDB.SetMaxIdleConns(10)
+		sqlDB.SetMaxOpenConns(100)
+		sqlDB.SetConnMaxLifetime(time.Hour)
+	} else {
+		// SQLite допускает одного писателя, а база в памяти существует,
+		// пока открыто ее единственное соединение
+		sqlDB.SetMaxOpenConns(1)
+		sqlDB.SetConnMaxLifetime(0)
+	}
 
 	return db, nil
 }
 
-// NewPostgresDB создает новое подключение к PostgreSQL
-func NewPostgresDB(config *Config) (*gorm.DB, error) {
-	dsn := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode)
-
-	return Connect(dsn)
+// Close закрывает пул соединений с базой данных
+func Close(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("ошибка получения SQL DB: %w", err)
+	}
+	return sqlDB.Close()
 }
 
 // Migrate выполняет миграции базы данных
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&models.User{})
+	if err := migrateUserEmails(db); err != nil {
+		return err
+	}
+	return db.AutoMigrate(
+		&models.User{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OIDCSigningKey{},
+		&models.OIDCKeyRotationLock{},
+		&models.AuditEvent{},
+		&models.AuditChainHead{},
+		&models.AuditCheckpoint{},
+		&models.AuditRetentionAnchor{},
+		&models.OutboxEvent{},
+		&models.WebhookSubscription{},
+		&models.WebhookDelivery{},
+	)
+}
+
+// legacyEmailIndex уникальный индекс по email с учетом регистра и удаленных пользователей,
+// который заменен индексом idx_users_email_active
+const legacyEmailIndex = "idx_users_email"
+
+// migrateUserEmails готовит существующую таблицу users к индексу idx_users_email_active:
+// удаляет прежний индекс и приводит email к нижнему регистру. Если активные пользователи
+// отличаются только регистром email, создание нового индекса завершится ошибкой, и такие
+// учетные записи нужно объединить вручную.
+func migrateUserEmails(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.User{}) {
+		return nil
+	}
+	if migrator.HasIndex(&models.User{}, legacyEmailIndex) {
+		if err := migrator.DropIndex(&models.User{}, legacyEmailIndex); err != nil {
+			return fmt.Errorf("ошибка удаления индекса %s: %w", legacyEmailIndex, err)
+		}
+	}
+	if err := db.Exec("UPDATE users SET email = lower(trim(email)) WHERE email <> lower(trim(email))").Error; err != nil {
+		return fmt.Errorf("ошибка нормализации email пользователей: %w", err)
+	}
+	return nil
 }
 
 // AutoMigrate выполняет автоматические миграции (для обратной совместимости)
 func AutoMigrate(db *gorm.DB) error {
 	return Migrate(db)
 }
-
-// getEnv получает значение переменной окружения или возвращает значение по умолчанию
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
-	}
-	return defaultValue
-}
@@ -0,0 +1,78 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// SlowQueryThreshold запросы дольше этого времени записываются с уровнем warn
+const SlowQueryThreshold = 200 * time.Millisecond
+
+// gormLogger передает сообщения GORM в slog с компонентом database.
+// Запросы пишутся с уровнем debug, медленные - warn, ошибки - error;
+// отсутствие записи ошибкой не считается. Уровень задается в настройках
+// логирования компонента database, LogMode GORM не используется.
+type gormLogger struct {
+	log *slog.Logger
+}
+
+// NewLogger создает логер GORM поверх slog
+func NewLogger(log *slog.Logger) gormlogger.Interface {
+	return &gormLogger{log: log}
+}
+
+func (l *gormLogger) LogMode(gormlogger.LogLevel) gormlogger.Interface {
+	return l
+}
+
+func (l *gormLogger) Info(ctx context.Context, msg string, args ...interface{}) {
+	l.logFor(ctx).Info(fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Warn(ctx context.Context, msg string, args ...interface{}) {
+	l.logFor(ctx).Warn(fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Error(ctx context.Context, msg string, args ...interface{}) {
+	l.logFor(ctx).Error(fmt.Sprintf(msg, args...))
+}
+
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	log := l.logFor(ctx)
+	elapsed := time.Since(begin)
+
+	level := slog.LevelDebug
+	switch {
+	case err != nil && !errors.Is(err, gorm.ErrRecordNotFound):
+		level = slog.LevelError
+	case elapsed > SlowQueryThreshold:
+		level = slog.LevelWarn
+	}
+	if !log.Enabled(ctx, level) {
+		return
+	}
+
+	sql, rows := fc()
+	attrs := []slog.Attr{
+		slog.String("sql", sql),
+		slog.Int64("rows", rows),
+		slog.Float64("latency_ms", float64(elapsed.Microseconds())/1000),
+	}
+	if err != nil {
+		attrs = append(attrs, logger.Err(err))
+	}
+	log.LogAttrs(ctx, level, "SQL запрос", attrs...)
+}
+
+// logFor возвращает логер компонента database с атрибутами запроса из контекста
+func (l *gormLogger) logFor(ctx context.Context) *slog.Logger {
+	return logger.Component(logger.FromContext(ctx, l.log), "database")
+}
@@ -0,0 +1,91 @@
+// Package etag передает версию ресурса между клиентом и gRPC сервером для условного
+// изменения. Сервер отдает версию в метаданных etag (в REST API - заголовок ETag),
+// клиент возвращает ее в поле запроса или в заголовке If-Match. Если версия устарела,
+// сервер отвечает FAILED_PRECONDITION, а gateway - 412 Precondition Failed.
+package etag
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// Header HTTP заголовок ответа с версией
+	Header = "ETag"
+	// MetadataKey ключ gRPC метаданных ответа с версией
+	MetadataKey = "etag"
+	// IfMatchMetadataKey ключ метаданных, в котором gateway передает заголовок If-Match
+	IfMatchMetadataKey = "grpcgateway-if-match"
+	// ViolationType тип нарушения в PreconditionFailure при устаревшей версии
+	ViolationType = "ETAG"
+)
+
+// SetHeader отправляет клиенту версию ресурса в метаданных ответа
+func SetHeader(ctx context.Context, version string) {
+	// Вне gRPC вызова (например, в тестах) заголовки не отправляются
+	_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, `"`+version+`"`))
+}
+
+// IfMatch возвращает условие из заголовка If-Match или пустую строку
+func IfMatch(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	return strings.Join(md.Get(IfMatchMetadataKey), ",")
+}
+
+// Matches проверяет условие If-Match: список версий через запятую, в кавычках
+// или без; слабые версии (W/) сравниваются как сильные, "*" совпадает с любой
+func Matches(condition, version string) bool {
+	for _, candidate := range strings.Split(condition, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.Trim(strings.TrimPrefix(candidate, "W/"), `"`) == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Mismatch возвращает ошибку устаревшей версии ресурса subject (например, users/1)
+func Mismatch(subject, message string) error {
+	st := status.New(codes.FailedPrecondition, message)
+	detailed, err := st.WithDetails(&errdetails.PreconditionFailure{
+		Violations: []*errdetails.PreconditionFailure_Violation{{
+			Type:        ViolationType,
+			Subject:     subject,
+			Description: "Версия ресурса не совпадает с etag запроса",
+		}},
+	})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// IsMismatch проверяет, что ошибка создана Mismatch
+func IsMismatch(err error) bool {
+	st, ok := status.FromError(err)
+	if !ok || st.Code() != codes.FailedPrecondition {
+		return false
+	}
+	for _, detail := range st.Details() {
+		if failure, ok := detail.(*errdetails.PreconditionFailure); ok {
+			for _, violation := range failure.GetViolations() {
+				if violation.GetType() == ViolationType {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
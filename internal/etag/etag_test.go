@@ -0,0 +1,45 @@
+package etag
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMatches(t *testing.T) {
+	tests := []struct {
+		condition string
+		want      bool
+	}{
+		{`"3"`, true},
+		{`3`, true},
+		{`W/"3"`, true},
+		{`"1", "3"`, true},
+		{`*`, true},
+		{`"2"`, false},
+		{``, false},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Matches(tt.condition, "3"), tt.condition)
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	assert.Empty(t, IfMatch(context.Background()))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(IfMatchMetadataKey, `"3"`))
+	assert.Equal(t, `"3"`, IfMatch(ctx))
+}
+
+func TestIsMismatch(t *testing.T) {
+	err := Mismatch("users/1", "Пользователь изменен")
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.True(t, IsMismatch(err))
+
+	assert.False(t, IsMismatch(status.Error(codes.FailedPrecondition, "другое условие")))
+	assert.False(t, IsMismatch(status.Error(codes.NotFound, "не найден")))
+}
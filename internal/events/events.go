@@ -0,0 +1,240 @@
+// Package events публикует события жизненного цикла пользователей для других
+// сервисов. Сервис записывает событие в таблицу outbox в той же транзакции, что
+// и изменение пользователя; Relay читает таблицу и передает события Publisher.
+// Доставка "хотя бы один раз": после сбоя событие публикуется повторно с тем же
+// ID, и получатели отбрасывают повторы по нему. Схема событий - protobuf
+// user.events.v1 (proto/events/v1/user_events.proto).
+package events
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/requestid"
+	eventsv1 "k8s-go-grpc-react/proto/events/v1"
+)
+
+// Типы событий
+const (
+	TypeUserCreated     = "user.created"
+	TypeUserUpdated     = "user.updated"
+	TypeUserDeactivated = "user.deactivated"
+	TypeUserDeleted     = "user.deleted"
+	TypeUserRestored    = "user.restored"
+	TypeUserErased      = "user.erased"
+)
+
+// Types все типы событий
+var Types = []string{TypeUserCreated, TypeUserUpdated, TypeUserDeactivated, TypeUserDeleted, TypeUserRestored, TypeUserErased}
+
+// Message событие для публикации
+type Message struct {
+	ID      string // Ключ идемпотентности
+	Type    string
+	Key     string // ID пользователя; события с одним ключом публикуются по порядку
+	Payload []byte // UserEvent в двоичном protobuf
+	Event   *eventsv1.UserEvent
+}
+
+// Publisher доставляет события получателям. Publish возвращает nil, только если
+// брокер подтвердил прием; при ошибке событие будет опубликовано повторно.
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+	Close() error
+}
+
+// Outbox записывает события в таблицу outbox. Методы вызываются с контекстом
+// транзакции изменения пользователя, тогда событие сохраняется вместе с изменением.
+// Методы nil Outbox ничего не делают: публикация событий выключена.
+type Outbox struct {
+	repo repository.OutboxRepository
+}
+
+// NewOutbox создает Outbox
+func NewOutbox(repo repository.OutboxRepository) *Outbox {
+	return &Outbox{repo: repo}
+}
+
+// UserCreated записывает событие о новом пользователе
+func (o *Outbox) UserCreated(ctx context.Context, user *models.User) error {
+	if o == nil {
+		return nil
+	}
+	event, err := userCreated(ctx, user)
+	if err != nil {
+		return err
+	}
+	return o.repo.Create(ctx, event)
+}
+
+// UserUpdated записывает событие об изменении пользователя
+func (o *Outbox) UserUpdated(ctx context.Context, user *models.User, changes []models.AuditChange) error {
+	if o == nil {
+		return nil
+	}
+	event, err := userUpdated(ctx, user, changes)
+	if err != nil {
+		return err
+	}
+	return o.repo.Create(ctx, event)
+}
+
+// UserDeleted записывает событие об удалении пользователя
+func (o *Outbox) UserDeleted(ctx context.Context, userID uint) error {
+	if o == nil {
+		return nil
+	}
+	event, err := userDeleted(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return o.repo.Create(ctx, event)
+}
+
+// UserRestored записывает событие о восстановлении удаленного пользователя
+func (o *Outbox) UserRestored(ctx context.Context, user *models.User) error {
+	if o == nil {
+		return nil
+	}
+	event, err := userRestored(ctx, user)
+	if err != nil {
+		return err
+	}
+	return o.repo.Create(ctx, event)
+}
+
+// UserErased записывает событие об удалении персональных данных пользователя
+func (o *Outbox) UserErased(ctx context.Context, userID uint) error {
+	if o == nil {
+		return nil
+	}
+	event, err := userErased(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return o.repo.Create(ctx, event)
+}
+
+// userCreated создает событие о новом пользователе
+func userCreated(ctx context.Context, user *models.User) (*models.OutboxEvent, error) {
+	return newOutboxEvent(ctx, TypeUserCreated, user.ID, &eventsv1.UserEvent{
+		Payload: &eventsv1.UserEvent_Created{Created: &eventsv1.UserCreated{User: snapshot(user)}},
+	})
+}
+
+// userUpdated создает событие об изменении пользователя. Блокировка публикуется
+// отдельным типом user.deactivated, чтобы получатели могли подписаться только на нее.
+func userUpdated(ctx context.Context, user *models.User, changes []models.AuditChange) (*models.OutboxEvent, error) {
+	fieldChanges := make([]*eventsv1.FieldChange, 0, len(changes))
+	deactivated := false
+	for _, change := range changes {
+		fieldChanges = append(fieldChanges, &eventsv1.FieldChange{
+			Field:    change.Field,
+			OldValue: change.Old,
+			NewValue: change.New,
+		})
+		if change.Field == "is_active" && change.New == "false" {
+			deactivated = true
+		}
+	}
+
+	if deactivated {
+		return newOutboxEvent(ctx, TypeUserDeactivated, user.ID, &eventsv1.UserEvent{
+			Payload: &eventsv1.UserEvent_Deactivated{Deactivated: &eventsv1.UserDeactivated{User: snapshot(user), Changes: fieldChanges}},
+		})
+	}
+	return newOutboxEvent(ctx, TypeUserUpdated, user.ID, &eventsv1.UserEvent{
+		Payload: &eventsv1.UserEvent_Updated{Updated: &eventsv1.UserUpdated{User: snapshot(user), Changes: fieldChanges}},
+	})
+}
+
+// userDeleted создает событие об удалении пользователя
+func userDeleted(ctx context.Context, userID uint) (*models.OutboxEvent, error) {
+	return newOutboxEvent(ctx, TypeUserDeleted, userID, &eventsv1.UserEvent{
+		Payload: &eventsv1.UserEvent_Deleted{Deleted: &eventsv1.UserDeleted{UserId: int32(userID)}},
+	})
+}
+
+// userRestored создает событие о восстановлении пользователя
+func userRestored(ctx context.Context, user *models.User) (*models.OutboxEvent, error) {
+	return newOutboxEvent(ctx, TypeUserRestored, user.ID, &eventsv1.UserEvent{
+		Payload: &eventsv1.UserEvent_Restored{Restored: &eventsv1.UserRestored{User: snapshot(user)}},
+	})
+}
+
+// userErased создает событие об удалении персональных данных пользователя
+func userErased(ctx context.Context, userID uint) (*models.OutboxEvent, error) {
+	return newOutboxEvent(ctx, TypeUserErased, userID, &eventsv1.UserEvent{
+		Payload: &eventsv1.UserEvent_Erased{Erased: &eventsv1.UserErased{UserId: int32(userID)}},
+	})
+}
+
+// newOutboxEvent заполняет конверт события и кодирует его для таблицы outbox
+func newOutboxEvent(ctx context.Context, eventType string, userID uint, event *eventsv1.UserEvent) (*models.OutboxEvent, error) {
+	now := time.Now().UTC()
+	event.Id = uuid.NewString()
+	event.Type = eventType
+	event.OccurredAt = timestamppb.New(now)
+	event.UserId = int32(userID)
+	event.RequestId = requestid.FromContext(ctx)
+
+	payload, err := proto.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка кодирования события %s: %w", eventType, err)
+	}
+	return &models.OutboxEvent{
+		CreatedAt: now,
+		EventID:   event.Id,
+		Type:      eventType,
+		UserID:    userID,
+		Payload:   payload,
+		Anonymous: Anonymous(event),
+	}, nil
+}
+
+// Anonymous сообщает, что событие содержит только ID пользователя, без
+// персональных данных. Такие события не удаляются вместе с данными
+// пользователя до публикации, чтобы получатели узнали об удалении.
+func Anonymous(event *eventsv1.UserEvent) bool {
+	switch event.GetPayload().(type) {
+	case *eventsv1.UserEvent_Deleted, *eventsv1.UserEvent_Erased:
+		return true
+	default:
+		return false
+	}
+}
+
+// Decode восстанавливает сообщение для публикации из записи outbox
+func Decode(event *models.OutboxEvent) (Message, error) {
+	var decoded eventsv1.UserEvent
+	if err := proto.Unmarshal(event.Payload, &decoded); err != nil {
+		return Message{}, fmt.Errorf("ошибка декодирования события %s: %w", event.EventID, err)
+	}
+	return Message{
+		ID:      event.EventID,
+		Type:    event.Type,
+		Key:     strconv.FormatUint(uint64(event.UserID), 10),
+		Payload: event.Payload,
+		Event:   &decoded,
+	}, nil
+}
+
+// snapshot возвращает состояние пользователя для события; хеш пароля не передается
+func snapshot(user *models.User) *eventsv1.UserSnapshot {
+	return &eventsv1.UserSnapshot{
+		Id:       int32(user.ID),
+		Name:     user.Name,
+		Email:    user.Email,
+		Role:     user.Role,
+		IsActive: user.IsActive,
+		Version:  int64(user.Version),
+	}
+}
@@ -0,0 +1,256 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/segmentio/kafka-go"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// Заголовки сообщений NATS
+const (
+	// natsMsgIDHeader ключ дедупликации JetStream: повтор с тем же ID в пределах
+	// окна дедупликации потока не сохраняется второй раз
+	natsMsgIDHeader = "Nats-Msg-Id"
+	// natsTypeHeader тип события, чтобы получатели могли фильтровать без декодирования
+	natsTypeHeader = "Event-Type"
+)
+
+// NATSPublisher публикует события в поток JetStream. Тема события -
+// <prefix>.<тип>, например events.user.created; поток с такими темами должен
+// быть создан заранее. Publish ждет подтверждения сохранения от сервера.
+type NATSPublisher struct {
+	conn   *nats.Conn
+	js     jetstream.JetStream
+	prefix string
+}
+
+// NewNATSPublisher подключается к NATS по url
+func NewNATSPublisher(url, prefix string) (*NATSPublisher, error) {
+	conn, err := nats.Connect(url, nats.Name("k8s-go-grpc-react"), nats.MaxReconnects(-1))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к NATS: %w", err)
+	}
+	js, err := jetstream.New(conn)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("ошибка подключения к JetStream: %w", err)
+	}
+	return &NATSPublisher{conn: conn, js: js, prefix: prefix}, nil
+}
+
+// Publish публикует событие и ждет подтверждения JetStream
+func (p *NATSPublisher) Publish(ctx context.Context, msg Message) error {
+	natsMsg := nats.NewMsg(p.prefix + "." + msg.Type)
+	natsMsg.Header.Set(natsMsgIDHeader, msg.ID)
+	natsMsg.Header.Set(natsTypeHeader, msg.Type)
+	natsMsg.Data = msg.Payload
+
+	if _, err := p.js.PublishMsg(ctx, natsMsg, jetstream.WithMsgID(msg.ID)); err != nil {
+		return fmt.Errorf("ошибка публикации события %s в NATS: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Close отправляет буферизованные данные и закрывает соединение
+func (p *NATSPublisher) Close() error {
+	return p.conn.Drain()
+}
+
+// Заголовки сообщений Kafka
+const (
+	// kafkaIDHeader ключ идемпотентности события для получателей
+	kafkaIDHeader = "Event-Id"
+	// kafkaTypeHeader тип события, чтобы получатели могли фильтровать без декодирования
+	kafkaTypeHeader = "Event-Type"
+)
+
+// KafkaPublisher публикует события в топик Kafka. Ключ сообщения - ID пользователя,
+// поэтому события одного пользователя попадают в один раздел и читаются по порядку.
+// Топик должен быть создан заранее. Publish ждет подтверждения от всех синхронных реплик.
+type KafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher создает публикацию в топик topic. Соединение с брокерами
+// устанавливается при первой публикации.
+func NewKafkaPublisher(brokers []string, topic string) (*KafkaPublisher, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("не заданы адреса брокеров Kafka")
+	}
+	return &KafkaPublisher{writer: &kafka.Writer{
+		Addr:         kafka.TCP(brokers...),
+		Topic:        topic,
+		Balancer:     &kafka.Hash{},
+		RequiredAcks: kafka.RequireAll,
+		// Relay публикует события по одному и ждет подтверждения каждого
+		BatchSize: 1,
+	}}, nil
+}
+
+// Publish публикует событие и ждет подтверждения Kafka
+func (p *KafkaPublisher) Publish(ctx context.Context, msg Message) error {
+	err := p.writer.WriteMessages(ctx, kafka.Message{
+		Key:   []byte(msg.Key),
+		Value: msg.Payload,
+		Headers: []kafka.Header{
+			{Key: kafkaIDHeader, Value: []byte(msg.ID)},
+			{Key: kafkaTypeHeader, Value: []byte(msg.Type)},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка публикации события %s в Kafka: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Close закрывает соединения с брокерами
+func (p *KafkaPublisher) Close() error {
+	return p.writer.Close()
+}
+
+// FilePublisher дописывает события в файл по одному JSON на строку. Подходит
+// для локальной разработки и отладки получателей без брокера.
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePublisher открывает файл для дописывания, создавая его при необходимости
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка открытия файла событий: %w", err)
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+// Publish записывает событие в файл
+func (p *FilePublisher) Publish(_ context.Context, msg Message) error {
+	line, err := protojson.Marshal(msg.Event)
+	if err != nil {
+		return fmt.Errorf("ошибка кодирования события %s: %w", msg.ID, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("ошибка записи события %s в файл: %w", msg.ID, err)
+	}
+	return nil
+}
+
+// Close закрывает файл
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// LogPublisher записывает события в лог с компонентом events
+type LogPublisher struct {
+	log *slog.Logger
+}
+
+// NewLogPublisher создает публикацию событий в лог
+func NewLogPublisher(log *slog.Logger) *LogPublisher {
+	return &LogPublisher{log: logger.Component(log, "events")}
+}
+
+// Publish записывает событие в лог
+func (p *LogPublisher) Publish(ctx context.Context, msg Message) error {
+	p.log.InfoContext(ctx, "Событие пользователя",
+		"event_id", msg.ID,
+		"type", msg.Type,
+		"user_id", msg.Key,
+		"event_request_id", msg.Event.GetRequestId(),
+	)
+	return nil
+}
+
+// Close ничего не делает
+func (p *LogPublisher) Close() error {
+	return nil
+}
+
+// MemoryPublisher сохраняет события в памяти процесса. Используется в тестах и
+// для подписчиков внутри процесса.
+type MemoryPublisher struct {
+	mu       sync.Mutex
+	messages []Message
+	err      error
+}
+
+// NewMemoryPublisher создает публикацию событий в память
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+// Publish сохраняет событие или возвращает ошибку, заданную SetError
+func (p *MemoryPublisher) Publish(_ context.Context, msg Message) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return p.err
+	}
+	p.messages = append(p.messages, msg)
+	return nil
+}
+
+// SetError задает ошибку, которую возвращает Publish; nil возвращает обычную работу
+func (p *MemoryPublisher) SetError(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.err = err
+}
+
+// Messages возвращает копию опубликованных событий в порядке публикации
+func (p *MemoryPublisher) Messages() []Message {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Message(nil), p.messages...)
+}
+
+// Close ничего не делает
+func (p *MemoryPublisher) Close() error {
+	return nil
+}
+
+// multiPublisher передает каждое событие всем публикациям по очереди
+type multiPublisher []Publisher
+
+// Multi объединяет публикации. Событие считается опубликованным, только если его
+// приняли все; при повторе его получат и те, кто уже принял, поэтому каждый
+// получатель должен отбрасывать повторы по ID.
+func Multi(publishers ...Publisher) Publisher {
+	if len(publishers) == 1 {
+		return publishers[0]
+	}
+	return multiPublisher(publishers)
+}
+
+// Publish публикует событие во все публикации
+func (m multiPublisher) Publish(ctx context.Context, msg Message) error {
+	for _, publisher := range m {
+		if err := publisher.Publish(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close закрывает все публикации
+func (m multiPublisher) Close() error {
+	var errs []error
+	for _, publisher := range m {
+		errs = append(errs, publisher.Close())
+	}
+	return errors.Join(errs...)
+}
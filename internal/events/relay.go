@@ -0,0 +1,233 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+const (
+	// DefaultRelayInterval период проверки таблицы outbox
+	DefaultRelayInterval = time.Second
+	// relayBatchSize наибольшее число событий, выбираемых за раз
+	relayBatchSize = 100
+	// publishTimeout наибольшее время публикации одного события
+	publishTimeout = 10 * time.Second
+	// claimLease время, на которое выбранные события откладываются для других
+	// экземпляров сервера. Пачка, не опубликованная за это время, прерывается,
+	// оставшиеся события публикуются после истечения срока.
+	claimLease = 5 * time.Minute
+	// maxRetryDelay наибольшая пауза перед повторной публикацией
+	maxRetryDelay = 5 * time.Minute
+	// cleanupInterval период удаления опубликованных событий
+	cleanupInterval = time.Hour
+)
+
+// Relay публикует события из таблицы outbox. Несколько экземпляров сервера могут
+// работать одновременно: выбранные события откладываются на claimLease, и другие
+// экземпляры их пропускают; публикация идет вне транзакции. Событие отмечается
+// опубликованным после подтверждения Publisher; при сбое между публикацией и
+// отметкой оно будет опубликовано повторно с тем же ID. Событие, не опубликованное
+// за maxAttempts попыток, больше не публикуется и не задерживает следующие события
+// пользователя.
+type Relay struct {
+	repo        repository.OutboxRepository
+	publisher   Publisher
+	maxAttempts int
+	log         *slog.Logger
+	retention   atomic.Int64
+
+	published    prometheus.Counter
+	failed       prometheus.Counter
+	deadLettered prometheus.Counter
+	pending      prometheus.Gauge
+}
+
+// NewRelay создает Relay, делающий до maxAttempts попыток публикации события.
+// Опубликованные события хранятся retention для разбора инцидентов и затем
+// удаляются; нулевой срок отключает удаление. События, исчерпавшие попытки,
+// не удаляются.
+func NewRelay(repo repository.OutboxRepository, publisher Publisher, maxAttempts int, retention time.Duration, log *slog.Logger) *Relay {
+	r := &Relay{
+		repo:        repo,
+		publisher:   publisher,
+		maxAttempts: maxAttempts,
+		log:         logger.Component(log, "events"),
+		published: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_events_published_total",
+			Help: "Количество опубликованных событий пользователей",
+		}),
+		failed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_publish_failures_total",
+			Help: "Количество неудачных попыток публикации событий пользователей",
+		}),
+		deadLettered: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "outbox_events_dead_lettered_total",
+			Help: "Количество событий пользователей, не опубликованных за все попытки",
+		}),
+		pending: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "outbox_events_pending",
+			Help: "Количество событий пользователей, ожидающих публикации",
+		}),
+	}
+	r.UpdateRetention(retention)
+	return r
+}
+
+// Collectors возвращает метрики публикации для регистрации в Prometheus
+func (r *Relay) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{r.published, r.failed, r.deadLettered, r.pending}
+}
+
+// UpdateRetention атомарно заменяет срок хранения опубликованных событий
+func (r *Relay) UpdateRetention(retention time.Duration) {
+	r.retention.Store(int64(retention))
+}
+
+// Run публикует события каждые interval до отмены ctx
+func (r *Relay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		if _, err := r.Flush(ctx); err != nil && ctx.Err() == nil {
+			r.log.Warn("Ошибка публикации событий", logger.Err(err))
+		}
+		if time.Since(lastCleanup) >= cleanupInterval {
+			if _, err := r.Cleanup(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				r.log.Warn("Ошибка удаления опубликованных событий", logger.Err(err))
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush публикует все события, срок попытки которых наступил, и возвращает
+// количество опубликованных
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		// За одну пачку публикуется не больше одного события пользователя, следующие
+		// становятся доступны после публикации предыдущих. Выбранные события
+		// откладываются, поэтому цикл завершается, когда выбирать больше нечего.
+		published, claimed, err := r.publishBatch(ctx)
+		total += published
+		if err != nil {
+			return total, err
+		}
+		if claimed == 0 {
+			break
+		}
+	}
+
+	if pending, err := r.repo.CountPending(ctx); err == nil {
+		r.pending.Set(float64(pending))
+	}
+	return total, nil
+}
+
+// publishBatch выбирает и публикует одну пачку событий и возвращает количество
+// опубликованных и выбранных событий
+func (r *Relay) publishBatch(ctx context.Context) (int, int, error) {
+	claimed := time.Now()
+	pending, err := r.repo.ClaimPending(ctx, claimed, claimLease, relayBatchSize)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	published := 0
+	for i, event := range pending {
+		// После истечения срока события могут быть выбраны другим экземпляром
+		if time.Since(claimed) > claimLease-publishTimeout {
+			r.log.Warn("Публикация пачки событий прервана по истечении срока выбора", "remaining", len(pending)-i)
+			break
+		}
+
+		msg, err := Decode(event)
+		if err == nil {
+			publishCtx, cancel := context.WithTimeout(ctx, publishTimeout)
+			err = r.publisher.Publish(publishCtx, msg)
+			cancel()
+		}
+		// При остановке сервера попытка не засчитывается
+		if ctx.Err() != nil {
+			return published, len(pending), ctx.Err()
+		}
+		if err != nil {
+			r.failed.Inc()
+			if event.Attempts+1 >= r.maxAttempts {
+				r.deadLettered.Inc()
+				r.log.Error("Событие не опубликовано за все попытки, публикация прекращена",
+					"event_id", event.EventID,
+					"type", event.Type,
+					"user_id", event.UserID,
+					"attempts", event.Attempts+1,
+					logger.Err(err),
+				)
+				if err := r.repo.MarkDeadLettered(ctx, event.ID, err.Error(), time.Now()); err != nil {
+					return published, len(pending), err
+				}
+				continue
+			}
+
+			next := time.Now().Add(retryDelay(event.Attempts + 1))
+			r.log.Warn("Событие не опубликовано",
+				"event_id", event.EventID,
+				"type", event.Type,
+				"attempts", event.Attempts+1,
+				"next_attempt_at", next,
+				logger.Err(err),
+			)
+			if err := r.repo.MarkFailed(ctx, event.ID, err.Error(), next); err != nil {
+				return published, len(pending), err
+			}
+			continue
+		}
+
+		if err := r.repo.MarkPublished(ctx, event.ID, time.Now()); err != nil {
+			return published, len(pending), err
+		}
+		published++
+		r.published.Inc()
+	}
+	return published, len(pending), nil
+}
+
+// Cleanup удаляет события, опубликованные раньше now минус срок хранения
+func (r *Relay) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	retention := time.Duration(r.retention.Load())
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	deleted, err := r.repo.DeletePublishedBefore(ctx, now.Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		r.log.Info("Удалены опубликованные события", "deleted", deleted, "retention", retention.String())
+	}
+	return deleted, nil
+}
+
+// retryDelay возвращает паузу перед попыткой attempt: 2, 4, 8 секунд и так далее
+// до maxRetryDelay
+func retryDelay(attempt int) time.Duration {
+	if attempt >= 9 {
+		return maxRetryDelay
+	}
+	return min(time.Duration(1<<attempt)*time.Second, maxRetryDelay)
+}
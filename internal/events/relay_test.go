@@ -0,0 +1,252 @@
+package events_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+type testEnv struct {
+	tx     repository.TxManager
+	users  repository.UserRepository
+	repo   repository.OutboxRepository
+	outbox *events.Outbox
+}
+
+func newTestEnv(t *testing.T) testEnv {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	repo := repository.NewOutboxRepository(db)
+	return testEnv{
+		tx:     repository.NewTxManager(db),
+		users:  repository.NewUserRepository(db),
+		repo:   repo,
+		outbox: events.NewOutbox(repo),
+	}
+}
+
+// createUser создает пользователя и событие о нем в одной транзакции
+func (e testEnv) createUser(t *testing.T, email string) *models.User {
+	user := &models.User{Name: "Ann", Email: email, PasswordHash: "hash", Role: "user", IsActive: true}
+	err := e.tx.WithinTx(context.Background(), func(ctx context.Context) error {
+		if err := e.users.Create(ctx, user); err != nil {
+			return err
+		}
+		return e.outbox.UserCreated(ctx, user)
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func TestRelay_Flush(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	publisher := events.NewMemoryPublisher()
+	relay := events.NewRelay(env.repo, publisher, 10, time.Hour, logger.Discard())
+
+	ann := env.createUser(t, "ann@example.com")
+	bob := env.createUser(t, "bob@example.com")
+	changes := []models.AuditChange{{Field: "is_active", Old: "true", New: "false"}}
+	ann.IsActive = false
+	require.NoError(t, env.outbox.UserUpdated(ctx, ann, changes))
+	require.NoError(t, env.outbox.UserDeleted(ctx, bob.ID))
+
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, published)
+
+	messages := publisher.Messages()
+	require.Len(t, messages, 4)
+	var annTypes []string
+	ids := make(map[string]bool)
+	for _, msg := range messages {
+		ids[msg.ID] = true
+		assert.Equal(t, msg.ID, msg.Event.GetId())
+		assert.Equal(t, msg.Type, msg.Event.GetType())
+		if msg.Key == "1" {
+			annTypes = append(annTypes, msg.Type)
+		}
+	}
+	assert.Len(t, ids, 4, "ключи идемпотентности уникальны")
+	// События одного пользователя публикуются в порядке записи
+	assert.Equal(t, []string{events.TypeUserCreated, events.TypeUserDeactivated}, annTypes)
+	assert.Equal(t, "ann@example.com", messages[0].Event.GetCreated().GetUser().GetEmail())
+
+	// Повторный запуск не публикует события еще раз
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+}
+
+func TestRelay_RollbackDropsEvent(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.createUser(t, "ann@example.com")
+
+	// Событие не сохраняется, если изменение пользователя откатилось
+	failure := errors.New("ошибка после записи события")
+	err := env.tx.WithinTx(ctx, func(ctx context.Context) error {
+		user := &models.User{Name: "Bob", Email: "bob@example.com", PasswordHash: "hash", Role: "user", IsActive: true}
+		require.NoError(t, env.users.Create(ctx, user))
+		require.NoError(t, env.outbox.UserCreated(ctx, user))
+		return failure
+	})
+	require.ErrorIs(t, err, failure)
+
+	pending, err := env.repo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+}
+
+func TestRelay_RetriesFailedEvents(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	publisher := events.NewMemoryPublisher()
+	relay := events.NewRelay(env.repo, publisher, 10, time.Hour, logger.Discard())
+
+	user := env.createUser(t, "ann@example.com")
+	require.NoError(t, env.outbox.UserDeleted(ctx, user.ID))
+
+	publisher.SetError(errors.New("брокер недоступен"))
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	// Первое событие отложено, второе ждет его, чтобы не нарушить порядок
+	pending, err := env.repo.ClaimPending(ctx, time.Now().Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, events.TypeUserCreated, pending[0].Type)
+	assert.Equal(t, 1, pending[0].Attempts)
+	assert.Contains(t, pending[0].LastError, "брокер недоступен")
+	assert.True(t, pending[0].NextAttemptAt.After(time.Now()))
+
+	// До срока повторной попытки событие не публикуется, даже если брокер доступен
+	publisher.SetError(nil)
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	pending, err = env.repo.ClaimPending(ctx, time.Now().Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.NoError(t, env.repo.MarkFailed(ctx, pending[0].ID, "", time.Now()))
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	messages := publisher.Messages()
+	require.Len(t, messages, 2)
+	assert.Equal(t, events.TypeUserCreated, messages[0].Type)
+	assert.Equal(t, events.TypeUserDeleted, messages[1].Type)
+}
+
+// failingPublisher не публикует события типа failType
+type failingPublisher struct {
+	*events.MemoryPublisher
+	failType string
+}
+
+func (p failingPublisher) Publish(ctx context.Context, msg events.Message) error {
+	if msg.Type == p.failType {
+		return errors.New("событие отклонено брокером")
+	}
+	return p.MemoryPublisher.Publish(ctx, msg)
+}
+
+func TestRelay_DeadLettersAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	publisher := failingPublisher{MemoryPublisher: events.NewMemoryPublisher(), failType: events.TypeUserCreated}
+	relay := events.NewRelay(env.repo, publisher, 3, time.Hour, logger.Discard())
+
+	user := env.createUser(t, "ann@example.com")
+	require.NoError(t, env.outbox.UserDeleted(ctx, user.ID))
+
+	published, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+
+	// Вторая неудачная попытка без ожидания паузы, третья - последняя:
+	// событие отмечается неопубликованным
+	pending, err := env.repo.ClaimPending(ctx, time.Now().Add(time.Hour), 0, 10)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.NoError(t, env.repo.MarkFailed(ctx, pending[0].ID, "", time.Now()))
+	published, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, published, "следующее событие пользователя не ждет неопубликованное")
+
+	messages := publisher.Messages()
+	require.Len(t, messages, 1)
+	assert.Equal(t, events.TypeUserDeleted, messages[0].Type)
+
+	count, err := env.repo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+	pending, err = env.repo.ClaimPending(ctx, time.Now().Add(24*time.Hour), 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, pending, "неопубликованное событие больше не публикуется")
+	assert.Equal(t, 1.0, testutil.ToFloat64(relay.Collectors()[2]))
+}
+
+func TestOutboxRepository_ClaimPending(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	env.createUser(t, "ann@example.com")
+	env.createUser(t, "bob@example.com")
+
+	now := time.Now()
+	claimed, err := env.repo.ClaimPending(ctx, now, time.Minute, 10)
+	require.NoError(t, err)
+	assert.Len(t, claimed, 2)
+
+	// Выбранные события не выбираются повторно до истечения срока
+	claimed, err = env.repo.ClaimPending(ctx, now.Add(30*time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	// Неопубликованные за срок события снова доступны
+	require.NoError(t, env.repo.MarkPublished(ctx, 1, now))
+	claimed, err = env.repo.ClaimPending(ctx, now.Add(2*time.Minute), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, uint(2), claimed[0].ID)
+}
+
+func TestRelay_Cleanup(t *testing.T) {
+	ctx := context.Background()
+	env := newTestEnv(t)
+	relay := events.NewRelay(env.repo, events.NewMemoryPublisher(), 10, time.Hour, logger.Discard())
+
+	env.createUser(t, "ann@example.com")
+	_, err := relay.Flush(ctx)
+	require.NoError(t, err)
+	env.createUser(t, "bob@example.com")
+
+	deleted, err := relay.Cleanup(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	// Удаляются только опубликованные события старше срока хранения
+	deleted, err = relay.Cleanup(ctx, time.Now().Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+
+	pending, err := env.repo.CountPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending)
+}
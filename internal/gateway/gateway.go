@@ -0,0 +1,268 @@
+// Package gateway реализует REST API поверх gRPC сервиса на основе grpc-gateway.
+// Маршруты генерируются из аннотаций google.api.http в proto/user.proto.
+// Gateway встраивается в HTTP сервер cmd/server или запускается отдельно в cmd/gateway.
+package gateway
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"k8s-go-grpc-react/internal/accesslog"
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/config"
+	"k8s-go-grpc-react/internal/cors"
+	"k8s-go-grpc-react/internal/etag"
+	"k8s-go-grpc-react/internal/openapi"
+	"k8s-go-grpc-react/internal/requestid"
+	pb "k8s-go-grpc-react/proto"
+)
+
+const (
+	// Prefix префикс путей REST API; маршруты из proto регистрируются без него
+	Prefix = "/api"
+	// SpecPath путь спецификации OpenAPI
+	SpecPath = Prefix + "/openapi.json"
+	// DocsPath путь страницы Swagger UI
+	DocsPath = Prefix + "/docs"
+	// LogoutPath путь выхода: удаляет cookie сессии, установленный при входе
+	LogoutPath = Prefix + "/v1/auth/logout"
+)
+
+// Options настройки gateway
+type Options struct {
+	// UseProtoNames выводит поля JSON в snake_case, иначе в camelCase
+	UseProtoNames bool
+	// EmitUnpopulated выводит поля со значениями по умолчанию
+	EmitUnpopulated bool
+	// Rewrites переписывание путей устаревших маршрутов до маршрутизации
+	Rewrites []config.RouteRewrite
+	// CORS возвращает текущую политику CORS; nil отключает CORS
+	CORS func() cors.Policy
+	// SessionTTL время жизни cookie сессии, равное сроку действия JWT токена
+	SessionTTL time.Duration
+	// SessionCookieDomain домен cookie сессии; пусто - только хост gateway
+	SessionCookieDomain string
+}
+
+// OptionsFromConfig создает настройки gateway из конфигурации.
+// Политика CORS читается из текущей конфигурации при каждом запросе.
+func OptionsFromConfig(watcher *config.Watcher) Options {
+	current := watcher.Current()
+	cfg := current.Gateway
+	return Options{
+		UseProtoNames:   cfg.UseProtoNames,
+		EmitUnpopulated: cfg.EmitUnpopulated,
+		Rewrites:        cfg.Rewrites,
+		CORS: func() cors.Policy {
+			return watcher.Current().CORS.Policy()
+		},
+		SessionTTL:          current.JWT.Expiration(),
+		SessionCookieDomain: cfg.SessionCookieDomain,
+	}
+}
+
+// Gateway HTTP обработчик REST API с префиксом Prefix
+type Gateway struct {
+	mux           *runtime.ServeMux
+	spec          http.Handler
+	docs          http.Handler
+	rewrites      []config.RouteRewrite
+	cookieDomain  string
+	sessionMaxAge int
+	handler       http.Handler
+}
+
+// New создает gateway, проксирующий запросы в gRPC сервер через conn.
+// Закрытие conn остается на вызывающей стороне.
+func New(ctx context.Context, conn *grpc.ClientConn, opts Options) (*Gateway, error) {
+	g := &Gateway{
+		rewrites:      opts.Rewrites,
+		cookieDomain:  opts.SessionCookieDomain,
+		sessionMaxAge: int(opts.SessionTTL.Seconds()),
+	}
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.HTTPBodyMarshaler{
+			Marshaler: &runtime.JSONPb{
+				MarshalOptions: protojson.MarshalOptions{
+					UseProtoNames:   opts.UseProtoNames,
+					EmitUnpopulated: opts.EmitUnpopulated,
+				},
+				// Во входящем JSON допускаются оба стиля имен полей
+				UnmarshalOptions: protojson.UnmarshalOptions{
+					DiscardUnknown: true,
+				},
+			},
+		}),
+		runtime.WithMetadata(forwardRequestID),
+		runtime.WithErrorHandler(errorHandler),
+		runtime.WithForwardResponseOption(recordUserID),
+		runtime.WithForwardResponseOption(g.setSessionCookie),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+	)
+
+	if err := pb.RegisterUserServiceHandler(ctx, mux, conn); err != nil {
+		return nil, fmt.Errorf("ошибка регистрации обработчиков gateway: %w", err)
+	}
+
+	// Имена полей в спецификации совпадают с форматом ответов gateway
+	spec, err := openapi.SpecHandler(opts.UseProtoNames)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки спецификации OpenAPI: %w", err)
+	}
+
+	g.mux = mux
+	g.spec = spec
+	g.docs = openapi.UIHandler(SpecPath)
+	g.handler = http.HandlerFunc(g.serve)
+	if opts.CORS != nil {
+		g.handler = cors.Middleware(opts.CORS)(g.handler)
+	}
+	g.handler = requestid.Middleware(g.handler)
+	return g, nil
+}
+
+// ServeHTTP обрабатывает запрос к REST API
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.handler.ServeHTTP(w, r)
+}
+
+// serve маршрутизирует запрос после проверки CORS
+func (g *Gateway) serve(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case SpecPath:
+		g.spec.ServeHTTP(w, r)
+		return
+	case DocsPath, DocsPath + "/":
+		g.docs.ServeHTTP(w, r)
+		return
+	case LogoutPath:
+		g.logout(w, r)
+		return
+	}
+
+	path, ok := strings.CutPrefix(rewritePath(g.rewrites, r.URL.Path), Prefix)
+	if !ok || !strings.HasPrefix(path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Маршруты из proto не содержат префикс, поэтому передаем копию запроса с укороченным путем
+	u := *r.URL
+	u.Path = path
+	u.RawPath = ""
+	proxied := r.Clone(r.Context())
+	proxied.URL = &u
+
+	g.mux.ServeHTTP(w, proxied)
+}
+
+// forwardRequestID передает идентификатор запроса в gRPC сервер вместе с токеном
+func forwardRequestID(ctx context.Context, r *http.Request) metadata.MD {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		return metadata.Pairs(requestid.MetadataKey, id)
+	}
+	return nil
+}
+
+// errorHandler добавляет идентификатор запроса в тело ошибки, если его не добавил сервер,
+// например при недоступности gRPC сервера или неверном JSON в запросе.
+// Устаревшая версия ресурса возвращается статусом 412 вместо 400.
+func errorHandler(ctx context.Context, mux *runtime.ServeMux, m runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	if id := requestid.FromContext(r.Context()); id != "" {
+		err = requestid.WithRequestInfo(err, id)
+	}
+	if etag.IsMismatch(err) {
+		err = &runtime.HTTPStatusError{HTTPStatus: http.StatusPreconditionFailed, Err: err}
+	}
+	_ = recordUserID(ctx, w, nil)
+	runtime.DefaultHTTPErrorHandler(ctx, mux, m, w, r, err)
+}
+
+// recordUserID передает журналу запросов идентификатор пользователя,
+// который gRPC сервер вернул после проверки токена
+func recordUserID(ctx context.Context, _ http.ResponseWriter, _ proto.Message) error {
+	if md, ok := runtime.ServerMetadataFromContext(ctx); ok {
+		if values := md.HeaderMD.Get(accesslog.UserIDMetadataKey); len(values) > 0 {
+			accesslog.SetUserIDString(ctx, values[0])
+		}
+	}
+	return nil
+}
+
+// setSessionCookie сохраняет токен из ответов Login и Register в cookie сессии,
+// по которому OIDC провайдер узнает пользователя браузера. Cookie недоступен
+// JavaScript, передается только по HTTPS и не отправляется с запросами других сайтов,
+// кроме переходов по ссылке.
+func (g *Gateway) setSessionCookie(_ context.Context, w http.ResponseWriter, msg proto.Message) error {
+	resp, ok := msg.(*pb.AuthResponse)
+	if !ok || resp.GetToken() == "" {
+		return nil
+	}
+	http.SetCookie(w, g.sessionCookie(resp.GetToken(), g.sessionMaxAge))
+	return nil
+}
+
+// logout удаляет cookie сессии. Сам токен остается действительным до истечения срока.
+func (g *Gateway) logout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+		return
+	}
+	http.SetCookie(w, g.sessionCookie("", -1))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sessionCookie создает cookie сессии; отрицательный maxAge удаляет его
+func (g *Gateway) sessionCookie(token string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     auth.SessionCookie,
+		Value:    token,
+		Path:     "/",
+		Domain:   g.cookieDomain,
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// contentDispositionKey метаданные ответа gRPC с заголовком Content-Disposition
+const contentDispositionKey = "content-disposition"
+
+// outgoingHeader не передает клиенту служебные метаданные: идентификатор запроса
+// уже есть в X-Request-ID, а идентификатор пользователя нужен только журналу.
+// Версия ресурса отдается в стандартном заголовке ETag, имя файла ответов
+// google.api.HttpBody - в Content-Disposition.
+func outgoingHeader(key string) (string, bool) {
+	switch key {
+	case requestid.MetadataKey, accesslog.UserIDMetadataKey:
+		return "", false
+	case etag.MetadataKey:
+		return etag.Header, true
+	case contentDispositionKey:
+		return "Content-Disposition", true
+	}
+	return runtime.MetadataHeaderPrefix + key, true
+}
+
+// rewritePath применяет первое подходящее правило из таблицы переписывания
+func rewritePath(rewrites []config.RouteRewrite, path string) string {
+	for _, rewrite := range rewrites {
+		from := strings.TrimSuffix(rewrite.From, "/")
+		rest, ok := strings.CutPrefix(path, from)
+		if ok && (rest == "" || strings.HasPrefix(rest, "/")) {
+			return strings.TrimSuffix(rewrite.To, "/") + rest
+		}
+	}
+	return path
+}
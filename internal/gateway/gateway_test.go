@@ -0,0 +1,305 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/config"
+	"k8s-go-grpc-react/internal/etag"
+	"k8s-go-grpc-react/internal/requestid"
+	pb "k8s-go-grpc-react/proto"
+)
+
+// stubUserService возвращает фиксированного пользователя и запоминает заголовок Authorization
+// и идентификатор запроса
+type stubUserService struct {
+	pb.UnimplementedUserServiceServer
+	authorization string
+	requestID     string
+}
+
+func (s *stubUserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.UserResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get("authorization")) > 0 {
+		s.authorization = md.Get("authorization")[0]
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(requestid.MetadataKey)) > 0 {
+		s.requestID = md.Get(requestid.MetadataKey)[0]
+	}
+	if req.Id != 1 {
+		return nil, status.Error(codes.NotFound, "Пользователь не найден")
+	}
+	return &pb.UserResponse{User: &pb.User{Id: 1, Name: "Иван", IsActive: false}}, nil
+}
+
+// UpdateUser принимает только версию "2" из поля etag или заголовка If-Match
+func (s *stubUserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
+	condition := req.Etag
+	if condition == "" {
+		condition = etag.IfMatch(ctx)
+	}
+	if condition != "" && !etag.Matches(condition, "2") {
+		return nil, etag.Mismatch("users/1", "Пользователь изменен")
+	}
+	etag.SetHeader(ctx, "3")
+	return &pb.UserResponse{User: &pb.User{Id: req.Id, Name: req.GetName(), Etag: "3"}}, nil
+}
+
+// ExportMyData возвращает файл с именем в метаданных content-disposition
+func (s *stubUserService) ExportMyData(ctx context.Context, req *pb.ExportMyDataRequest) (*httpbody.HttpBody, error) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs("content-disposition", `attachment; filename="export.zip"`))
+	return &httpbody.HttpBody{ContentType: "application/zip", Data: []byte("PK\x03\x04")}, nil
+}
+
+// Login выдает токен только для пароля "secret"
+func (s *stubUserService) Login(_ context.Context, req *pb.LoginRequest) (*pb.AuthResponse, error) {
+	if req.Password != "secret" {
+		return nil, status.Error(codes.Unauthenticated, "Неверный email или пароль")
+	}
+	return &pb.AuthResponse{Token: "jwt-token", User: &pb.User{Id: 1, Email: req.Email}}, nil
+}
+
+func newTestGateway(t *testing.T, opts Options) (*Gateway, *stubUserService) {
+	lis := bufconn.Listen(1024 * 1024)
+	stub := &stubUserService{}
+	server := grpc.NewServer()
+	pb.RegisterUserServiceServer(server, stub)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	gw, err := New(context.Background(), conn, opts)
+	require.NoError(t, err)
+	return gw, stub
+}
+
+func getJSON(t *testing.T, handler http.Handler, path string) (int, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	return rec.Code, body
+}
+
+func TestGateway_MarshalerOptions(t *testing.T) {
+	gw, stub := newTestGateway(t, Options{UseProtoNames: true, EmitUnpopulated: true})
+
+	code, body := getJSON(t, gw, "/api/v1/users/1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "Bearer token", stub.authorization)
+
+	user := body["user"].(map[string]interface{})
+	assert.Equal(t, false, user["is_active"])
+
+	gw, _ = newTestGateway(t, Options{})
+	_, body = getJSON(t, gw, "/api/v1/users/1")
+	user = body["user"].(map[string]interface{})
+	assert.NotContains(t, user, "is_active")
+	assert.NotContains(t, user, "isActive")
+	assert.Equal(t, "Иван", user["name"])
+}
+
+func TestGateway_RewritesAndErrors(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{
+		Rewrites: []config.RouteRewrite{{From: "/api/users", To: "/api/v1/users"}},
+	})
+
+	code, body := getJSON(t, gw, "/api/users/1")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, "user")
+
+	// Ошибки gRPC возвращаются в едином формате grpc-gateway
+	code, body = getJSON(t, gw, "/api/users/2")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, float64(codes.NotFound), body["code"])
+	assert.Equal(t, "Пользователь не найден", body["message"])
+}
+
+func TestRewritePath(t *testing.T) {
+	rewrites := []config.RouteRewrite{{From: "/api/users", To: "/api/v1/users"}}
+
+	assert.Equal(t, "/api/v1/users", rewritePath(rewrites, "/api/users"))
+	assert.Equal(t, "/api/v1/users/5", rewritePath(rewrites, "/api/users/5"))
+	assert.Equal(t, "/api/usersettings", rewritePath(rewrites, "/api/usersettings"))
+	assert.Equal(t, "/api/v1/users", rewritePath(rewrites, "/api/v1/users"))
+}
+
+func TestGateway_CORS(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{
+		CORS: config.CORSConfig{
+			AllowedOrigins: []string{"https://app.example.com"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Authorization"},
+		}.Policy,
+	})
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/users", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "POST")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.Equal(t, "https://app.example.com", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rec.Header().Values("Vary"), "Origin")
+
+	// Запрос с другого origin до gRPC сервиса не доходит
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestGateway_RequestID(t *testing.T) {
+	gw, stub := newTestGateway(t, Options{UseProtoNames: true})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/2", nil)
+	req.Header.Set(requestid.Header, "support-42")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	assert.Equal(t, "support-42", stub.requestID)
+	assert.Equal(t, "support-42", rec.Header().Get(requestid.Header))
+
+	// Идентификатор попадает в тело ошибки как google.rpc.RequestInfo
+	var body struct {
+		Details []map[string]interface{} `json:"details"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Details, 1)
+	assert.Equal(t, "type.googleapis.com/google.rpc.RequestInfo", body.Details[0]["@type"])
+	assert.Equal(t, "support-42", body.Details[0]["request_id"])
+}
+
+func TestGateway_ServesOpenAPI(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{UseProtoNames: true})
+
+	code, body := getJSON(t, gw, SpecPath)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "3.0.3", body["openapi"])
+
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, DocsPath, nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), SpecPath)
+}
+
+func TestGateway_ETag(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{})
+
+	patch := func(ifMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", strings.NewReader(`{"name":"Петр"}`))
+		req.Header.Set("Authorization", "Bearer token")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := patch(`"2"`)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `"3"`, rec.Header().Get("ETag"))
+
+	// Устаревшая версия в If-Match
+	rec = patch(`"1"`)
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, float64(codes.FailedPrecondition), body["code"])
+
+	// Без условия изменение выполняется
+	assert.Equal(t, http.StatusOK, patch("").Code)
+}
+
+func TestGateway_HTTPBody(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/me/export?format=zip", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rec := httptest.NewRecorder()
+	gw.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/zip", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="export.zip"`, rec.Header().Get("Content-Disposition"))
+	assert.Equal(t, "PK\x03\x04", rec.Body.String(), "тело передается без JSON обертки")
+}
+
+func TestGateway_SessionCookie(t *testing.T) {
+	gw, _ := newTestGateway(t, Options{SessionTTL: time.Hour, SessionCookieDomain: "example.com"})
+
+	login := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader(`{"email":"ann@example.com","password":"`+password+`"}`))
+		rec := httptest.NewRecorder()
+		gw.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := login("secret")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	cookies := rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	cookie := cookies[0]
+	assert.Equal(t, auth.SessionCookie, cookie.Name)
+	assert.Equal(t, "jwt-token", cookie.Value)
+	assert.Equal(t, "/", cookie.Path)
+	assert.Equal(t, "example.com", cookie.Domain)
+	assert.Equal(t, 3600, cookie.MaxAge)
+	assert.True(t, cookie.HttpOnly)
+	assert.True(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	// Неудачный вход и другие ответы cookie не устанавливают
+	rec = login("wrong")
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	assert.Empty(t, rec.Result().Cookies())
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/users/1", nil))
+	assert.Empty(t, rec.Result().Cookies())
+
+	// Выход удаляет cookie
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, LogoutPath, nil))
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	cookies = rec.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, auth.SessionCookie, cookies[0].Name)
+	assert.Empty(t, cookies[0].Value)
+	assert.Negative(t, cookies[0].MaxAge)
+
+	rec = httptest.NewRecorder()
+	gw.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, LogoutPath, nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
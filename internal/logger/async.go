@@ -0,0 +1,322 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	stdlog "log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Graylog2/go-gelf/gelf"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Транспорты GELF
+const (
+	TransportUDP = "udp"
+	TransportTCP = "tcp"
+	TransportTLS = "tls"
+)
+
+// OverflowPolicy поведение при заполненном буфере сообщений
+type OverflowPolicy string
+
+const (
+	// OverflowDropOldest вытесняет самое старое сообщение, запись в лог не блокируется
+	OverflowDropOldest OverflowPolicy = "drop_oldest"
+	// OverflowBlock блокирует запись в лог до освобождения места в буфере
+	OverflowBlock OverflowPolicy = "block"
+)
+
+const (
+	// DefaultBufferSize размер буфера сообщений по умолчанию
+	DefaultBufferSize = 10000
+
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 10 * time.Second
+	minBackoff   = 100 * time.Millisecond
+	maxBackoff   = 30 * time.Second
+)
+
+// GraylogOptions настройки отправки логов в Graylog
+type GraylogOptions struct {
+	Addr string
+	// Transport udp, tcp или tls
+	Transport string
+	// TLS настройки клиента для транспорта tls
+	TLS *tls.Config
+	// BufferSize максимальное число сообщений, ожидающих отправки
+	BufferSize int
+	// Overflow поведение при заполненном буфере
+	Overflow OverflowPolicy
+}
+
+// sender отправляет сообщения по установленному соединению
+type sender interface {
+	WriteMessage(m *gelf.Message) error
+	Close() error
+}
+
+// streamSender отправляет GELF по TCP или TLS: JSON сообщения, разделенные нулевым байтом
+type streamSender struct {
+	conn net.Conn
+	buf  bytes.Buffer
+}
+
+func (s *streamSender) WriteMessage(m *gelf.Message) error {
+	s.buf.Reset()
+	if err := m.MarshalJSONBuf(&s.buf); err != nil {
+		return err
+	}
+	s.buf.WriteByte(0)
+
+	if err := s.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	_, err := s.conn.Write(s.buf.Bytes())
+	return err
+}
+
+func (s *streamSender) Close() error {
+	return s.conn.Close()
+}
+
+// dialSender устанавливает соединение с Graylog выбранным транспортом
+func dialSender(opts GraylogOptions) (sender, error) {
+	switch opts.Transport {
+	case "", TransportUDP:
+		return gelf.NewWriter(opts.Addr)
+	case TransportTCP:
+		conn, err := net.DialTimeout("tcp", opts.Addr, dialTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return &streamSender{conn: conn}, nil
+	case TransportTLS:
+		conn, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", opts.Addr, opts.TLS)
+		if err != nil {
+			return nil, err
+		}
+		return &streamSender{conn: conn}, nil
+	default:
+		return nil, fmt.Errorf("неизвестный транспорт GELF %q", opts.Transport)
+	}
+}
+
+// AsyncWriter буферизует GELF сообщения и отправляет их в фоне, чтобы медленный
+// или недоступный Graylog не задерживал обработку запросов. При ошибках соединение
+// переустанавливается с экспоненциальной задержкой, сообщение отправляется повторно.
+type AsyncWriter struct {
+	opts GraylogOptions
+	dial func() (sender, error)
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queue   []*gelf.Message
+	closed  bool
+	aborted chan struct{}
+	done    chan struct{}
+
+	sent     prometheus.Counter
+	dropped  *prometheus.CounterVec
+	errors   prometheus.Counter
+	buffered prometheus.Gauge
+}
+
+// NewAsyncWriter создает AsyncWriter и запускает фоновую отправку.
+// serviceName попадает в постоянную метку service метрик.
+func NewAsyncWriter(serviceName string, opts GraylogOptions) *AsyncWriter {
+	return newAsyncWriter(serviceName, opts, func() (sender, error) { return dialSender(opts) })
+}
+
+func newAsyncWriter(serviceName string, opts GraylogOptions, dial func() (sender, error)) *AsyncWriter {
+	if opts.BufferSize <= 0 {
+		opts.BufferSize = DefaultBufferSize
+	}
+	if opts.Overflow == "" {
+		opts.Overflow = OverflowDropOldest
+	}
+
+	labels := prometheus.Labels{"service": serviceName}
+	w := &AsyncWriter{
+		opts:    opts,
+		dial:    dial,
+		aborted: make(chan struct{}),
+		done:    make(chan struct{}),
+		sent: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "gelf_messages_sent_total",
+			Help:        "Количество сообщений, отправленных в Graylog",
+			ConstLabels: labels,
+		}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name:        "gelf_messages_dropped_total",
+			Help:        "Количество сообщений, потерянных при переполнении буфера или остановке",
+			ConstLabels: labels,
+		}, []string{"reason"}),
+		errors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name:        "gelf_send_errors_total",
+			Help:        "Количество ошибок подключения и отправки в Graylog",
+			ConstLabels: labels,
+		}),
+		buffered: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name:        "gelf_buffer_messages",
+			Help:        "Количество сообщений в буфере отправки",
+			ConstLabels: labels,
+		}),
+	}
+	w.cond = sync.NewCond(&w.mu)
+
+	go w.run()
+	return w
+}
+
+// Collectors возвращает метрики отправки для регистрации в Prometheus
+func (w *AsyncWriter) Collectors() []prometheus.Collector {
+	if w == nil {
+		return nil
+	}
+	return []prometheus.Collector{w.sent, w.dropped, w.errors, w.buffered}
+}
+
+// WriteMessage ставит сообщение в очередь на отправку
+func (w *AsyncWriter) WriteMessage(m *gelf.Message) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) >= w.opts.BufferSize && w.opts.Overflow == OverflowBlock && !w.closed {
+		w.cond.Wait()
+	}
+	if w.closed {
+		w.dropped.WithLabelValues("closed").Inc()
+		return nil
+	}
+
+	if len(w.queue) >= w.opts.BufferSize {
+		w.queue[0] = nil
+		w.queue = w.queue[1:]
+		w.dropped.WithLabelValues("overflow").Inc()
+	}
+	w.queue = append(w.queue, m)
+	w.buffered.Set(float64(len(w.queue)))
+	w.cond.Broadcast()
+	return nil
+}
+
+// Close прекращает прием сообщений и ждет отправки буфера до истечения ctx.
+// Неотправленные к этому времени сообщения теряются и учитываются в метриках.
+func (w *AsyncWriter) Close(ctx context.Context) error {
+	if w == nil {
+		return nil
+	}
+
+	w.mu.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		close(w.aborted)
+		<-w.done
+		return ctx.Err()
+	}
+}
+
+// next извлекает следующее сообщение; false означает, что буфер пуст и writer закрыт
+func (w *AsyncWriter) next() (*gelf.Message, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for len(w.queue) == 0 && !w.closed {
+		w.cond.Wait()
+	}
+	if len(w.queue) == 0 {
+		return nil, false
+	}
+
+	m := w.queue[0]
+	w.queue[0] = nil
+	w.queue = w.queue[1:]
+	w.buffered.Set(float64(len(w.queue)))
+	w.cond.Broadcast()
+	return m, true
+}
+
+// run отправляет сообщения из буфера до закрытия writer
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	var conn sender
+	defer func() {
+		if conn != nil {
+			_ = conn.Close()
+		}
+	}()
+
+	backoff := minBackoff
+	connected := true
+	for {
+		m, ok := w.next()
+		if !ok {
+			return
+		}
+
+		for {
+			var err error
+			if conn == nil {
+				conn, err = w.dial()
+			}
+			if err == nil {
+				if err = conn.WriteMessage(m); err == nil {
+					w.sent.Inc()
+					backoff = minBackoff
+					if !connected {
+						stdlog.Printf("Соединение с Graylog %s восстановлено", w.opts.Addr)
+						connected = true
+					}
+					break
+				}
+				_ = conn.Close()
+				conn = nil
+			}
+
+			// Логер не используется, чтобы ошибки отправки не попадали в тот же буфер
+			w.errors.Inc()
+			if connected {
+				stdlog.Printf("Ошибка отправки логов в Graylog %s: %v", w.opts.Addr, err)
+				connected = false
+			}
+			if !w.sleep(backoff) {
+				w.dropRemaining(1)
+				return
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+	}
+}
+
+// sleep ждет перед повторной попыткой; false означает, что ожидание прервано Close
+func (w *AsyncWriter) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-w.aborted:
+		return false
+	}
+}
+
+// dropRemaining учитывает потерянные при остановке сообщения: current уже извлеченных и весь буфер
+func (w *AsyncWriter) dropRemaining(current int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.dropped.WithLabelValues("shutdown").Add(float64(current + len(w.queue)))
+	w.queue = nil
+	w.buffered.Set(0)
+}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Graylog2/go-gelf/gelf"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSender запоминает отправленные сообщения
+type fakeSender struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (s *fakeSender) WriteMessage(m *gelf.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.messages = append(s.messages, m.Short)
+	return nil
+}
+
+func (s *fakeSender) Close() error { return nil }
+
+func (s *fakeSender) sent() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.messages...)
+}
+
+// gatedDial возвращает dial, который ждет закрытия release перед подключением
+func gatedDial(s *fakeSender, release <-chan struct{}) func() (sender, error) {
+	return func() (sender, error) {
+		<-release
+		return s, nil
+	}
+}
+
+func message(text string) *gelf.Message {
+	return &gelf.Message{Version: "1.1", Host: "test", Short: text}
+}
+
+// waitQueue ждет, пока в буфере останется n сообщений
+func waitQueue(t *testing.T, w *AsyncWriter, n int) {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		return len(w.queue) == n
+	}, time.Second, time.Millisecond)
+}
+
+func TestAsyncWriter_DropOldest(t *testing.T) {
+	s := &fakeSender{}
+	release := make(chan struct{})
+	w := newAsyncWriter("test", GraylogOptions{BufferSize: 2, Overflow: OverflowDropOldest}, gatedDial(s, release))
+
+	// Первое сообщение забирает worker и ждет подключения
+	require.NoError(t, w.WriteMessage(message("1")))
+	waitQueue(t, w, 0)
+
+	for _, text := range []string{"2", "3", "4"} {
+		require.NoError(t, w.WriteMessage(message(text)))
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(w.dropped.WithLabelValues("overflow")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(w.buffered))
+
+	close(release)
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{"1", "3", "4"}, s.sent())
+	assert.Equal(t, 3.0, testutil.ToFloat64(w.sent))
+}
+
+func TestAsyncWriter_Block(t *testing.T) {
+	s := &fakeSender{}
+	release := make(chan struct{})
+	w := newAsyncWriter("test", GraylogOptions{BufferSize: 1, Overflow: OverflowBlock}, gatedDial(s, release))
+
+	require.NoError(t, w.WriteMessage(message("1")))
+	waitQueue(t, w, 0)
+	require.NoError(t, w.WriteMessage(message("2")))
+
+	written := make(chan struct{})
+	go func() {
+		_ = w.WriteMessage(message("3"))
+		close(written)
+	}()
+
+	select {
+	case <-written:
+		t.Fatal("запись не заблокирована при заполненном буфере")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	<-written
+	require.NoError(t, w.Close(context.Background()))
+	assert.Equal(t, []string{"1", "2", "3"}, s.sent())
+	assert.Equal(t, 0.0, testutil.ToFloat64(w.dropped.WithLabelValues("overflow")))
+}
+
+func TestAsyncWriter_Reconnect(t *testing.T) {
+	s := &fakeSender{}
+	attempts := 0
+	w := newAsyncWriter("test", GraylogOptions{}, func() (sender, error) {
+		attempts++
+		if attempts <= 2 {
+			return nil, errors.New("connection refused")
+		}
+		return s, nil
+	})
+
+	require.NoError(t, w.WriteMessage(message("1")))
+	require.NoError(t, w.WriteMessage(message("2")))
+	require.NoError(t, w.Close(context.Background()))
+
+	assert.Equal(t, []string{"1", "2"}, s.sent())
+	assert.Equal(t, 2.0, testutil.ToFloat64(w.errors))
+}
+
+func TestAsyncWriter_CloseTimeout(t *testing.T) {
+	w := newAsyncWriter("test", GraylogOptions{}, func() (sender, error) {
+		return nil, errors.New("connection refused")
+	})
+
+	for _, text := range []string{"1", "2", "3"} {
+		require.NoError(t, w.WriteMessage(message(text)))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, w.Close(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 3.0, testutil.ToFloat64(w.dropped.WithLabelValues("shutdown")))
+
+	// После закрытия сообщения не принимаются
+	require.NoError(t, w.WriteMessage(message("4")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(w.dropped.WithLabelValues("closed")))
+}
+
+func TestAsyncWriter_TCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var frames []string
+		scanner := bufio.NewScanner(conn)
+		scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+			if i := bytes.IndexByte(data, 0); i >= 0 {
+				return i + 1, data[:i], nil
+			}
+			return 0, nil, nil
+		})
+		for scanner.Scan() {
+			frames = append(frames, scanner.Text())
+		}
+		received <- frames
+	}()
+
+	log, w := Setup(Options{Service: "test", Output: io.Discard}, GraylogOptions{
+		Addr:      listener.Addr().String(),
+		Transport: TransportTCP,
+	})
+
+	log.Info("первое", "request_id", "req-1")
+	log.Info("второе")
+	require.NoError(t, w.Close(context.Background()))
+
+	frames := <-received
+	require.Len(t, frames, 2)
+	assert.Contains(t, frames[0], `"short_message":"первое"`)
+	assert.Contains(t, frames[0], `"_request_id":"req-1"`)
+	assert.Contains(t, frames[1], `"_service":"test"`)
+}
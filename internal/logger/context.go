@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"context"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// NewContext сохраняет в контексте логер с атрибутами запроса
+func NewContext(ctx context.Context, log *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext возвращает логер запроса из контекста.
+// Если его нет, используется fallback без дополнительных атрибутов.
+func FromContext(ctx context.Context, fallback *slog.Logger) *slog.Logger {
+	if log, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+		return log
+	}
+	return fallback
+}
@@ -1,76 +1,123 @@
 package logger
 
 import (
+	"context"
+	"fmt"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/Graylog2/go-gelf/gelf"
-	"github.com/sirupsen/logrus"
 )
 
-// SetupGraylogLogger настраивает логирование в Graylog
-func SetupGraylogLogger(serviceName string) *logrus.Logger {
-	log := logrus.New()
-
-	// Получаем адрес Graylog из переменной окружения
-	graylogAddr := os.Getenv("GRAYLOG_ADDR")
-	if graylogAddr == "" {
-		graylogAddr = "localhost:12201" // значение по умолчанию
-	}
+// MessageWriter отправляет GELF сообщения
+type MessageWriter interface {
+	WriteMessage(m *gelf.Message) error
+}
 
-	// Создаем GELF writer
-	gelfWriter, err := gelf.NewWriter(graylogAddr)
-	if err != nil {
-		log.WithError(err).Warn("Failed to create GELF writer, using stdout")
-		return log
-	}
+// GELFHandler реализует slog.Handler для отправки логов в Graylog.
+// Атрибуты становятся дополнительными полями GELF, атрибуты групп
+// получают имена вида group.key.
+type GELFHandler struct {
+	writer MessageWriter
+	host   string
+	extra  map[string]interface{}
+	prefix string
+}
 
-	// Создаем hook для отправки логов в Graylog
-	hook := &GraylogHook{
-		Writer: gelfWriter,
-		Extra: map[string]interface{}{
-			"service": serviceName,
-			"version": "1.0.0",
-		},
+// NewGELFHandler создает обработчик; extra добавляется в каждое сообщение
+func NewGELFHandler(writer MessageWriter, extra map[string]interface{}) *GELFHandler {
+	fields := make(map[string]interface{}, len(extra))
+	for k, v := range extra {
+		fields["_"+k] = v
 	}
-
-	log.AddHook(hook)
-	log.SetFormatter(&logrus.JSONFormatter{})
-
-	return log
+	return &GELFHandler{writer: writer, host: getHostname(), extra: fields}
 }
 
-// GraylogHook реализует logrus.Hook для отправки логов в Graylog
-type GraylogHook struct {
-	Writer *gelf.Writer
-	Extra  map[string]interface{}
+func (h *GELFHandler) Enabled(context.Context, slog.Level) bool {
+	return true
 }
 
-func (hook *GraylogHook) Fire(entry *logrus.Entry) error {
+func (h *GELFHandler) Handle(_ context.Context, r slog.Record) error {
 	// Создаем GELF сообщение
 	gelfMsg := gelf.Message{
 		Version:  "1.1",
-		Host:     getHostname(),
-		Short:    entry.Message,
-		TimeUnix: float64(entry.Time.Unix()),
-		Level:    gelfLevel(entry.Level),
-		Extra:    make(map[string]interface{}),
+		Host:     h.host,
+		Short:    r.Message,
+		TimeUnix: float64(r.Time.UnixNano()) / float64(time.Second),
+		Level:    gelfLevel(r.Level),
+		Extra:    make(map[string]interface{}, len(h.extra)+r.NumAttrs()),
 	}
 
-	// Добавляем дополнительные поля
-	for k, v := range hook.Extra {
-		gelfMsg.Extra["_"+k] = v
+	// Добавляем поля логера и записи
+	for k, v := range h.extra {
+		gelfMsg.Extra[k] = v
 	}
+	r.Attrs(func(attr slog.Attr) bool {
+		addGELFField(gelfMsg.Extra, h.prefix, attr)
+		return true
+	})
+
+	return h.writer.WriteMessage(&gelfMsg)
+}
 
-	// Добавляем поля из entry
-	for k, v := range entry.Data {
-		gelfMsg.Extra["_"+k] = v
+func (h *GELFHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := h.clone()
+	for _, attr := range attrs {
+		addGELFField(clone.extra, h.prefix, attr)
 	}
+	return clone
+}
 
-	return hook.Writer.WriteMessage(&gelfMsg)
+func (h *GELFHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	clone := h.clone()
+	clone.prefix = h.prefix + name + "."
+	return clone
 }
 
-func (hook *GraylogHook) Levels() []logrus.Level {
-	return logrus.AllLevels
+func (h *GELFHandler) clone() *GELFHandler {
+	extra := make(map[string]interface{}, len(h.extra))
+	for k, v := range h.extra {
+		extra[k] = v
+	}
+	return &GELFHandler{writer: h.writer, host: h.host, extra: extra, prefix: h.prefix}
+}
+
+// addGELFField добавляет атрибут в дополнительные поля GELF
+func addGELFField(extra map[string]interface{}, prefix string, attr slog.Attr) {
+	value := attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+
+	switch value.Kind() {
+	case slog.KindGroup:
+		groupPrefix := prefix
+		if attr.Key != "" {
+			groupPrefix = prefix + attr.Key + "."
+		}
+		for _, item := range value.Group() {
+			addGELFField(extra, groupPrefix, item)
+		}
+	case slog.KindDuration:
+		extra["_"+prefix+attr.Key] = value.Duration().String()
+	case slog.KindTime:
+		extra["_"+prefix+attr.Key] = value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			extra["_"+prefix+attr.Key] = v.Error()
+		case fmt.Stringer:
+			extra["_"+prefix+attr.Key] = v.String()
+		default:
+			extra["_"+prefix+attr.Key] = v
+		}
+	default:
+		extra["_"+prefix+attr.Key] = value.Any()
+	}
 }
 
 func getHostname() string {
@@ -81,23 +128,16 @@ func getHostname() string {
 	return hostname
 }
 
-func gelfLevel(level logrus.Level) int32 {
-	switch level {
-	case logrus.PanicLevel:
-		return 0
-	case logrus.FatalLevel:
-		return 1
-	case logrus.ErrorLevel:
+// gelfLevel переводит уровень slog в уровень syslog, используемый GELF
+func gelfLevel(level slog.Level) int32 {
+	switch {
+	case level >= slog.LevelError:
 		return 3
-	case logrus.WarnLevel:
+	case level >= slog.LevelWarn:
 		return 4
-	case logrus.InfoLevel:
+	case level >= slog.LevelInfo:
 		return 6
-	case logrus.DebugLevel:
-		return 7
-	case logrus.TraceLevel:
-		return 7
 	default:
-		return 6
+		return 7
 	}
 }
@@ -0,0 +1,222 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// LevelTrace уровень подробной трассировки, ниже slog.LevelDebug
+const LevelTrace = slog.Level(-8)
+
+// levelMin пропускает все уровни в обработчиках вывода
+const levelMin = LevelTrace
+
+// ParseLevel разбирает имя уровня: trace, debug, info, warn или error
+func ParseLevel(name string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "trace":
+		return LevelTrace, nil
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return 0, fmt.Errorf("неизвестный уровень %q", name)
+	}
+}
+
+// LevelName возвращает имя уровня в том виде, в котором его принимает ParseLevel
+func LevelName(level slog.Level) string {
+	if level <= LevelTrace {
+		return "trace"
+	}
+	return strings.ToLower(level.String())
+}
+
+// ParseLevels разбирает уровень по умолчанию и уровни компонентов в формате component=level
+func ParseLevels(defaultLevel string, components []string) (slog.Level, map[string]slog.Level, error) {
+	def, err := ParseLevel(defaultLevel)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	levels := make(map[string]slog.Level, len(components))
+	for _, item := range components {
+		name, value, ok := strings.Cut(item, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return 0, nil, fmt.Errorf("неверный уровень компонента %q, ожидается component=level", item)
+		}
+		level, err := ParseLevel(value)
+		if err != nil {
+			return 0, nil, fmt.Errorf("компонент %s: %w", name, err)
+		}
+		levels[name] = level
+	}
+	return def, levels, nil
+}
+
+// levelState уровни из конфигурации и переопределения, заданные во время работы
+type levelState struct {
+	def        slog.Level
+	components map[string]slog.Level
+	overrides  map[string]slog.Level
+}
+
+// Levels уровни логирования компонентов. Компонент определяется атрибутом
+// component, заданным через With (см. Component). Изменения применяются
+// ко всем созданным логерам сразу.
+//
+// Уровни из конфигурации (Set, Update) можно временно переопределить (Override).
+// Переопределения сохраняются при перезагрузке конфигурации. Уровень компонента
+// выбирается по порядку: переопределение компонента, уровень компонента из
+// конфигурации, общее переопределение, общий уровень из конфигурации.
+type Levels struct {
+	mu    sync.Mutex // Сериализует изменения, чтение идет без блокировки
+	state atomic.Pointer[levelState]
+}
+
+// NewLevels создает уровни с общим уровнем def
+func NewLevels(def slog.Level) *Levels {
+	l := &Levels{}
+	l.Set(def, nil)
+	return l
+}
+
+// Set атомарно заменяет уровень по умолчанию и уровни компонентов из конфигурации
+func (l *Levels) Set(def slog.Level, components map[string]slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var overrides map[string]slog.Level
+	if current := l.state.Load(); current != nil {
+		overrides = current.overrides
+	}
+	l.state.Store(&levelState{def: def, components: copyLevels(components), overrides: overrides})
+}
+
+// Update разбирает и применяет уровни из конфигурации. При ошибке уровни не меняются.
+func (l *Levels) Update(defaultLevel string, components []string) error {
+	def, levels, err := ParseLevels(defaultLevel, components)
+	if err != nil {
+		return err
+	}
+	l.Set(def, levels)
+	return nil
+}
+
+// Override переопределяет уровень компонента; пустое имя переопределяет общий уровень
+func (l *Levels) Override(component string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := l.state.Load()
+	overrides := copyLevels(current.overrides)
+	overrides[component] = level
+	l.state.Store(&levelState{def: current.def, components: current.components, overrides: overrides})
+}
+
+// ResetOverride отменяет переопределение; false, если его не было
+func (l *Levels) ResetOverride(component string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	current := l.state.Load()
+	if _, ok := current.overrides[component]; !ok {
+		return false
+	}
+	overrides := copyLevels(current.overrides)
+	delete(overrides, component)
+	l.state.Store(&levelState{def: current.def, components: current.components, overrides: overrides})
+	return true
+}
+
+// Level возвращает действующий уровень компонента
+func (l *Levels) Level(component string) slog.Level {
+	state := l.state.Load()
+	if component != "" {
+		if level, ok := state.overrides[component]; ok {
+			return level
+		}
+		if level, ok := state.components[component]; ok {
+			return level
+		}
+	}
+	if level, ok := state.overrides[""]; ok {
+		return level
+	}
+	return state.def
+}
+
+// Default возвращает действующий общий уровень
+func (l *Levels) Default() slog.Level {
+	return l.Level("")
+}
+
+// Components возвращает уровни компонентов из конфигурации
+func (l *Levels) Components() map[string]slog.Level {
+	return copyLevels(l.state.Load().components)
+}
+
+// Overrides возвращает переопределенные уровни; пустое имя компонента - общий уровень
+func (l *Levels) Overrides() map[string]slog.Level {
+	return copyLevels(l.state.Load().overrides)
+}
+
+func copyLevels(levels map[string]slog.Level) map[string]slog.Level {
+	copied := make(map[string]slog.Level, len(levels))
+	for name, level := range levels {
+		copied[name] = level
+	}
+	return copied
+}
+
+// levelHandler пропускает записи с уровнем не ниже уровня компонента
+type levelHandler struct {
+	next      slog.Handler
+	levels    *Levels
+	component string
+	grouped   bool
+}
+
+func (h *levelHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.Level(h.component)
+}
+
+func (h *levelHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.next.Handle(ctx, r)
+}
+
+func (h *levelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	if !h.grouped {
+		for _, attr := range attrs {
+			if attr.Key == ComponentKey {
+				component = attr.Value.String()
+			}
+		}
+	}
+	return &levelHandler{next: h.next.WithAttrs(attrs), levels: h.levels, component: component, grouped: h.grouped}
+}
+
+func (h *levelHandler) WithGroup(name string) slog.Handler {
+	return &levelHandler{next: h.next.WithGroup(name), levels: h.levels, component: h.component, grouped: true}
+}
+
+// replaceLevel выводит LevelTrace как TRACE вместо DEBUG-4
+func replaceLevel(groups []string, a slog.Attr) slog.Attr {
+	if a.Key == slog.LevelKey && len(groups) == 0 {
+		if level, ok := a.Value.Any().(slog.Level); ok && level <= LevelTrace {
+			return slog.String(slog.LevelKey, "TRACE")
+		}
+	}
+	return a
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevels_PerComponent(t *testing.T) {
+	levels := NewLevels(slog.LevelInfo)
+	require.NoError(t, levels.Update("warn", []string{"auth=debug", "database = trace"}))
+
+	out := &bytes.Buffer{}
+	log := New(Options{Output: out, Levels: levels})
+	auth := Component(log, "auth")
+	database := Component(log, "database")
+
+	log.Info("общий info")
+	auth.Debug("auth debug")
+	database.Log(context.Background(), LevelTrace, "database trace")
+	assert.NotContains(t, out.String(), "общий info")
+	assert.Contains(t, out.String(), "auth debug")
+	assert.Contains(t, out.String(), `"level":"TRACE"`)
+
+	// Изменение применяется к уже созданным логерам
+	require.NoError(t, levels.Update("info", []string{"auth=error"}))
+	out.Reset()
+	log.Info("общий info")
+	auth.Warn("auth warn")
+	assert.Contains(t, out.String(), "общий info")
+	assert.NotContains(t, out.String(), "auth warn")
+	assert.Equal(t, map[string]slog.Level{"auth": slog.LevelError}, levels.Components())
+}
+
+func TestParseLevels(t *testing.T) {
+	def, components, err := ParseLevels("debug", []string{"oidc=warning"})
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelDebug, def)
+	assert.Equal(t, map[string]slog.Level{"oidc": slog.LevelWarn}, components)
+
+	_, _, err = ParseLevels("verbose", nil)
+	assert.Error(t, err)
+	_, _, err = ParseLevels("info", []string{"oidc"})
+	assert.Error(t, err)
+	_, _, err = ParseLevels("info", []string{"oidc=loud"})
+	assert.Error(t, err)
+
+	// При ошибке действующие уровни сохраняются
+	levels := NewLevels(slog.LevelInfo)
+	assert.Error(t, levels.Update("info", []string{"=debug"}))
+	assert.Equal(t, slog.LevelInfo, levels.Default())
+}
+
+func TestKnownComponents(t *testing.T) {
+	known := KnownComponents()
+	assert.True(t, slices.IsSorted(known), "KnownComponent ищет компонент двоичным поиском")
+	assert.True(t, KnownComponent("auth"))
+	assert.False(t, KnownComponent("atuh"))
+}
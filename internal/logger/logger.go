@@ -0,0 +1,187 @@
+// Package logger настраивает логирование сервисов на log/slog: вывод в stdout
+// в формате JSON или текстом, отправка в Graylog, скрытие персональных данных
+// и уровни логирования отдельных компонентов, изменяемые во время работы.
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+)
+
+// Форматы вывода в stdout
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// ComponentKey атрибут, по которому выбирается уровень логирования компонента
+const ComponentKey = "component"
+
+// ErrorKey атрибут с текстом ошибки
+const ErrorKey = "error"
+
+// knownComponents компоненты сервера и gateway, передаваемые в Component.
+// Новый компонент нужно добавить сюда, иначе его уровень нельзя будет
+// изменить через API уровней логирования.
+var knownComponents = []string{
+	"audit",
+	"auth",
+	"config",
+	"database",
+	"events",
+	"gateway-init",
+	"gateway-tls",
+	"grpc-access",
+	"http-access",
+	"http-server",
+	"loglevel",
+	"oidc",
+	"shutdown",
+	"startup",
+	"users",
+	"webhook",
+}
+
+// KnownComponents возвращает компоненты, которые пишут логи, по алфавиту
+func KnownComponents() []string {
+	return slices.Clone(knownComponents)
+}
+
+// KnownComponent проверяет, что компонент пишет логи
+func KnownComponent(name string) bool {
+	_, found := slices.BinarySearch(knownComponents, name)
+	return found
+}
+
+// Options настройки логера
+type Options struct {
+	// Service имя сервиса, добавляется в сообщения Graylog
+	Service string
+	// Format формат вывода: json или text
+	Format string
+	// Output поток вывода, по умолчанию os.Stdout
+	Output io.Writer
+	// Levels уровни логирования, по умолчанию info для всех компонентов
+	Levels *Levels
+	// Redactor скрывает персональные данные до вывода и отправки в Graylog
+	Redactor *Redactor
+	// GELF получатель сообщений для Graylog, nil отключает отправку
+	GELF MessageWriter
+}
+
+// New создает логер. Записи проходят проверку уровня компонента, скрытие
+// данных и затем выводятся в stdout и отправляются в Graylog.
+func New(opts Options) *slog.Logger {
+	if opts.Output == nil {
+		opts.Output = os.Stdout
+	}
+	if opts.Levels == nil {
+		opts.Levels = NewLevels(slog.LevelInfo)
+	}
+
+	// Фильтрация выполняется levelHandler, поэтому обработчики вывода пропускают все уровни
+	handlerOpts := &slog.HandlerOptions{Level: levelMin, ReplaceAttr: replaceLevel}
+	var output slog.Handler
+	if opts.Format == FormatText {
+		output = slog.NewTextHandler(opts.Output, handlerOpts)
+	} else {
+		output = slog.NewJSONHandler(opts.Output, handlerOpts)
+	}
+
+	handler := output
+	if opts.GELF != nil {
+		handler = &multiHandler{handlers: []slog.Handler{
+			output,
+			NewGELFHandler(opts.GELF, map[string]interface{}{
+				"service": opts.Service,
+				"version": "1.0.0",
+			}),
+		}}
+	}
+	if opts.Redactor != nil {
+		handler = opts.Redactor.Handler(handler)
+	}
+	return slog.New(&levelHandler{next: handler, levels: opts.Levels})
+}
+
+// Setup создает логер сервиса. При заданном адресе Graylog сообщения отправляются
+// в фоне через AsyncWriter: его метрики нужно зарегистрировать, а при остановке
+// вызвать Close, чтобы отправить накопленные записи. Без адреса AsyncWriter равен nil.
+func Setup(opts Options, graylog GraylogOptions) (*slog.Logger, *AsyncWriter) {
+	if graylog.Addr == "" {
+		return New(opts), nil
+	}
+
+	// Соединение устанавливается в фоне, недоступность Graylog не мешает запуску
+	writer := NewAsyncWriter(opts.Service, graylog)
+	opts.GELF = writer
+	return New(opts), writer
+}
+
+// Component возвращает логер компонента; уровень его записей задается в Levels
+func Component(log *slog.Logger, name string) *slog.Logger {
+	return log.With(ComponentKey, name)
+}
+
+// Err возвращает атрибут с ошибкой
+func Err(err error) slog.Attr {
+	if err == nil {
+		return slog.String(ErrorKey, "")
+	}
+	return slog.String(ErrorKey, err.Error())
+}
+
+// Fatal записывает ошибку и завершает процесс
+func Fatal(log *slog.Logger, msg string, args ...any) {
+	log.Error(msg, args...)
+	os.Exit(1)
+}
+
+// Discard возвращает логер без вывода, для тестов и значений по умолчанию
+func Discard() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelError + 1}))
+}
+
+// multiHandler передает запись нескольким обработчикам
+type multiHandler struct {
+	handlers []slog.Handler
+}
+
+func (h *multiHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *multiHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h *multiHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithAttrs(attrs)
+	}
+	return &multiHandler{handlers: handlers}
+}
+
+func (h *multiHandler) WithGroup(name string) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	for i, handler := range h.handlers {
+		handlers[i] = handler.WithGroup(name)
+	}
+	return &multiHandler{handlers: handlers}
+}
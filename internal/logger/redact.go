@@ -0,0 +1,245 @@
+package logger
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// RedactedValue значение, которым заменяются скрытые данные
+const RedactedValue = "[REDACTED]"
+
+// hashPrefix отмечает значения, замененные хешем
+const hashPrefix = "hmac:"
+
+// RedactionRules правила скрытия персональных данных и секретов в логах.
+// Имена полей сравниваются без учета регистра по вхождению: правило password
+// срабатывает и для поля new_password.
+type RedactionRules struct {
+	// MaskFields поля, значения которых заменяются на RedactedValue
+	MaskFields []string
+	// HashFields поля, значения которых заменяются ключевым хешем:
+	// записи одного пользователя можно связать, не раскрывая данные
+	HashFields []string
+	// MaskPatterns регулярные выражения, совпадения с которыми в сообщении
+	// и строковых значениях заменяются на RedactedValue
+	MaskPatterns []string
+	// HashPatterns регулярные выражения, совпадения с которыми заменяются ключевым хешем
+	HashPatterns []string
+}
+
+// DefaultRedactionRules правила по умолчанию: пароли и токены скрываются,
+// email заменяется хешем
+func DefaultRedactionRules() RedactionRules {
+	return RedactionRules{
+		MaskFields: []string{
+			"password", "token", "authorization", "secret", "cookie", "code_verifier",
+		},
+		HashFields: []string{"email", "user_name"},
+		MaskPatterns: []string{
+			`(?i)bearer\s+[a-z0-9._~+/=-]+`,
+			`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`,
+			`(?i)(password|secret|token)=[^\s&]+`,
+			`\$2[aby]\$\d{2}\$[./A-Za-z0-9]{53}`,
+		},
+		HashPatterns: []string{
+			`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+		},
+	}
+}
+
+// compiledRules правила с подготовленными регулярными выражениями
+type compiledRules struct {
+	maskFields   []string
+	hashFields   []string
+	maskPatterns []*regexp.Regexp
+	hashPatterns []*regexp.Regexp
+}
+
+// Redactor скрывает персональные данные и секреты в записях лога.
+// Обработчик Handler применяет правила до вывода в stdout и отправки в Graylog.
+type Redactor struct {
+	key   []byte
+	rules atomic.Pointer[compiledRules]
+}
+
+// NewRedactor создает Redactor. Ключ хеширования должен совпадать у всех процессов,
+// чтобы хеши одного значения в логах сервера и gateway совпадали;
+// пустой ключ заменяется случайным ключом процесса.
+func NewRedactor(key string, rules RedactionRules) (*Redactor, error) {
+	r := &Redactor{key: []byte(key)}
+	if key == "" {
+		r.key = make([]byte, 32)
+		_, _ = rand.Read(r.key)
+	}
+	if err := r.Update(rules); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Update атомарно заменяет правила. При ошибке в выражении правила не меняются.
+func (r *Redactor) Update(rules RedactionRules) error {
+	compiled := &compiledRules{
+		maskFields: lowerAll(rules.MaskFields),
+		hashFields: lowerAll(rules.HashFields),
+	}
+
+	var err error
+	if compiled.maskPatterns, err = compileAll(rules.MaskPatterns); err != nil {
+		return err
+	}
+	if compiled.hashPatterns, err = compileAll(rules.HashPatterns); err != nil {
+		return err
+	}
+
+	r.rules.Store(compiled)
+	return nil
+}
+
+// ValidatePatterns проверяет регулярные выражения правил
+func ValidatePatterns(patterns []string) error {
+	_, err := compileAll(patterns)
+	return err
+}
+
+// Hash возвращает ключевой хеш значения в том виде, в котором он попадает в лог
+func (r *Redactor) Hash(value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(value))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil))[:16]
+}
+
+// Handler возвращает обработчик, скрывающий данные в сообщении и атрибутах
+// записей перед передачей в next
+func (r *Redactor) Handler(next slog.Handler) slog.Handler {
+	return &redactHandler{redactor: r, next: next}
+}
+
+// redactHandler применяет правила Redactor к записям
+type redactHandler struct {
+	redactor *Redactor
+	next     slog.Handler
+}
+
+func (h *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *redactHandler) Handle(ctx context.Context, record slog.Record) error {
+	rules := h.redactor.rules.Load()
+	redacted := slog.NewRecord(record.Time, record.Level, h.redactor.redactString(rules, record.Message), record.PC)
+	record.Attrs(func(attr slog.Attr) bool {
+		redacted.AddAttrs(h.redactor.redactAttr(rules, attr))
+		return true
+	})
+	return h.next.Handle(ctx, redacted)
+}
+
+// WithAttrs скрывает атрибуты логера по правилам, действующим на момент вызова With
+func (h *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	rules := h.redactor.rules.Load()
+	redacted := make([]slog.Attr, len(attrs))
+	for i, attr := range attrs {
+		redacted[i] = h.redactor.redactAttr(rules, attr)
+	}
+	return &redactHandler{redactor: h.redactor, next: h.next.WithAttrs(redacted)}
+}
+
+func (h *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{redactor: h.redactor, next: h.next.WithGroup(name)}
+}
+
+// redactAttr скрывает значение атрибута по имени или по содержимому
+func (r *Redactor) redactAttr(rules *compiledRules, attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+	name := strings.ToLower(attr.Key)
+	if containsAny(name, rules.maskFields) {
+		return slog.String(attr.Key, RedactedValue)
+	}
+	if containsAny(name, rules.hashFields) && value.Kind() != slog.KindGroup {
+		return slog.String(attr.Key, r.Hash(value.String()))
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, r.redactString(rules, value.String()))
+	case slog.KindGroup:
+		group := value.Group()
+		redacted := make([]slog.Attr, len(group))
+		for i, item := range group {
+			redacted[i] = r.redactAttr(rules, item)
+		}
+		return slog.Attr{Key: attr.Key, Value: slog.GroupValue(redacted...)}
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, r.redactString(rules, v.Error()))
+		case []string:
+			redacted := make([]string, len(v))
+			for i, item := range v {
+				redacted[i] = r.redactString(rules, item)
+			}
+			return slog.Any(attr.Key, redacted)
+		case map[string]interface{}:
+			redacted := make(map[string]interface{}, len(v))
+			for k, item := range v {
+				redacted[k] = r.redactAttr(rules, slog.Any(k, item)).Value.Any()
+			}
+			return slog.Any(attr.Key, redacted)
+		}
+	}
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// redactString заменяет совпадения с выражениями правил
+func (r *Redactor) redactString(rules *compiledRules, s string) string {
+	for _, re := range rules.maskPatterns {
+		s = re.ReplaceAllString(s, RedactedValue)
+	}
+	for _, re := range rules.hashPatterns {
+		s = re.ReplaceAllStringFunc(s, r.Hash)
+	}
+	return s
+}
+
+// containsAny проверяет вхождение любого из шаблонов в имя поля
+func containsAny(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// lowerAll приводит имена полей к нижнему регистру
+func lowerAll(values []string) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value = strings.ToLower(strings.TrimSpace(value)); value != "" {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// compileAll компилирует регулярные выражения
+func compileAll(patterns []string) ([]*regexp.Regexp, error) {
+	compiled := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("неверное выражение %q: %w", pattern, err)
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
@@ -0,0 +1,294 @@
+// Package loglevel HTTP API администратора для просмотра и изменения уровней
+// логирования во время работы, без перезапуска и повторного развертывания.
+//
+//	GET    /admin/log-levels                 действующие уровни и переопределения
+//	PUT    /admin/log-levels                 {"component": "auth", "level": "debug", "ttl_seconds": 600}
+//	DELETE /admin/log-levels?component=auth  отмена переопределения
+//
+// Пустой component означает общий уровень, другие имена должны быть известными
+// компонентами (logger.KnownComponents) или заданы в конфигурации, иначе запрос
+// отклоняется: опечатка не должна молча создавать переопределение, которое ни
+// на что не влияет. По истечении ttl_seconds уровень
+// возвращается к значению из конфигурации. Каждое изменение записывается в лог
+// с идентификатором администратора независимо от действующих уровней.
+package loglevel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// Path путь API уровней логирования
+const Path = "/admin/log-levels"
+
+// MaxTTL максимальное время действия временного изменения
+const MaxTTL = 24 * time.Hour
+
+// override переопределение с отложенной отменой
+type override struct {
+	expiresAt time.Time
+	timer     *time.Timer
+}
+
+// Controller обрабатывает запросы к API уровней логирования.
+// Проверка прав администратора выполняется middleware перед ним.
+type Controller struct {
+	levels *logger.Levels
+	log    *slog.Logger
+
+	mu        sync.Mutex
+	overrides map[string]*override
+}
+
+// New создает Controller, изменяющий levels; изменения записываются в log
+func New(levels *logger.Levels, log *slog.Logger) *Controller {
+	return &Controller{
+		levels:    levels,
+		log:       logger.Component(log, "loglevel"),
+		overrides: make(map[string]*override),
+	}
+}
+
+// setRequest запрос изменения уровня
+type setRequest struct {
+	Component  string `json:"component"`
+	Level      string `json:"level"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+// overrideInfo переопределение в ответе
+type overrideInfo struct {
+	Component string     `json:"component"`
+	Level     string     `json:"level"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// stateResponse действующие уровни
+type stateResponse struct {
+	Default    string            `json:"default"`
+	Components map[string]string `json:"components"`
+	Overrides  []overrideInfo    `json:"overrides"`
+	Known      []string          `json:"known_components"`
+}
+
+// ServeHTTP обрабатывает GET, PUT и DELETE
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		c.writeJSON(w, http.StatusOK, c.state())
+	case http.MethodPut, http.MethodPost:
+		c.handleSet(w, r)
+	case http.MethodDelete:
+		c.handleReset(w, r)
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, "Метод не поддерживается", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleSet изменяет уровень общий или компонента
+func (c *Controller) handleSet(w http.ResponseWriter, r *http.Request) {
+	var req setRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&req); err != nil {
+		http.Error(w, "Неверное тело запроса", http.StatusBadRequest)
+		return
+	}
+	if !c.knownComponent(req.Component) {
+		http.Error(w, fmt.Sprintf("Неизвестный компонент %q, доступны: %s", req.Component, strings.Join(c.components(), ", ")), http.StatusBadRequest)
+		return
+	}
+	level, err := logger.ParseLevel(req.Level)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if ttl < 0 || ttl > MaxTTL {
+		http.Error(w, "ttl_seconds должен быть от 0 до 86400", http.StatusBadRequest)
+		return
+	}
+
+	previous := c.levels.Level(req.Component)
+	c.set(req.Component, level, ttl)
+
+	attrs := append(actor(r.Context()),
+		slog.String("target", target(req.Component)),
+		slog.String("level", logger.LevelName(level)),
+		slog.String("previous", logger.LevelName(previous)),
+	)
+	if ttl > 0 {
+		attrs = append(attrs, slog.String("ttl", ttl.String()))
+	}
+	c.record(r.Context(), "Уровень логирования изменен", attrs...)
+
+	c.writeJSON(w, http.StatusOK, c.state())
+}
+
+// handleReset отменяет переопределение и возвращает уровень из конфигурации
+func (c *Controller) handleReset(w http.ResponseWriter, r *http.Request) {
+	component := r.URL.Query().Get("component")
+	if !c.reset(component) {
+		http.Error(w, "Уровень не переопределен", http.StatusNotFound)
+		return
+	}
+
+	c.record(r.Context(), "Переопределение уровня логирования отменено", append(actor(r.Context()),
+		slog.String("target", target(component)),
+		slog.String("level", logger.LevelName(c.levels.Level(component))),
+	)...)
+
+	c.writeJSON(w, http.StatusOK, c.state())
+}
+
+// knownComponent проверяет, что уровень component можно изменить: это общий
+// уровень, известный компонент или компонент из конфигурации
+func (c *Controller) knownComponent(component string) bool {
+	if component == "" || logger.KnownComponent(component) {
+		return true
+	}
+	_, configured := c.levels.Components()[component]
+	return configured
+}
+
+// components возвращает компоненты, уровень которых можно изменить, по алфавиту
+func (c *Controller) components() []string {
+	names := logger.KnownComponents()
+	for name := range c.levels.Components() {
+		if !logger.KnownComponent(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// set переопределяет уровень и при ttl > 0 планирует его отмену
+func (c *Controller) set(component string, level slog.Level, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if previous, ok := c.overrides[component]; ok && previous.timer != nil {
+		previous.timer.Stop()
+	}
+	c.levels.Override(component, level)
+
+	o := &override{}
+	if ttl > 0 {
+		o.expiresAt = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() { c.expire(component, o) })
+	}
+	c.overrides[component] = o
+}
+
+// reset отменяет переопределение; false, если его не было
+func (c *Controller) reset(component string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, ok := c.overrides[component]
+	if !ok {
+		return false
+	}
+	if o.timer != nil {
+		o.timer.Stop()
+	}
+	delete(c.overrides, component)
+	c.levels.ResetOverride(component)
+	return true
+}
+
+// expire отменяет переопределение по истечении TTL, если его не заменили новым
+func (c *Controller) expire(component string, o *override) {
+	c.mu.Lock()
+	if c.overrides[component] != o {
+		c.mu.Unlock()
+		return
+	}
+	delete(c.overrides, component)
+	c.levels.ResetOverride(component)
+	c.mu.Unlock()
+
+	c.record(context.Background(), "Уровень логирования возвращен по истечении времени",
+		slog.String("target", target(component)),
+		slog.String("level", logger.LevelName(c.levels.Level(component))),
+	)
+}
+
+// state возвращает действующие уровни
+func (c *Controller) state() stateResponse {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resp := stateResponse{
+		Default:    logger.LevelName(c.levels.Default()),
+		Components: make(map[string]string),
+		Overrides:  []overrideInfo{},
+		Known:      c.components(),
+	}
+	for name := range c.levels.Components() {
+		resp.Components[name] = logger.LevelName(c.levels.Level(name))
+	}
+	for name, level := range c.levels.Overrides() {
+		if name != "" {
+			resp.Components[name] = logger.LevelName(level)
+		}
+		info := overrideInfo{Component: name, Level: logger.LevelName(level)}
+		if o, ok := c.overrides[name]; ok && !o.expiresAt.IsZero() {
+			expiresAt := o.expiresAt.UTC()
+			info.ExpiresAt = &expiresAt
+		}
+		resp.Overrides = append(resp.Overrides, info)
+	}
+	sort.Slice(resp.Overrides, func(i, j int) bool {
+		return resp.Overrides[i].Component < resp.Overrides[j].Component
+	})
+	return resp
+}
+
+// record записывает изменение в обход проверки уровней: запись об изменении
+// должна остаться, даже если новый уровень ее бы отфильтровал
+func (c *Controller) record(ctx context.Context, msg string, attrs ...slog.Attr) {
+	record := slog.NewRecord(time.Now(), slog.LevelWarn, msg, 0)
+	record.AddAttrs(attrs...)
+	if err := c.log.Handler().Handle(ctx, record); err != nil {
+		c.log.Error("Ошибка записи изменения уровня логирования", logger.Err(err))
+	}
+}
+
+func (c *Controller) writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		c.log.Error("Ошибка кодирования ответа", logger.Err(err))
+	}
+}
+
+// actor возвращает атрибуты администратора, выполнившего изменение
+func actor(ctx context.Context) []slog.Attr {
+	claims, ok := auth.GetUserFromContext(ctx)
+	if !ok {
+		return []slog.Attr{slog.String("user_id", "")}
+	}
+	return []slog.Attr{
+		slog.Uint64("user_id", uint64(claims.UserID)),
+		slog.String("user_email", claims.Email),
+	}
+}
+
+// target имя компонента для записи в лог, * - общий уровень
+func target(component string) string {
+	if component == "" {
+		return "*"
+	}
+	return component
+}
@@ -0,0 +1,137 @@
+package loglevel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/logger"
+)
+
+// syncBuffer буфер вывода, в который пишет и таймер отмены
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+// do выполняет запрос от имени администратора и разбирает ответ
+func do(t *testing.T, c *Controller, method, target, body string) (*httptest.ResponseRecorder, stateResponse) {
+	t.Helper()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req = req.WithContext(context.WithValue(req.Context(), auth.UserContextKey, &auth.Claims{UserID: 1, Email: "admin@example.com", Role: "admin"}))
+	rec := httptest.NewRecorder()
+	c.ServeHTTP(rec, req)
+
+	var state stateResponse
+	if rec.Code == http.StatusOK {
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &state))
+	}
+	return rec, state
+}
+
+func TestController_SetAndReset(t *testing.T) {
+	levels := logger.NewLevels(slog.LevelInfo)
+	require.NoError(t, levels.Update("error", []string{"database=warn"}))
+	out := &syncBuffer{}
+	c := New(levels, logger.New(logger.Options{Output: out, Levels: levels}))
+
+	rec, state := do(t, c, http.MethodPut, Path, `{"component": "auth", "level": "debug"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "error", state.Default)
+	assert.Equal(t, map[string]string{"auth": "debug", "database": "warn"}, state.Components)
+	assert.Equal(t, slog.LevelDebug, levels.Level("auth"))
+
+	// Изменение записано, хотя общий уровень error отфильтровал бы запись warn
+	assert.Contains(t, out.String(), "Уровень логирования изменен")
+	assert.Contains(t, out.String(), `"user_id":1`)
+	assert.Contains(t, out.String(), `"previous":"error"`)
+
+	// Перезагрузка конфигурации не сбрасывает переопределение
+	require.NoError(t, levels.Update("info", nil))
+	assert.Equal(t, slog.LevelDebug, levels.Level("auth"))
+
+	rec, state = do(t, c, http.MethodDelete, Path+"?component=auth", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, state.Overrides)
+	assert.Equal(t, slog.LevelInfo, levels.Level("auth"))
+
+	rec, _ = do(t, c, http.MethodDelete, Path+"?component=auth", "")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestController_TTL(t *testing.T) {
+	levels := logger.NewLevels(slog.LevelInfo)
+	out := &syncBuffer{}
+	c := New(levels, logger.New(logger.Options{Output: out, Levels: levels}))
+
+	rec, state := do(t, c, http.MethodPut, Path, `{"level": "trace", "ttl_seconds": 1}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "trace", state.Default)
+	require.Len(t, state.Overrides, 1)
+	assert.NotNil(t, state.Overrides[0].ExpiresAt)
+
+	require.Eventually(t, func() bool {
+		return levels.Default() == slog.LevelInfo
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "возвращен по истечении времени")
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestController_Validation(t *testing.T) {
+	c := New(logger.NewLevels(slog.LevelInfo), logger.Discard())
+
+	for _, body := range []string{
+		`{"level": "loud"}`,
+		`{"level": "debug", "ttl_seconds": -1}`,
+		`{"level": "debug", "ttl_seconds": 100000}`,
+		`{"component": "atuh", "level": "debug"}`,
+		`not json`,
+	} {
+		rec, _ := do(t, c, http.MethodPut, Path, body)
+		assert.Equal(t, http.StatusBadRequest, rec.Code, body)
+	}
+
+	rec, _ := do(t, c, http.MethodPatch, Path, "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestController_Components(t *testing.T) {
+	levels := logger.NewLevels(slog.LevelInfo)
+	require.NoError(t, levels.Update("info", []string{"billing=warn"}))
+	c := New(levels, logger.Discard())
+
+	rec, _ := do(t, c, http.MethodPut, Path, `{"component": "atuh", "level": "debug"}`)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "auth", "в ответе перечислены доступные компоненты")
+	assert.Empty(t, levels.Overrides(), "переопределение для опечатки не создано")
+
+	// Компонент из конфигурации можно изменить, даже если он не известен логеру
+	rec, state := do(t, c, http.MethodPut, Path, `{"component": "billing", "level": "debug"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, state.Known, "billing")
+	assert.Contains(t, state.Known, "database")
+}
@@ -0,0 +1,164 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// AuditEvent представляет запись журнала аудита. Записи только добавляются,
+// удаляются лишь по истечении срока хранения. Каждая запись содержит хеш своего
+// содержимого вместе с хешем предыдущей записи, поэтому изменение или удаление
+// записи из середины журнала обнаруживается проверкой цепочки.
+type AuditEvent struct {
+	ID          uint      `gorm:"primarykey" json:"id"`
+	CreatedAt   time.Time `gorm:"not null;index" json:"created_at"`
+	Action      string    `gorm:"not null;size:64;index" json:"action"`
+	ActorID     *uint     `gorm:"index" json:"actor_id,omitempty"` // Пусто, если пользователь не аутентифицирован
+	ActorEmail  string    `gorm:"size:255" json:"actor_email,omitempty"`
+	TargetID    *uint     `gorm:"index" json:"target_id,omitempty"`
+	TargetEmail string    `gorm:"size:255" json:"target_email,omitempty"`
+	IP          string    `gorm:"size:64" json:"ip,omitempty"`
+	UserAgent   string    `gorm:"size:512" json:"user_agent,omitempty"`
+	RequestID   string    `gorm:"size:128;index" json:"request_id,omitempty"`
+	Reason      string    `gorm:"size:255" json:"reason,omitempty"`   // Причина отказа для неуспешных действий
+	Changes     string    `gorm:"type:text" json:"changes,omitempty"` // JSON список AuditChange
+	PrevHash    string    `gorm:"size:64" json:"prev_hash"`
+	Hash        string    `gorm:"size:64;index" json:"hash"` // Пустой у записей, созданных до включения цепочки
+}
+
+// TableName возвращает имя таблицы для модели AuditEvent
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
+
+// ComputeHash вычисляет хеш записи: SHA-256 от содержимого и PrevHash.
+// ID в хеш не входит, он назначается базой данных при вставке.
+func (e *AuditEvent) ComputeHash() string {
+	content, _ := json.Marshal([]string{
+		e.PrevHash,
+		strconv.FormatInt(e.CreatedAt.UTC().UnixMicro(), 10),
+		e.Action,
+		optionalIDString(e.ActorID),
+		e.ActorEmail,
+		optionalIDString(e.TargetID),
+		e.TargetEmail,
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+		e.Reason,
+		e.Changes,
+	})
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditChainHead последняя запись цепочки журнала аудита. Единственная строка
+// блокируется при добавлении записи, чтобы записи разных процессов выстраивались
+// в одну цепочку.
+type AuditChainHead struct {
+	ID      uint   `gorm:"primarykey"`
+	EventID uint   `gorm:"not null"`
+	Hash    string `gorm:"not null;size:64"`
+}
+
+// TableName возвращает имя таблицы для модели AuditChainHead
+func (AuditChainHead) TableName() string {
+	return "audit_chain_head"
+}
+
+// AuditCheckpoint подписанная контрольная точка цепочки: хеш записи EventID,
+// заверенный HMAC. Подделать журнал целиком, пересчитав все хеши, нельзя без ключа подписи.
+type AuditCheckpoint struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"not null;index" json:"created_at"`
+	EventID   uint      `gorm:"not null;uniqueIndex" json:"event_id"`
+	Hash      string    `gorm:"not null;size:64" json:"hash"`
+	Signature string    `gorm:"not null;size:64" json:"signature"`
+}
+
+// TableName возвращает имя таблицы для модели AuditCheckpoint
+func (AuditCheckpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// Sign вычисляет подпись контрольной точки ключом key
+func (c *AuditCheckpoint) Sign(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(strconv.FormatUint(uint64(c.EventID), 10) + ":" + c.Hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверяет подпись контрольной точки ключом key
+func (c *AuditCheckpoint) VerifySignature(key []byte) bool {
+	return hmac.Equal([]byte(c.Signature), []byte(c.Sign(key)))
+}
+
+// AuditRetentionAnchor якорь начала цепочки после удаления записей по сроку
+// хранения: ID и хеш последней удаленной записи, заверенные HMAC. Первая
+// сохранившаяся запись должна ссылаться на этот хеш, иначе начало журнала
+// удалено в обход срока хранения. Единственная строка обновляется при каждом удалении.
+type AuditRetentionAnchor struct {
+	ID        uint      `gorm:"primarykey"`
+	UpdatedAt time.Time `gorm:"not null"`
+	EventID   uint      `gorm:"not null"`
+	Hash      string    `gorm:"not null;size:64"`
+	Signature string    `gorm:"not null;size:64"`
+}
+
+// TableName возвращает имя таблицы для модели AuditRetentionAnchor
+func (AuditRetentionAnchor) TableName() string {
+	return "audit_retention_anchor"
+}
+
+// Sign вычисляет подпись якоря ключом key. Подпись отличается от подписи
+// контрольной точки той же записи, поэтому одну нельзя выдать за другую.
+func (a *AuditRetentionAnchor) Sign(key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("anchor:" + strconv.FormatUint(uint64(a.EventID), 10) + ":" + a.Hash))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature проверяет подпись якоря ключом key
+func (a *AuditRetentionAnchor) VerifySignature(key []byte) bool {
+	return hmac.Equal([]byte(a.Signature), []byte(a.Sign(key)))
+}
+
+// AuditChange изменение поля объекта: значения до и после действия
+type AuditChange struct {
+	Field string `json:"field"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// SetChanges сохраняет список изменений в поле Changes
+func (e *AuditEvent) SetChanges(changes []AuditChange) {
+	if len(changes) == 0 {
+		e.Changes = ""
+		return
+	}
+	data, _ := json.Marshal(changes)
+	e.Changes = string(data)
+}
+
+// ChangeList возвращает список изменений из поля Changes
+func (e *AuditEvent) ChangeList() []AuditChange {
+	if e.Changes == "" {
+		return nil
+	}
+	var changes []AuditChange
+	if err := json.Unmarshal([]byte(e.Changes), &changes); err != nil {
+		return nil
+	}
+	return changes
+}
+
+func optionalIDString(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
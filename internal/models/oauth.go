@@ -0,0 +1,81 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClient представляет зарегистрированное OAuth2/OIDC клиентское приложение
+type OAuthClient struct {
+	ID               uint           `gorm:"primarykey" json:"-"`
+	ClientID         string         `gorm:"uniqueIndex;not null;size:64" json:"client_id"`
+	ClientSecretHash string         `gorm:"size:255" json:"-"` // Пустой для публичных клиентов
+	Name             string         `gorm:"not null;size:255" json:"name"`
+	RedirectURIs     string         `gorm:"not null;type:text" json:"-"` // Разделены переводом строки
+	Public           bool           `gorm:"not null;default:false" json:"public"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+	DeletedAt        gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// TableName возвращает имя таблицы для модели OAuthClient
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// RedirectURIList возвращает список разрешенных redirect URI клиента
+func (c *OAuthClient) RedirectURIList() []string {
+	if c.RedirectURIs == "" {
+		return nil
+	}
+	return strings.Split(c.RedirectURIs, "\n")
+}
+
+// OAuthAuthorizationCode представляет одноразовый код авторизации
+type OAuthAuthorizationCode struct {
+	CodeHash            string    `gorm:"primarykey;size:64"` // SHA-256 от кода, сам код не хранится
+	ClientID            string    `gorm:"not null;size:64;index"`
+	UserID              uint      `gorm:"not null;index"`
+	RedirectURI         string    `gorm:"not null;type:text"`
+	Scope               string    `gorm:"not null;size:255"`
+	Nonce               string    `gorm:"size:255"`
+	CodeChallenge       string    `gorm:"not null;size:128"`
+	CodeChallengeMethod string    `gorm:"not null;size:16"`
+	AuthTime            time.Time `gorm:"not null"`
+	ExpiresAt           time.Time `gorm:"not null;index"`
+	CreatedAt           time.Time
+}
+
+// TableName возвращает имя таблицы для модели OAuthAuthorizationCode
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// OIDCSigningKey представляет ключ подписи ID токенов
+type OIDCSigningKey struct {
+	KID           string    `gorm:"primarykey;size:64"`
+	Algorithm     string    `gorm:"not null;size:16"`
+	PrivateKeyPEM string    `gorm:"not null;type:text"`                           // Зашифрован ключом oidc.signing_key_encryption_key (см. oidc.KeyManager)
+	ActiveFrom    time.Time `gorm:"not null;default:'1970-01-01 00:00:00+00:00'"` // До этого момента ключ только публикуется в JWKS
+	ActiveUntil   time.Time `gorm:"not null"`                                     // После этого момента ключ не используется для подписи
+	ExpiresAt     time.Time `gorm:"not null;index"`                               // После этого момента ключ удаляется из JWKS
+	CreatedAt     time.Time
+}
+
+// TableName возвращает имя таблицы для модели OIDCSigningKey
+func (OIDCSigningKey) TableName() string {
+	return "oidc_signing_keys"
+}
+
+// OIDCKeyRotationLock единственная строка, которую блокирует реплика, создающая
+// новый ключ подписи, чтобы реплики не создали несколько ключей одновременно
+type OIDCKeyRotationLock struct {
+	ID uint `gorm:"primarykey"`
+}
+
+// TableName возвращает имя таблицы для модели OIDCKeyRotationLock
+func (OIDCKeyRotationLock) TableName() string {
+	return "oidc_key_rotation_lock"
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// OutboxEvent событие, ожидающее публикации. Записывается в одной транзакции
+// с изменением пользователя, поэтому событие не теряется и не публикуется
+// без изменения. Публикует события relay (internal/events).
+type OutboxEvent struct {
+	ID            uint       `gorm:"primarykey" json:"id"`
+	CreatedAt     time.Time  `gorm:"not null" json:"created_at"`
+	EventID       string     `gorm:"not null;size:36;uniqueIndex" json:"event_id"` // Ключ идемпотентности для получателей
+	Type          string     `gorm:"not null;size:64" json:"type"`
+	UserID        uint       `gorm:"not null;index" json:"user_id"`
+	Payload       []byte     `gorm:"not null" json:"-"`                       // user.events.v1.UserEvent в двоичном protobuf
+	Anonymous     bool       `gorm:"not null;default:false" json:"anonymous"` // Только ID пользователя, без персональных данных
+	Attempts      int        `gorm:"not null;default:0" json:"attempts"`
+	LastError     string     `gorm:"size:1024" json:"last_error,omitempty"`
+	NextAttemptAt time.Time  `gorm:"not null;index:idx_outbox_pending,priority:2" json:"next_attempt_at"`
+	PublishedAt   *time.Time `gorm:"index:idx_outbox_pending,priority:1" json:"published_at,omitempty"` // Пусто, пока событие не опубликовано
+	// FailedAt время, когда событие исчерпало попытки публикации и больше не публикуется
+	FailedAt *time.Time `gorm:"index" json:"failed_at,omitempty"`
+}
+
+// TableName возвращает имя таблицы для модели OutboxEvent
+func (OutboxEvent) TableName() string {
+	return "outbox_events"
+}
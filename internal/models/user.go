@@ -1,6 +1,9 @@
 package models
 
 import (
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -10,16 +13,46 @@ import (
 type User struct {
 	ID           uint           `gorm:"primarykey" json:"id"`
 	Name         string         `gorm:"not null;size:255" json:"name"`
-	Email        string         `gorm:"uniqueIndex;not null;size:255" json:"email"`
+	Email        string         `gorm:"not null;size:255;uniqueIndex:idx_users_email_active,expression:lower(email),where:deleted_at IS NULL" json:"email"`
 	PasswordHash string         `gorm:"not null;size:255" json:"-"` // Хеш пароля, не возвращается в JSON
 	Role         string         `gorm:"not null;default:'user';size:50" json:"role"`
 	IsActive     bool           `gorm:"not null;default:true" json:"is_active"`
+	Version      uint           `gorm:"not null;default:1" json:"version"` // Увеличивается при каждом изменении
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+	ErasedAt     *time.Time     `json:"-"` // Момент удаления персональных данных по запросу пользователя
+}
+
+// ErasedName имя пользователя после удаления персональных данных
+const ErasedName = "Удаленный пользователь"
+
+// ErasedEmail возвращает email, который заменяет адрес пользователя после удаления
+// персональных данных. Домен .invalid зарезервирован (RFC 2606), почта на него не
+// доставляется, а ID сохраняет уникальность адреса.
+func ErasedEmail(id uint) string {
+	return fmt.Sprintf("erased-%d@erased.invalid", id)
 }
 
 // TableName возвращает имя таблицы для модели User
 func (User) TableName() string {
 	return "users"
 }
+
+// Erased сообщает, что персональные данные пользователя удалены
+func (u *User) Erased() bool {
+	return u.ErasedAt != nil
+}
+
+// ETag возвращает версию пользователя в виде, который передается клиентам
+// для условного изменения
+func (u *User) ETag() string {
+	return strconv.FormatUint(uint64(u.Version), 10)
+}
+
+// NormalizeEmail приводит email к виду, в котором он хранится: без пробелов по краям
+// и в нижнем регистре. Уникальный индекс дополнительно сравнивает lower(email), поэтому
+// адреса, отличающиеся только регистром, не могут принадлежать разным пользователям.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
@@ -0,0 +1,78 @@
+package models
+
+import (
+	"slices"
+	"strings"
+	"time"
+)
+
+// Статусы доставки webhook
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed" // Попытки исчерпаны
+)
+
+// WebhookSubscription подписка внешней системы на события пользователей по HTTP.
+// Подписка отключается автоматически, если адрес раз за разом не принимает события.
+type WebhookSubscription struct {
+	ID                  uint       `gorm:"primarykey" json:"id"`
+	CreatedAt           time.Time  `json:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at"`
+	URL                 string     `gorm:"not null;size:2048" json:"url"`
+	EventTypes          string     `gorm:"not null;size:512" json:"event_types"` // Типы событий через запятую
+	Secret              string     `gorm:"not null;size:128" json:"-"`           // Ключ HMAC подписи запросов
+	Enabled             bool       `gorm:"not null;default:true" json:"enabled"`
+	ConsecutiveFailures int        `gorm:"not null;default:0" json:"consecutive_failures"`
+	DisabledAt          *time.Time `json:"disabled_at,omitempty"`
+	DisabledReason      string     `gorm:"size:255" json:"disabled_reason,omitempty"`
+}
+
+// TableName возвращает имя таблицы для модели WebhookSubscription
+func (WebhookSubscription) TableName() string {
+	return "webhook_subscriptions"
+}
+
+// Types возвращает типы событий подписки
+func (s *WebhookSubscription) Types() []string {
+	if s.EventTypes == "" {
+		return nil
+	}
+	return strings.Split(s.EventTypes, ",")
+}
+
+// SetTypes сохраняет типы событий подписки
+func (s *WebhookSubscription) SetTypes(types []string) {
+	s.EventTypes = strings.Join(types, ",")
+}
+
+// Subscribed проверяет, что подписка получает события типа eventType
+func (s *WebhookSubscription) Subscribed(eventType string) bool {
+	return slices.Contains(s.Types(), eventType)
+}
+
+// WebhookDelivery доставка события по подписке и журнал попыток. Событие
+// доставляется по подписке один раз: повтор публикации не создает новую доставку.
+type WebhookDelivery struct {
+	ID             uint       `gorm:"primarykey" json:"id"`
+	CreatedAt      time.Time  `gorm:"index" json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	SubscriptionID uint       `gorm:"not null;uniqueIndex:idx_webhook_deliveries_event,priority:1" json:"subscription_id"`
+	EventID        string     `gorm:"not null;size:36;uniqueIndex:idx_webhook_deliveries_event,priority:2" json:"event_id"`
+	EventType      string     `gorm:"not null;size:64" json:"event_type"`
+	UserID         uint       `gorm:"not null;default:0;index" json:"user_id"` // Пользователь, о котором событие
+	Payload        []byte     `gorm:"not null" json:"-"`                       // Тело запроса, JSON
+	Anonymous      bool       `gorm:"not null;default:false" json:"anonymous"` // Только ID пользователя, без персональных данных
+	Status         string     `gorm:"not null;size:16;index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int        `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  time.Time  `gorm:"not null;index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"` // HTTP статус последней попытки, 0 - ответа не было
+	LastError      string     `gorm:"size:1024" json:"last_error,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// TableName возвращает имя таблицы для модели WebhookDelivery
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
@@ -0,0 +1,573 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// Коды ошибок OAuth2 (RFC 6749, раздел 4.1.2.1 и 5.2)
+const (
+	errInvalidRequest          = "invalid_request"
+	errInvalidClient           = "invalid_client"
+	errInvalidGrant            = "invalid_grant"
+	errUnauthorizedClient      = "unauthorized_client"
+	errUnsupportedGrantType    = "unsupported_grant_type"
+	errUnsupportedResponseType = "unsupported_response_type"
+	errInvalidScope            = "invalid_scope"
+	errInvalidToken            = "invalid_token"
+	errLoginRequired           = "login_required"
+	errAccessDenied            = "access_denied"
+	errServerError             = "server_error"
+)
+
+// errorResponse тело ответа с ошибкой OAuth2
+type errorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
+// tokenResponse ответ token endpoint
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+	Scope       string `json:"scope"`
+}
+
+// registerClientRequest запрос на регистрацию клиентского приложения
+type registerClientRequest struct {
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"`
+}
+
+// registerClientResponse ответ на регистрацию клиентского приложения.
+// client_secret возвращается только один раз.
+type registerClientResponse struct {
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Name         string   `json:"name"`
+	RedirectURIs []string `json:"redirect_uris"`
+	Public       bool     `json:"public"`
+}
+
+// handleAuthorize обрабатывает authorization endpoint
+func (p *Provider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		p.writeError(w, http.StatusBadRequest, errInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	clientID := r.Form.Get("client_id")
+	redirectURI := r.Form.Get("redirect_uri")
+
+	// Пока redirect_uri не проверен, ошибки возвращаются напрямую, без перенаправления
+	client, err := p.oauthRepo.GetClient(r.Context(), clientID)
+	if err != nil {
+		p.writeError(w, http.StatusBadRequest, errInvalidClient, "Неизвестный client_id")
+		return
+	}
+	if !containsString(client.RedirectURIList(), redirectURI) {
+		p.writeError(w, http.StatusBadRequest, errInvalidRequest, "redirect_uri не зарегистрирован для клиента")
+		return
+	}
+
+	state := r.Form.Get("state")
+	redirectError := func(code, description string) {
+		redirectWithParams(w, r, redirectURI, url.Values{
+			"error":             {code},
+			"error_description": {description},
+			"state":             {state},
+		})
+	}
+
+	if r.Form.Get("response_type") != "code" {
+		redirectError(errUnsupportedResponseType, "Поддерживается только response_type=code")
+		return
+	}
+
+	scopes := parseScope(r.Form.Get("scope"))
+	if !scopes[ScopeOpenID] {
+		redirectError(errInvalidScope, "Scope openid обязателен")
+		return
+	}
+
+	challenge := r.Form.Get("code_challenge")
+	method := r.Form.Get("code_challenge_method")
+	if method != codeChallengeMethodS256 || !codeChallengePattern.MatchString(challenge) {
+		redirectError(errInvalidRequest, "Требуется PKCE с code_challenge_method=S256")
+		return
+	}
+
+	claims, ok := p.authenticate(r)
+	if !ok {
+		if p.config.LoginURL == "" || r.Form.Get("prompt") == "none" {
+			redirectError(errLoginRequired, "Требуется вход в систему")
+			return
+		}
+		returnTo := p.config.Issuer + authorizePath + "?" + r.Form.Encode()
+		redirectWithParams(w, r, p.config.LoginURL, url.Values{"return_to": {returnTo}})
+		return
+	}
+
+	user, err := p.userRepo.GetByID(r.Context(), claims.UserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		p.log.Error("Ошибка получения пользователя", logger.Err(err))
+		redirectError(errServerError, "Ошибка получения пользователя")
+		return
+	}
+	if err != nil || !user.IsActive {
+		redirectError(errAccessDenied, "Учетная запись недоступна")
+		return
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		redirectError(errServerError, "Ошибка генерации кода авторизации")
+		return
+	}
+
+	authTime := time.Now()
+	if claims.IssuedAt != nil {
+		authTime = claims.IssuedAt.Time
+	}
+
+	err = p.oauthRepo.SaveAuthorizationCode(r.Context(), &models.OAuthAuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              user.ID,
+		RedirectURI:         redirectURI,
+		Scope:               formatScope(scopes),
+		Nonce:               r.Form.Get("nonce"),
+		CodeChallenge:       challenge,
+		CodeChallengeMethod: method,
+		AuthTime:            authTime,
+		ExpiresAt:           time.Now().Add(p.config.CodeTTL),
+	})
+	if err != nil {
+		p.log.Error("Ошибка сохранения кода авторизации", logger.Err(err))
+		redirectError(errServerError, "Ошибка сохранения кода авторизации")
+		return
+	}
+
+	p.log.Info("Выдан код авторизации", "client_id", client.ClientID, "user_id", user.ID)
+
+	redirectWithParams(w, r, redirectURI, url.Values{"code": {code}, "state": {state}})
+}
+
+// handleToken обрабатывает token endpoint
+func (p *Provider) handleToken(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		p.writeError(w, http.StatusBadRequest, errInvalidRequest, "Неверный формат запроса")
+		return
+	}
+
+	if r.PostForm.Get("grant_type") != "authorization_code" {
+		p.writeError(w, http.StatusBadRequest, errUnsupportedGrantType, "Поддерживается только authorization_code")
+		return
+	}
+
+	client, ok := p.authenticateClient(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+		p.writeError(w, http.StatusUnauthorized, errInvalidClient, "Ошибка аутентификации клиента")
+		return
+	}
+
+	code, err := p.oauthRepo.ConsumeAuthorizationCode(r.Context(), hashToken(r.PostForm.Get("code")))
+	if err != nil {
+		if !errors.Is(err, repository.ErrAuthorizationCodeNotFound) {
+			p.log.Error("Ошибка получения кода авторизации", logger.Err(err))
+		}
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "Код авторизации недействителен")
+		return
+	}
+
+	now := time.Now()
+	if code.ClientID != client.ClientID {
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "Код выдан другому клиенту")
+		return
+	}
+	if now.After(code.ExpiresAt) {
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "Срок действия кода авторизации истек")
+		return
+	}
+	if code.RedirectURI != r.PostForm.Get("redirect_uri") {
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "redirect_uri не совпадает")
+		return
+	}
+	if !verifyPKCE(code.CodeChallenge, code.CodeChallengeMethod, r.PostForm.Get("code_verifier")) {
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "Неверный code_verifier")
+		return
+	}
+
+	user, err := p.userRepo.GetByID(r.Context(), code.UserID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		p.log.Error("Ошибка получения пользователя", logger.Err(err))
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка получения пользователя")
+		return
+	}
+	if err != nil || !user.IsActive {
+		p.writeError(w, http.StatusBadRequest, errInvalidGrant, "Учетная запись недоступна")
+		return
+	}
+
+	idToken, err := p.issueIDToken(user, code, now)
+	if err != nil {
+		p.log.Error("Ошибка выпуска ID токена", logger.Err(err))
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка выпуска токена")
+		return
+	}
+
+	accessToken, err := p.issueAccessToken(user, code, now)
+	if err != nil {
+		p.log.Error("Ошибка выпуска access токена", logger.Err(err))
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка выпуска токена")
+		return
+	}
+
+	p.log.Info("Выданы токены OIDC", "client_id", client.ClientID, "user_id", user.ID)
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	p.writeJSON(w, http.StatusOK, tokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(p.config.AccessTokenTTL.Seconds()),
+		IDToken:     idToken,
+		Scope:       code.Scope,
+	})
+}
+
+// handleUserinfo возвращает claims пользователя по access токену
+func (p *Provider) handleUserinfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+		p.writeError(w, http.StatusUnauthorized, errInvalidRequest, "Токен не предоставлен")
+		return
+	}
+
+	claims, err := p.validateAccessToken(token)
+	if err != nil {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		p.writeError(w, http.StatusUnauthorized, errInvalidToken, "Недействительный токен")
+		return
+	}
+
+	userID, err := strconv.ParseUint(claims.Subject, 10, 64)
+	if err != nil {
+		p.writeError(w, http.StatusUnauthorized, errInvalidToken, "Недействительный токен")
+		return
+	}
+
+	user, err := p.userRepo.GetByID(r.Context(), uint(userID))
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		p.log.Error("Ошибка получения пользователя", logger.Err(err))
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка получения пользователя")
+		return
+	}
+	if err != nil || !user.IsActive {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		p.writeError(w, http.StatusUnauthorized, errInvalidToken, "Учетная запись недоступна")
+		return
+	}
+
+	info := map[string]interface{}{"sub": claims.Subject}
+	scopes := parseScope(claims.Scope)
+	if scopes[ScopeProfile] {
+		info["name"] = user.Name
+		info["role"] = user.Role
+	}
+	if scopes[ScopeEmail] {
+		info["email"] = user.Email
+	}
+
+	p.writeJSON(w, http.StatusOK, info)
+}
+
+// handleClients управляет регистрацией клиентских приложений (только для админов)
+func (p *Provider) handleClients(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		clients, err := p.oauthRepo.ListClients(r.Context())
+		if err != nil {
+			p.log.Error("Ошибка получения списка OAuth клиентов", logger.Err(err))
+			p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка получения списка клиентов")
+			return
+		}
+		resp := make([]registerClientResponse, len(clients))
+		for i, c := range clients {
+			resp[i] = registerClientResponse{ClientID: c.ClientID, Name: c.Name, RedirectURIs: c.RedirectURIList(), Public: c.Public}
+		}
+		p.writeJSON(w, http.StatusOK, resp)
+
+	case http.MethodPost:
+		p.registerClient(w, r)
+
+	case http.MethodDelete:
+		clientID := r.URL.Query().Get("client_id")
+		if clientID == "" {
+			p.writeError(w, http.StatusBadRequest, errInvalidRequest, "client_id обязателен")
+			return
+		}
+		if err := p.oauthRepo.DeleteClient(r.Context(), clientID); err != nil {
+			p.log.Error("Ошибка удаления OAuth клиента", logger.Err(err))
+			p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка удаления клиента")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// registerClient регистрирует новое клиентское приложение
+func (p *Provider) registerClient(w http.ResponseWriter, r *http.Request) {
+	var req registerClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		p.writeError(w, http.StatusBadRequest, errInvalidRequest, "Неверный JSON")
+		return
+	}
+
+	if req.Name == "" || len(req.RedirectURIs) == 0 {
+		p.writeError(w, http.StatusBadRequest, errInvalidRequest, "name и redirect_uris обязательны")
+		return
+	}
+	for _, uri := range req.RedirectURIs {
+		if !validRedirectURI(uri) {
+			p.writeError(w, http.StatusBadRequest, errInvalidRequest, "Недопустимый redirect_uri: "+uri)
+			return
+		}
+	}
+
+	clientID, err := randomHex(16)
+	if err != nil {
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка генерации client_id")
+		return
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, "\n"),
+		Public:       req.Public,
+	}
+
+	resp := registerClientResponse{
+		ClientID:     clientID,
+		Name:         req.Name,
+		RedirectURIs: req.RedirectURIs,
+		Public:       req.Public,
+	}
+
+	if !req.Public {
+		secret, err := randomToken(32)
+		if err != nil {
+			p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка генерации client_secret")
+			return
+		}
+		hash, err := p.jwtService.HashPassword(secret)
+		if err != nil {
+			p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка хеширования client_secret")
+			return
+		}
+		client.ClientSecretHash = hash
+		resp.ClientSecret = secret
+	}
+
+	if err := p.oauthRepo.CreateClient(r.Context(), client); err != nil {
+		p.log.Error("Ошибка регистрации OAuth клиента", logger.Err(err))
+		p.writeError(w, http.StatusInternalServerError, errServerError, "Ошибка регистрации клиента")
+		return
+	}
+
+	log := p.log.With("client_id", clientID, "client_name", req.Name)
+	if admin, ok := auth.GetUserFromContext(r.Context()); ok {
+		log = log.With("admin_id", admin.UserID)
+	}
+	log.Info("Зарегистрирован OAuth клиент")
+
+	p.writeJSON(w, http.StatusCreated, resp)
+}
+
+// authenticate проверяет JWT токен платформы из заголовка Authorization или cookie
+func (p *Provider) authenticate(r *http.Request) (*auth.Claims, bool) {
+	token := bearerToken(r)
+	if token == "" && p.config.SessionCookie != "" {
+		if cookie, err := r.Cookie(p.config.SessionCookie); err == nil {
+			token = cookie.Value
+		}
+	}
+	if token == "" {
+		return nil, false
+	}
+
+	claims, err := p.jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, false
+	}
+	return claims, true
+}
+
+// authenticateClient проверяет учетные данные клиента (client_secret_basic,
+// client_secret_post или none для публичных клиентов)
+func (p *Provider) authenticateClient(r *http.Request) (*models.OAuthClient, bool) {
+	clientID, secret, basic := r.BasicAuth()
+	if !basic {
+		clientID = r.PostForm.Get("client_id")
+		secret = r.PostForm.Get("client_secret")
+	}
+	if clientID == "" {
+		return nil, false
+	}
+
+	client, err := p.oauthRepo.GetClient(r.Context(), clientID)
+	if err != nil {
+		return nil, false
+	}
+
+	if client.Public {
+		return client, secret == ""
+	}
+	if secret == "" || p.jwtService.CheckPassword(client.ClientSecretHash, secret) != nil {
+		return nil, false
+	}
+	return client, true
+}
+
+// writeJSON записывает JSON ответ
+func (p *Provider) writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		p.log.Error("Ошибка кодирования ответа", logger.Err(err))
+	}
+}
+
+// writeError записывает ответ с ошибкой OAuth2
+func (p *Provider) writeError(w http.ResponseWriter, statusCode int, code, description string) {
+	p.writeJSON(w, statusCode, errorResponse{Error: code, ErrorDescription: description})
+}
+
+// redirectWithParams перенаправляет на URI, добавляя параметры к query
+func redirectWithParams(w http.ResponseWriter, r *http.Request, target string, params url.Values) {
+	u, err := url.Parse(target)
+	if err != nil {
+		http.Error(w, "Неверный адрес перенаправления", http.StatusBadRequest)
+		return
+	}
+
+	query := u.Query()
+	for k, values := range params {
+		for _, v := range values {
+			if v != "" {
+				query.Add(k, v)
+			}
+		}
+	}
+	u.RawQuery = query.Encode()
+
+	http.Redirect(w, r, u.String(), http.StatusFound)
+}
+
+// validRedirectURI проверяет, что redirect_uri абсолютный и без фрагмента
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Fragment != "" || u.Host == "" {
+		return false
+	}
+	return u.Scheme == "https" || u.Scheme == "http"
+}
+
+// bearerToken извлекает токен из заголовка Authorization
+func bearerToken(r *http.Request) string {
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return ""
+	}
+	return parts[1]
+}
+
+// parseScope разбирает строку scope в множество
+func parseScope(scope string) map[string]bool {
+	scopes := make(map[string]bool)
+	for _, s := range strings.Fields(scope) {
+		scopes[s] = true
+	}
+	return scopes
+}
+
+// formatScope оставляет только поддерживаемые scope в фиксированном порядке
+func formatScope(scopes map[string]bool) string {
+	var result []string
+	for _, s := range []string{ScopeOpenID, ScopeProfile, ScopeEmail} {
+		if scopes[s] {
+			result = append(result, s)
+		}
+	}
+	return strings.Join(result, " ")
+}
+
+// containsString проверяет наличие строки в срезе
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// randomToken генерирует случайную строку в base64url
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// randomHex генерирует случайную hex строку
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// hashToken возвращает SHA-256 от токена для хранения в базе данных
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,553 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/gateway"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/service"
+	pb "k8s-go-grpc-react/proto"
+)
+
+const (
+	testIssuer      = "https://auth.example.com"
+	testRedirectURI = "https://app.example.com/callback"
+	testVerifier    = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+// testProvider провайдер с базой данных в памяти и пользователями user и admin
+type testProvider struct {
+	*Provider
+	handler    http.Handler
+	oauthRepo  repository.OAuthRepository
+	userRepo   repository.UserRepository
+	jwtService auth.JWTService
+	user       *models.User
+	userToken  string
+	adminToken string
+}
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	oauthRepo := repository.NewOAuthRepository(db)
+	userRepo := repository.NewUserRepository(db)
+	jwtService := auth.NewJWTService("test-secret", time.Hour)
+
+	config := DefaultConfig(testIssuer)
+	config.SigningKeyEncryptionKey = "test-key"
+	p := &testProvider{
+		Provider:   NewProvider(config, oauthRepo, userRepo, jwtService, logger.Discard()),
+		oauthRepo:  oauthRepo,
+		userRepo:   userRepo,
+		jwtService: jwtService,
+	}
+	require.NoError(t, p.keys.Rotate(context.Background()))
+
+	mux := http.NewServeMux()
+	p.RegisterRoutes(mux)
+	p.handler = mux
+
+	p.user = &models.User{Name: "Ann", Email: "ann@example.com", PasswordHash: "hash", Role: "user", IsActive: true}
+	admin := &models.User{Name: "Admin", Email: "admin@example.com", PasswordHash: "hash", Role: "admin", IsActive: true}
+	for _, user := range []*models.User{p.user, admin} {
+		require.NoError(t, userRepo.Create(context.Background(), user))
+	}
+	p.userToken, err = jwtService.GenerateToken(p.user.ID, p.user.Email, p.user.Role)
+	require.NoError(t, err)
+	p.adminToken, err = jwtService.GenerateToken(admin.ID, admin.Email, admin.Role)
+	require.NoError(t, err)
+	return p
+}
+
+// do выполняет запрос к провайдеру
+func (p *testProvider) do(r *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	p.handler.ServeHTTP(rec, r)
+	return rec
+}
+
+// registerClient регистрирует клиента от имени администратора
+func (p *testProvider) registerClient(t *testing.T, public bool) registerClientResponse {
+	t.Helper()
+	body, err := json.Marshal(registerClientRequest{Name: "App", RedirectURIs: []string{testRedirectURI}, Public: public})
+	require.NoError(t, err)
+	r := httptest.NewRequest(http.MethodPost, clientsPath, strings.NewReader(string(body)))
+	r.Header.Set("Authorization", "Bearer "+p.adminToken)
+	rec := p.do(r)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var client registerClientResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &client))
+	return client
+}
+
+// authorizeRequest запрос кода авторизации для клиента без аутентификации пользователя
+func authorizeRequest(clientID string) *http.Request {
+	return httptest.NewRequest(http.MethodGet, authorizePath+"?"+url.Values{
+		"client_id":             {clientID},
+		"redirect_uri":          {testRedirectURI},
+		"response_type":         {"code"},
+		"scope":                 {"openid email profile"},
+		"state":                 {"xyz"},
+		"nonce":                 {"n-1"},
+		"code_challenge":        {S256Challenge(testVerifier)},
+		"code_challenge_method": {"S256"},
+	}.Encode(), nil)
+}
+
+// authorize получает код авторизации для клиента от имени пользователя
+func (p *testProvider) authorize(t *testing.T, clientID string) string {
+	t.Helper()
+	r := authorizeRequest(clientID)
+	r.Header.Set("Authorization", "Bearer "+p.userToken)
+	return codeFromRedirect(t, p.do(r))
+}
+
+// codeFromRedirect возвращает код авторизации из перенаправления на redirect_uri
+func codeFromRedirect(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	require.Equal(t, http.StatusFound, rec.Code, rec.Body.String())
+
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	require.Empty(t, location.Query().Get("error"), location.Query().Get("error_description"))
+	assert.Equal(t, "xyz", location.Query().Get("state"))
+	return location.Query().Get("code")
+}
+
+// exchange обменивает код на токены: конфиденциальный клиент аутентифицируется
+// через client_secret_basic, публичный передает только client_id
+func (p *testProvider) exchange(client registerClientResponse, form url.Values) *httptest.ResponseRecorder {
+	form.Set("grant_type", "authorization_code")
+	if client.ClientSecret == "" {
+		form.Set("client_id", client.ClientID)
+	}
+	r := httptest.NewRequest(http.MethodPost, tokenPath, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if client.ClientSecret != "" {
+		r.SetBasicAuth(client.ClientID, client.ClientSecret)
+	}
+	return p.do(r)
+}
+
+// oauthError возвращает код ошибки OAuth2 из ответа
+func oauthError(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var resp errorResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	return resp.Error
+}
+
+func TestProvider_AuthorizationCodeFlow(t *testing.T) {
+	p := newTestProvider(t)
+	client := p.registerClient(t, false)
+	code := p.authorize(t, client.ClientID)
+
+	rec := p.exchange(client, url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "no-store", rec.Header().Get("Cache-Control"))
+	var tokens tokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	assert.Equal(t, "openid profile email", tokens.Scope)
+
+	// ID токен подписан ключом из JWKS
+	claims := &IDTokenClaims{}
+	_, err := jwt.ParseWithClaims(tokens.IDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		key, ok := p.keys.publicKey(token.Header["kid"].(string))
+		require.True(t, ok)
+		return key, nil
+	}, jwt.WithIssuer(testIssuer), jwt.WithAudience(client.ClientID))
+	require.NoError(t, err)
+	assert.Equal(t, "n-1", claims.Nonce)
+	assert.Equal(t, "ann@example.com", claims.Email)
+
+	r := httptest.NewRequest(http.MethodGet, userinfoPath, nil)
+	r.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	rec = p.do(r)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"email":"ann@example.com"`)
+
+	// ID токен не принимается вместо access токена
+	r = httptest.NewRequest(http.MethodGet, userinfoPath, nil)
+	r.Header.Set("Authorization", "Bearer "+tokens.IDToken)
+	assert.Equal(t, http.StatusUnauthorized, p.do(r).Code)
+
+	// Код одноразовый
+	rec = p.exchange(client, url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, errInvalidGrant, oauthError(t, rec))
+}
+
+// newTestGateway запускает REST gateway поверх сервиса пользователей с теми же
+// репозиторием и секретом JWT, что у провайдера
+func (p *testProvider) newTestGateway(t *testing.T) http.Handler {
+	t.Helper()
+	lis := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	pb.RegisterUserServiceServer(server, service.NewUserService(p.userRepo, p.jwtService))
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	gw, err := gateway.New(context.Background(), conn, gateway.Options{SessionTTL: time.Hour})
+	require.NoError(t, err)
+	return gw
+}
+
+func TestProvider_AuthorizeWithLoginSessionCookie(t *testing.T) {
+	p := newTestProvider(t)
+	client := p.registerClient(t, false)
+	gw := p.newTestGateway(t)
+
+	hash, err := p.jwtService.HashPassword("password123")
+	require.NoError(t, err)
+	user := &models.User{Name: "Bob", Email: "bob@example.com", PasswordHash: hash, Role: "user", IsActive: true}
+	require.NoError(t, p.userRepo.Create(context.Background(), user))
+
+	// Без сессии код не выдается
+	rec := p.do(authorizeRequest(client.ClientID))
+	require.Equal(t, http.StatusFound, rec.Code)
+	location, err := url.Parse(rec.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, errLoginRequired, location.Query().Get("error"))
+
+	// Вход через REST API устанавливает cookie сессии
+	login := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login",
+		strings.NewReader(`{"email":"bob@example.com","password":"password123"}`))
+	loginRec := httptest.NewRecorder()
+	gw.ServeHTTP(loginRec, login)
+	require.Equal(t, http.StatusOK, loginRec.Code, loginRec.Body.String())
+	var session *http.Cookie
+	for _, cookie := range loginRec.Result().Cookies() {
+		if cookie.Name == p.config.SessionCookie {
+			session = cookie
+		}
+	}
+	require.NotNil(t, session, "вход устанавливает cookie сессии")
+	assert.True(t, session.HttpOnly)
+	assert.True(t, session.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, session.SameSite)
+
+	// Браузер передает cookie провайдеру, и пользователь получает код без повторного входа
+	r := authorizeRequest(client.ClientID)
+	r.AddCookie(&http.Cookie{Name: session.Name, Value: session.Value})
+	code := codeFromRedirect(t, p.do(r))
+
+	rec = p.exchange(client, url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}})
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var tokens tokenResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &tokens))
+	claims := &IDTokenClaims{}
+	_, err = jwt.ParseWithClaims(tokens.IDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		key, ok := p.keys.publicKey(token.Header["kid"].(string))
+		require.True(t, ok)
+		return key, nil
+	}, jwt.WithIssuer(testIssuer), jwt.WithAudience(client.ClientID))
+	require.NoError(t, err)
+	assert.Equal(t, strconv.FormatUint(uint64(user.ID), 10), claims.Subject)
+	assert.Equal(t, "bob@example.com", claims.Email)
+}
+
+func TestProvider_TokenErrors(t *testing.T) {
+	p := newTestProvider(t)
+	client := p.registerClient(t, false)
+	other := p.registerClient(t, false)
+	public := p.registerClient(t, true)
+
+	testCases := []struct {
+		name   string
+		client registerClientResponse
+		form   func(code string) url.Values
+		status int
+		error  string
+	}{
+		{
+			name:   "Wrong verifier",
+			client: client,
+			form: func(code string) url.Values {
+				return url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {strings.Repeat("a", 43)}}
+			},
+			status: http.StatusBadRequest,
+			error:  errInvalidGrant,
+		},
+		{
+			name:   "Redirect URI mismatch",
+			client: client,
+			form: func(code string) url.Values {
+				return url.Values{"code": {code}, "redirect_uri": {"https://app.example.com/other"}, "code_verifier": {testVerifier}}
+			},
+			status: http.StatusBadRequest,
+			error:  errInvalidGrant,
+		},
+		{
+			name:   "Code issued to another client",
+			client: other,
+			form: func(code string) url.Values {
+				return url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}}
+			},
+			status: http.StatusBadRequest,
+			error:  errInvalidGrant,
+		},
+		{
+			name:   "Wrong client secret",
+			client: registerClientResponse{ClientID: client.ClientID, ClientSecret: "wrong"},
+			form: func(code string) url.Values {
+				return url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}}
+			},
+			status: http.StatusUnauthorized,
+			error:  errInvalidClient,
+		},
+		{
+			name:   "Public client with secret",
+			client: registerClientResponse{ClientID: public.ClientID, ClientSecret: "secret"},
+			form: func(code string) url.Values {
+				return url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}}
+			},
+			status: http.StatusUnauthorized,
+			error:  errInvalidClient,
+		},
+		{
+			name:   "Unknown code",
+			client: client,
+			form: func(string) url.Values {
+				return url.Values{"code": {"unknown"}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}}
+			},
+			status: http.StatusBadRequest,
+			error:  errInvalidGrant,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			code := p.authorize(t, client.ClientID)
+			rec := p.exchange(tc.client, tc.form(code))
+			assert.Equal(t, tc.status, rec.Code, rec.Body.String())
+			assert.Equal(t, tc.error, oauthError(t, rec))
+		})
+	}
+
+	t.Run("Public client without secret", func(t *testing.T) {
+		code := p.authorize(t, public.ClientID)
+		rec := p.exchange(public, url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}})
+		assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	})
+}
+
+func TestProvider_ExpiredCode(t *testing.T) {
+	p := newTestProvider(t)
+	client := p.registerClient(t, false)
+
+	code := "expired-code"
+	require.NoError(t, p.oauthRepo.SaveAuthorizationCode(context.Background(), &models.OAuthAuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              p.user.ID,
+		RedirectURI:         testRedirectURI,
+		Scope:               ScopeOpenID,
+		CodeChallenge:       S256Challenge(testVerifier),
+		CodeChallengeMethod: codeChallengeMethodS256,
+		AuthTime:            time.Now().Add(-time.Hour),
+		ExpiresAt:           time.Now().Add(-time.Minute),
+	}))
+
+	rec := p.exchange(client, url.Values{"code": {code}, "redirect_uri": {testRedirectURI}, "code_verifier": {testVerifier}})
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, errInvalidGrant, oauthError(t, rec))
+}
+
+func TestProvider_AuthorizeRejectsUnregisteredRedirectURI(t *testing.T) {
+	p := newTestProvider(t)
+	client := p.registerClient(t, false)
+
+	r := httptest.NewRequest(http.MethodGet, authorizePath+"?"+url.Values{
+		"client_id":     {client.ClientID},
+		"redirect_uri":  {"https://evil.example.com/callback"},
+		"response_type": {"code"},
+		"scope":         {"openid"},
+	}.Encode(), nil)
+	r.Header.Set("Authorization", "Bearer "+p.userToken)
+	rec := p.do(r)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "на незарегистрированный адрес не перенаправляем")
+	assert.Empty(t, rec.Header().Get("Location"))
+	assert.Equal(t, errInvalidRequest, oauthError(t, rec))
+}
+
+func TestProvider_ClientsAdminOnly(t *testing.T) {
+	p := newTestProvider(t)
+
+	testCases := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "Anonymous", token: "", status: http.StatusUnauthorized},
+		{name: "User", token: p.userToken, status: http.StatusForbidden},
+		{name: "Admin", token: p.adminToken, status: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, method := range []string{http.MethodGet, http.MethodPost, http.MethodDelete} {
+				if method != http.MethodGet && tc.status == http.StatusOK {
+					continue
+				}
+				r := httptest.NewRequest(method, clientsPath, strings.NewReader(`{}`))
+				if tc.token != "" {
+					r.Header.Set("Authorization", "Bearer "+tc.token)
+				}
+				assert.Equal(t, tc.status, p.do(r).Code, method)
+			}
+		})
+	}
+
+	client := p.registerClient(t, false)
+	assert.NotEmpty(t, client.ClientSecret, "секрет возвращается при регистрации")
+	r := httptest.NewRequest(http.MethodGet, clientsPath, nil)
+	r.Header.Set("Authorization", "Bearer "+p.adminToken)
+	rec := p.do(r)
+	assert.Contains(t, rec.Body.String(), client.ClientID)
+	assert.NotContains(t, rec.Body.String(), client.ClientSecret, "секрет не возвращается в списке")
+}
+
+func TestKeyManager_EncryptsKeys(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+	repo := repository.NewOAuthRepository(db)
+	ctx := context.Background()
+
+	manager := NewKeyManager(repo, time.Hour, time.Hour, "key")
+	require.NoError(t, manager.Rotate(ctx))
+
+	stored, err := repo.ListSigningKeys(ctx, time.Now())
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.True(t, strings.HasPrefix(stored[0].PrivateKeyPEM, encryptedKeyPrefix))
+	assert.NotContains(t, stored[0].PrivateKeyPEM, "PRIVATE KEY")
+
+	// Реплика с тем же ключом шифрования использует сохраненный ключ подписи
+	replica := NewKeyManager(repo, time.Hour, time.Hour, "key")
+	require.NoError(t, replica.Rotate(ctx))
+	assert.Equal(t, manager.JWKS(), replica.JWKS())
+
+	assert.Error(t, NewKeyManager(repo, time.Hour, time.Hour, "other").Rotate(ctx), "неверный ключ шифрования")
+	assert.Error(t, NewKeyManager(repo, time.Hour, time.Hour, "").Rotate(ctx), "ключ шифрования не задан")
+}
+
+func TestKeyManager_ConcurrentRotateCreatesOneKey(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+	repo := repository.NewOAuthRepository(db)
+
+	managers := make([]*KeyManager, 4)
+	var wg sync.WaitGroup
+	for i := range managers {
+		managers[i] = NewKeyManager(repo, time.Hour, time.Hour, "key")
+		wg.Add(1)
+		go func(m *KeyManager) {
+			defer wg.Done()
+			assert.NoError(t, m.Rotate(context.Background()))
+		}(managers[i])
+	}
+	wg.Wait()
+
+	stored, err := repo.ListSigningKeys(context.Background(), time.Now())
+	require.NoError(t, err)
+	assert.Len(t, stored, 1)
+	for _, m := range managers {
+		key, err := m.current()
+		require.NoError(t, err)
+		assert.Equal(t, stored[0].KID, key.kid)
+	}
+}
+
+func TestKeyManager_PrepublishesNextKey(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+	repo := repository.NewOAuthRepository(db)
+	ctx := context.Background()
+
+	manager := NewKeyManager(repo, time.Hour, time.Hour, "key")
+	replica := NewKeyManager(repo, time.Hour, time.Hour, "key")
+	lead := manager.prepublish() + manager.pollInterval()
+	start := time.Now()
+
+	// Первый ключ подписывает сразу
+	require.NoError(t, manager.rotate(ctx, start))
+	first, err := manager.signingKey(start)
+	require.NoError(t, err)
+	assert.Len(t, manager.JWKS().Keys, 1)
+
+	// До окна публикации следующий ключ не создается
+	require.NoError(t, manager.rotate(ctx, first.activeUntil.Add(-lead-time.Second)))
+	assert.Len(t, manager.JWKS().Keys, 1)
+
+	// В окне публикации следующий ключ публикуется, но не подписывает до окончания текущего
+	now := first.activeUntil.Add(-lead)
+	require.NoError(t, manager.rotate(ctx, now))
+	require.NoError(t, replica.rotate(ctx, now))
+	assert.Len(t, manager.JWKS().Keys, 2)
+	assert.Equal(t, manager.JWKS(), replica.JWKS(), "реплика не создает второй следующий ключ")
+
+	key, err := manager.signingKey(first.activeUntil.Add(-time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, first.kid, key.kid)
+
+	next, err := manager.signingKey(first.activeUntil)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.kid, next.kid)
+	assert.Equal(t, first.activeUntil, next.activeFrom)
+	assert.False(t, next.activeFrom.Before(now.Add(manager.prepublish())))
+
+	// Запоздавшая ротация публикует ключ заранее, а подпись продолжается прежним ключом
+	now = next.activeUntil.Add(time.Minute)
+	require.NoError(t, manager.rotate(ctx, now))
+	key, err = manager.signingKey(now)
+	require.NoError(t, err)
+	assert.Equal(t, next.kid, key.kid)
+
+	late, err := manager.signingKey(now.Add(manager.prepublish()))
+	require.NoError(t, err)
+	assert.NotEqual(t, next.kid, late.kid)
+	assert.Equal(t, now.Add(manager.prepublish()), late.activeFrom)
+}
@@ -0,0 +1,351 @@
+package oidc
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+const (
+	signingAlgorithm = "RS256"
+	rsaKeyBits       = 2048
+
+	// encryptedKeyPrefix отмечает закрытый ключ, зашифрованный AES-256-GCM:
+	// за ним в base64 следуют nonce и шифротекст
+	encryptedKeyPrefix = "enc:v1:"
+
+	// jwksMaxAge сколько клиенты могут кешировать JWKS
+	jwksMaxAge = 5 * time.Minute
+)
+
+// signingKey ключ подписи, загруженный в память
+type signingKey struct {
+	kid         string
+	privateKey  *rsa.PrivateKey
+	activeFrom  time.Time
+	activeUntil time.Time
+	expiresAt   time.Time
+}
+
+// KeyManager управляет ротацией ключей подписи ID токенов.
+// Ключи хранятся в базе данных, поэтому все реплики сервера подписывают
+// токены одним и тем же ключом и публикуют одинаковый JWKS. Закрытые ключи
+// шифруются ключом encryptionKey, доступ к базе данных не раскрывает их.
+// Ключи, сохраненные без шифрования до его включения, читаются как есть и
+// удаляются по истечении срока.
+//
+// Следующий ключ создается заранее и публикуется в JWKS не позже чем за
+// jwksMaxAge плюс интервал опроса до начала подписи: к этому моменту его
+// загрузили все реплики, а клиенты обновили кеш JWKS.
+type KeyManager struct {
+	repo             repository.OAuthRepository
+	rotationInterval time.Duration
+	verificationTTL  time.Duration
+	encryptionKey    [32]byte
+	encrypt          bool
+
+	mu   sync.RWMutex
+	keys []*signingKey // Отсортированы по началу подписи от нового к старому
+}
+
+// NewKeyManager создает новый менеджер ключей.
+// rotationInterval - как долго ключ используется для подписи,
+// verificationTTL - сколько ключ публикуется в JWKS после окончания подписи,
+// encryptionKey - секрет для шифрования закрытых ключей; пустой секрет
+// отключает шифрование (конфигурация сервера требует его при включенном OIDC).
+func NewKeyManager(repo repository.OAuthRepository, rotationInterval, verificationTTL time.Duration, encryptionKey string) *KeyManager {
+	return &KeyManager{
+		repo:             repo,
+		rotationInterval: rotationInterval,
+		verificationTTL:  verificationTTL,
+		encryptionKey:    sha256.Sum256([]byte(encryptionKey)),
+		encrypt:          encryptionKey != "",
+	}
+}
+
+// Rotate загружает ключи из хранилища и создает следующий ключ, если подпись
+// текущим скоро закончится. Если ротацию одновременно начали несколько реплик,
+// ключ создает одна из них, остальные загружают его.
+func (m *KeyManager) Rotate(ctx context.Context) error {
+	return m.rotate(ctx, time.Now())
+}
+
+// rotate выполняет ротацию на момент now
+func (m *KeyManager) rotate(ctx context.Context, now time.Time) error {
+	if err := m.repo.DeleteExpiredSigningKeys(ctx, now); err != nil {
+		return err
+	}
+
+	keys, err := m.load(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	// Первый ключ подписывает сразу: токенов, которые нужно проверять, еще нет
+	activeFrom, after := now, now
+	if len(keys) > 0 {
+		after = keys[0].activeUntil
+		for _, key := range keys[1:] {
+			if key.activeUntil.After(after) {
+				after = key.activeUntil
+			}
+		}
+		activeFrom = now.Add(m.prepublish())
+		if after.After(activeFrom) {
+			activeFrom = after
+		}
+	}
+
+	// Следующий ключ создается с запасом в интервал опроса: ротация может
+	// выполниться не раньше следующего тика
+	if len(keys) == 0 || !after.After(now.Add(m.prepublish()+m.pollInterval())) {
+		_, err := m.repo.CreateSigningKeyIfNoneActiveAfter(ctx, after, func() (*models.OIDCSigningKey, error) {
+			return m.generate(activeFrom)
+		})
+		if err != nil {
+			return err
+		}
+		if keys, err = m.load(ctx, now); err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return errors.New("нет доступного ключа подписи")
+		}
+	}
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+
+	return nil
+}
+
+// pollInterval как часто реплики перечитывают ключи из хранилища
+func (m *KeyManager) pollInterval() time.Duration {
+	return m.rotationInterval / 10
+}
+
+// prepublish за сколько до начала подписи ключ публикуется в JWKS
+func (m *KeyManager) prepublish() time.Duration {
+	return jwksMaxAge + m.pollInterval()
+}
+
+// load загружает неистекшие ключи из хранилища, начиная с позже всех начинающего подпись
+func (m *KeyManager) load(ctx context.Context, now time.Time) ([]*signingKey, error) {
+	stored, err := m.repo.ListSigningKeys(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*signingKey, 0, len(stored))
+	for _, s := range stored {
+		key, err := m.decodeSigningKey(s)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].activeFrom.After(keys[j].activeFrom)
+	})
+	return keys, nil
+}
+
+// Run периодически выполняет ротацию ключей до отмены контекста
+func (m *KeyManager) Run(ctx context.Context, onError func(error)) {
+	// Проверяем чаще интервала ротации, чтобы подхватывать ключи, созданные другими репликами
+	ticker := time.NewTicker(m.pollInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Rotate(ctx); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}
+}
+
+// current возвращает ключ для подписи новых токенов
+func (m *KeyManager) current() (*signingKey, error) {
+	return m.signingKey(time.Now())
+}
+
+// signingKey возвращает ключ, подписывающий в момент now: последний из уже
+// начавших подпись. Заранее опубликованные ключи не используются до начала
+// подписи. Если ротация запаздывает, подпись продолжается прежним ключом.
+func (m *KeyManager) signingKey(now time.Time) (*signingKey, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if !key.activeFrom.After(now) {
+			return key, nil
+		}
+	}
+	return nil, errors.New("нет доступного ключа подписи")
+}
+
+// publicKey возвращает публичный ключ по идентификатору
+func (m *KeyManager) publicKey(kid string) (*rsa.PublicKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.kid == kid {
+			return &key.privateKey.PublicKey, true
+		}
+	}
+	return nil, false
+}
+
+// JWKS возвращает набор публичных ключей в формате RFC 7517
+func (m *KeyManager) JWKS() JSONWebKeySet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0, len(m.keys))}
+	for _, key := range m.keys {
+		pub := key.privateKey.PublicKey
+		set.Keys = append(set.Keys, JSONWebKey{
+			KeyType:   "RSA",
+			Use:       "sig",
+			Algorithm: signingAlgorithm,
+			KeyID:     key.kid,
+			N:         base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			E:         base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		})
+	}
+	return set
+}
+
+// generate создает новый ключ подписи, начинающий подпись в момент activeFrom
+func (m *KeyManager) generate(activeFrom time.Time) (*models.OIDCSigningKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка генерации ключа подписи: %w", err)
+	}
+
+	der := x509.MarshalPKCS1PrivateKey(privateKey)
+	pubDER, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации публичного ключа: %w", err)
+	}
+	thumbprint := sha256.Sum256(pubDER)
+	kid := base64.RawURLEncoding.EncodeToString(thumbprint[:16])
+
+	privateKeyPEM, err := m.sealKey(kid, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.OIDCSigningKey{
+		KID:           kid,
+		Algorithm:     signingAlgorithm,
+		PrivateKeyPEM: privateKeyPEM,
+		ActiveFrom:    activeFrom,
+		ActiveUntil:   activeFrom.Add(m.rotationInterval),
+		ExpiresAt:     activeFrom.Add(m.rotationInterval + m.verificationTTL),
+	}, nil
+}
+
+// decodeSigningKey восстанавливает ключ подписи из хранилища
+func (m *KeyManager) decodeSigningKey(s *models.OIDCSigningKey) (*signingKey, error) {
+	privateKeyPEM, err := m.openKey(s)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, fmt.Errorf("ключ подписи %s поврежден", s.KID)
+	}
+
+	privateKey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора ключа подписи %s: %w", s.KID, err)
+	}
+
+	return &signingKey{
+		kid:         s.KID,
+		privateKey:  privateKey,
+		activeFrom:  s.ActiveFrom,
+		activeUntil: s.ActiveUntil,
+		expiresAt:   s.ExpiresAt,
+	}, nil
+}
+
+// aead возвращает шифр закрытых ключей
+func (m *KeyManager) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(m.encryptionKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания шифра ключей подписи: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// sealKey шифрует закрытый ключ kid. Идентификатор ключа входит в
+// аутентифицированные данные: шифротекст нельзя подставить другому ключу.
+func (m *KeyManager) sealKey(kid string, privateKeyPEM []byte) (string, error) {
+	if !m.encrypt {
+		return string(privateKeyPEM), nil
+	}
+
+	aead, err := m.aead()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("ошибка генерации nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, privateKeyPEM, []byte(kid))
+	return encryptedKeyPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// openKey расшифровывает закрытый ключ из хранилища
+func (m *KeyManager) openKey(s *models.OIDCSigningKey) ([]byte, error) {
+	encoded, encrypted := strings.CutPrefix(s.PrivateKeyPEM, encryptedKeyPrefix)
+	if !encrypted {
+		return []byte(s.PrivateKeyPEM), nil
+	}
+	if !m.encrypt {
+		return nil, fmt.Errorf("ключ подписи %s зашифрован, а ключ шифрования не задан", s.KID)
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("ключ подписи %s поврежден", s.KID)
+	}
+	aead, err := m.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("ключ подписи %s поврежден", s.KID)
+	}
+	privateKeyPEM, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(s.KID))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расшифровки ключа подписи %s: неверный ключ шифрования", s.KID)
+	}
+	return privateKeyPEM, nil
+}
@@ -0,0 +1,31 @@
+package oidc
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+const codeChallengeMethodS256 = "S256"
+
+// codeVerifierPattern допустимый формат code_verifier (RFC 7636, раздел 4.1)
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+// codeChallengePattern допустимый формат code_challenge для метода S256
+var codeChallengePattern = regexp.MustCompile(`^[A-Za-z0-9\-_]{43}$`)
+
+// S256Challenge вычисляет code_challenge из code_verifier по методу S256
+func S256Challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// verifyPKCE проверяет code_verifier относительно сохраненного code_challenge.
+// Метод plain не поддерживается, так как не защищает от перехвата кода.
+func verifyPKCE(challenge, method, verifier string) bool {
+	if method != codeChallengeMethodS256 || !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(S256Challenge(verifier)), []byte(challenge)) == 1
+}
@@ -0,0 +1,38 @@
+package oidc
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestS256Challenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", S256Challenge(verifier))
+}
+
+func TestVerifyPKCE(t *testing.T) {
+	verifier := strings.Repeat("a", 43)
+	challenge := S256Challenge(verifier)
+
+	testCases := []struct {
+		name     string
+		method   string
+		verifier string
+		expected bool
+	}{
+		{name: "Valid verifier", method: "S256", verifier: verifier, expected: true},
+		{name: "Wrong verifier", method: "S256", verifier: strings.Repeat("b", 43), expected: false},
+		{name: "Plain method", method: "plain", verifier: verifier, expected: false},
+		{name: "Too short verifier", method: "S256", verifier: "short", expected: false},
+		{name: "Empty verifier", method: "S256", verifier: "", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, verifyPKCE(challenge, tc.method, tc.verifier))
+		})
+	}
+}
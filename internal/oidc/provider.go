@@ -0,0 +1,200 @@
+package oidc
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+const (
+	// DiscoveryPath путь к метаданным OIDC провайдера
+	DiscoveryPath = "/.well-known/openid-configuration"
+
+	authorizePath = "/oauth2/authorize"
+	tokenPath     = "/oauth2/token"
+	userinfoPath  = "/oauth2/userinfo"
+	jwksPath      = "/oauth2/jwks"
+	clientsPath   = "/oauth2/clients"
+)
+
+// Поддерживаемые scope
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Config содержит настройки OIDC провайдера
+type Config struct {
+	// Issuer внешний URL провайдера, например https://auth.example.com
+	Issuer string
+	// LoginURL страница входа, на которую перенаправляется неаутентифицированный пользователь
+	LoginURL string
+	// SessionCookie имя cookie с JWT токеном платформы
+	SessionCookie string
+	// SigningKeyEncryptionKey секрет для шифрования ключей подписи в базе данных
+	SigningKeyEncryptionKey string
+
+	CodeTTL             time.Duration
+	AccessTokenTTL      time.Duration
+	IDTokenTTL          time.Duration
+	KeyRotationInterval time.Duration
+	KeyVerificationTTL  time.Duration
+}
+
+// DefaultConfig возвращает конфигурацию провайдера по умолчанию
+func DefaultConfig(issuer string) Config {
+	return Config{
+		Issuer:              strings.TrimSuffix(issuer, "/"),
+		SessionCookie:       auth.SessionCookie,
+		CodeTTL:             time.Minute,
+		AccessTokenTTL:      time.Hour,
+		IDTokenTTL:          time.Hour,
+		KeyRotationInterval: 24 * time.Hour,
+		KeyVerificationTTL:  48 * time.Hour,
+	}
+}
+
+// Provider минимальный OpenID Connect провайдер поверх учетных записей платформы.
+// Поддерживается только authorization code flow с обязательным PKCE (S256).
+type Provider struct {
+	config     Config
+	oauthRepo  repository.OAuthRepository
+	userRepo   repository.UserRepository
+	jwtService auth.JWTService
+	auth       *auth.AuthMiddleware
+	keys       *KeyManager
+	log        *slog.Logger
+}
+
+// NewProvider создает новый OIDC провайдер
+func NewProvider(config Config, oauthRepo repository.OAuthRepository, userRepo repository.UserRepository, jwtService auth.JWTService, log *slog.Logger) *Provider {
+	return &Provider{
+		config:     config,
+		oauthRepo:  oauthRepo,
+		userRepo:   userRepo,
+		jwtService: jwtService,
+		auth:       auth.NewAuthMiddleware(jwtService, log),
+		keys:       NewKeyManager(oauthRepo, config.KeyRotationInterval, config.KeyVerificationTTL, config.SigningKeyEncryptionKey),
+		log:        logger.Component(log, "oidc"),
+	}
+}
+
+// Start загружает ключи подписи и запускает их фоновую ротацию
+func (p *Provider) Start(ctx context.Context) error {
+	if err := p.keys.Rotate(ctx); err != nil {
+		return err
+	}
+
+	go p.keys.Run(ctx, func(err error) {
+		p.log.Error("Ошибка ротации ключей подписи OIDC", logger.Err(err))
+	})
+
+	go p.cleanupCodes(ctx)
+
+	return nil
+}
+
+// RegisterRoutes регистрирует HTTP обработчики провайдера
+func (p *Provider) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc(DiscoveryPath, p.handleDiscovery)
+	mux.HandleFunc(jwksPath, p.handleJWKS)
+	mux.HandleFunc(authorizePath, p.handleAuthorize)
+	mux.HandleFunc(tokenPath, p.handleToken)
+	mux.HandleFunc(userinfoPath, p.handleUserinfo)
+	mux.Handle(clientsPath, p.auth.RequireAuth(p.auth.RequireRole("admin")(http.HandlerFunc(p.handleClients))))
+}
+
+// cleanupCodes периодически удаляет неиспользованные просроченные коды авторизации
+func (p *Provider) cleanupCodes(ctx context.Context) {
+	ticker := time.NewTicker(10 * p.config.CodeTTL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := p.oauthRepo.DeleteExpiredAuthorizationCodes(ctx, time.Now()); err != nil {
+				p.log.Warn("Ошибка очистки кодов авторизации", logger.Err(err))
+			}
+		}
+	}
+}
+
+// discoveryDocument метаданные провайдера (OpenID Connect Discovery 1.0)
+type discoveryDocument struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// JSONWebKey публичный ключ в формате JWK
+type JSONWebKey struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"kid"`
+	N         string `json:"n"`
+	E         string `json:"e"`
+}
+
+// JSONWebKeySet набор публичных ключей в формате JWKS
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// handleDiscovery возвращает метаданные провайдера
+func (p *Provider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	issuer := p.config.Issuer
+	p.writeJSON(w, http.StatusOK, discoveryDocument{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + authorizePath,
+		TokenEndpoint:                     issuer + tokenPath,
+		UserinfoEndpoint:                  issuer + userinfoPath,
+		JWKSURI:                           issuer + jwksPath,
+		RegistrationEndpoint:              issuer + clientsPath,
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{signingAlgorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{codeChallengeMethodS256},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "name", "email", "role"},
+	})
+}
+
+// handleJWKS возвращает публичные ключи для проверки ID токенов
+func (p *Provider) handleJWKS(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(jwksMaxAge.Seconds())))
+	p.writeJSON(w, http.StatusOK, p.keys.JWKS())
+}
@@ -0,0 +1,129 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// accessTokenType значение заголовка typ для access токенов (RFC 9068)
+const accessTokenType = "at+jwt"
+
+// IDTokenClaims claims ID токена
+type IDTokenClaims struct {
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time"`
+	Name     string `json:"name,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Role     string `json:"role,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// AccessTokenClaims claims access токена для userinfo endpoint
+type AccessTokenClaims struct {
+	ClientID string `json:"client_id"`
+	Scope    string `json:"scope"`
+	jwt.RegisteredClaims
+}
+
+// subject возвращает идентификатор пользователя для claim sub
+func subject(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// sign подписывает claims текущим ключом провайдера
+func (p *Provider) sign(claims jwt.Claims, typ string) (string, error) {
+	key, err := p.keys.current()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = key.kid
+	if typ != "" {
+		token.Header["typ"] = typ
+	}
+
+	signed, err := token.SignedString(key.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("ошибка подписи токена: %w", err)
+	}
+	return signed, nil
+}
+
+// issueIDToken создает ID токен для клиента
+func (p *Provider) issueIDToken(user *models.User, code *models.OAuthAuthorizationCode, now time.Time) (string, error) {
+	claims := &IDTokenClaims{
+		Nonce:    code.Nonce,
+		AuthTime: code.AuthTime.Unix(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.config.Issuer,
+			Subject:   subject(user.ID),
+			Audience:  jwt.ClaimStrings{code.ClientID},
+			ExpiresAt: jwt.NewNumericDate(now.Add(p.config.IDTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	scopes := parseScope(code.Scope)
+	if scopes[ScopeProfile] {
+		claims.Name = user.Name
+		claims.Role = user.Role
+	}
+	if scopes[ScopeEmail] {
+		claims.Email = user.Email
+	}
+
+	return p.sign(claims, "")
+}
+
+// issueAccessToken создает access токен для userinfo endpoint
+func (p *Provider) issueAccessToken(user *models.User, code *models.OAuthAuthorizationCode, now time.Time) (string, error) {
+	claims := &AccessTokenClaims{
+		ClientID: code.ClientID,
+		Scope:    code.Scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.config.Issuer,
+			Subject:   subject(user.ID),
+			Audience:  jwt.ClaimStrings{p.config.Issuer + userinfoPath},
+			ExpiresAt: jwt.NewNumericDate(now.Add(p.config.AccessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
+		},
+	}
+
+	return p.sign(claims, accessTokenType)
+}
+
+// validateAccessToken проверяет access токен, выданный провайдером
+func (p *Provider) validateAccessToken(tokenString string) (*AccessTokenClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &AccessTokenClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != accessTokenType {
+			return nil, errors.New("токен не является токеном доступа")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, ok := p.keys.publicKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("неизвестный идентификатор ключа %q", kid)
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods([]string{signingAlgorithm}),
+		jwt.WithIssuer(p.config.Issuer),
+		jwt.WithAudience(p.config.Issuer+userinfoPath),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора токена: %w", err)
+	}
+
+	claims, ok := token.Claims.(*AccessTokenClaims)
+	if !ok || !token.Valid {
+		return nil, errors.New("недействительный токен")
+	}
+
+	return claims, nil
+}
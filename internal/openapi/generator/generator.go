@@ -0,0 +1,447 @@
+// Package generator строит документ OpenAPI 3.0 из proto файлов с аннотациями
+// google.api.http. Используется командой cmd/openapi-gen и проверкой расхождения
+// спецификации с proto.
+package generator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bufbuild/protocompile"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	"k8s-go-grpc-react/internal/auth"
+)
+
+const (
+	// BearerScheme схема аутентификации JWT токеном
+	BearerScheme = "bearerAuth"
+	// APIKeyScheme схема аутентификации ключом API
+	APIKeyScheme = "apiKeyAuth"
+
+	// statusSchema схема ошибки grpc-gateway
+	statusSchema = "Status"
+	// anySchema схема google.protobuf.Any в деталях ошибки
+	anySchema = "Any"
+
+	jsonContentType = "application/json"
+	// httpBodyMessage ответ произвольного типа содержимого
+	httpBodyMessage = "google.api.HttpBody"
+)
+
+// pathParamPattern параметр шаблона пути: {id} или {name=resources/*}
+var pathParamPattern = regexp.MustCompile(`\{([^}=]+)(=[^}]*)?\}`)
+
+// Options параметры генерации
+type Options struct {
+	// ImportPaths пути поиска proto файлов
+	ImportPaths []string
+	// Files proto файлы относительно ImportPaths
+	Files []string
+	// Title заголовок API
+	Title string
+	// Version версия API
+	Version string
+	// ServerURL базовый путь, под которым gateway обслуживает маршруты из proto
+	ServerURL string
+	// PublicMethods полные имена gRPC методов, не требующих аутентификации
+	PublicMethods []string
+}
+
+// DefaultOptions параметры генерации спецификации UserService относительно корня репозитория
+func DefaultOptions(root string) Options {
+	return Options{
+		ImportPaths:   []string{root, filepath.Join(root, "third_party", "googleapis")},
+		Files:         []string{"proto/user.proto"},
+		Title:         "UserService REST API",
+		Version:       "1.0.0",
+		ServerURL:     "/api",
+		PublicMethods: auth.DefaultPublicMethods,
+	}
+}
+
+// Generate компилирует proto файлы и возвращает документ OpenAPI в формате JSON
+func Generate(ctx context.Context, opts Options) ([]byte, error) {
+	compiler := protocompile.Compiler{
+		Resolver: protocompile.WithStandardImports(&protocompile.SourceResolver{
+			ImportPaths: opts.ImportPaths,
+		}),
+		SourceInfoMode: protocompile.SourceInfoStandard,
+	}
+
+	files, err := compiler.Compile(ctx, opts.Files...)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка компиляции proto: %w", err)
+	}
+
+	g := newGenerator(opts)
+	for _, file := range files {
+		if err := g.addFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.MarshalIndent(g.doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("ошибка сериализации OpenAPI: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// generator накапливает документ по мере обхода сервисов
+type generator struct {
+	doc           *Document
+	publicMethods map[string]bool
+	pkg           protoreflect.FullName // Пакет текущего файла
+}
+
+func newGenerator(opts Options) *generator {
+	publicMethods := make(map[string]bool, len(opts.PublicMethods))
+	for _, method := range opts.PublicMethods {
+		publicMethods[method] = true
+	}
+
+	doc := &Document{
+		OpenAPI: "3.0.3",
+		Info: Info{
+			Title:   opts.Title,
+			Version: opts.Version,
+		},
+		Servers:  []Server{{URL: opts.ServerURL}},
+		Security: []SecurityRequirement{{BearerScheme: {}}},
+		Paths:    make(map[string]*PathItem),
+		Components: Components{
+			Schemas: map[string]*Schema{
+				statusSchema: {
+					Type:        "object",
+					Description: "Ошибка в формате grpc-gateway: код gRPC, сообщение и детали",
+					Properties: map[string]*Schema{
+						"code":    {Type: "integer", Format: "int32", Description: "Код ошибки gRPC (google.rpc.Code)"},
+						"message": {Type: "string", Description: "Описание ошибки"},
+						"details": {Type: "array", Items: &Schema{Ref: schemaRef(anySchema)}},
+					},
+				},
+				anySchema: {
+					Type:                 "object",
+					Description:          "Детали ошибки с типом в поле @type",
+					Properties:           map[string]*Schema{"@type": {Type: "string"}},
+					AdditionalProperties: true,
+				},
+			},
+			SecuritySchemes: map[string]*SecurityScheme{
+				BearerScheme: {
+					Type:         "http",
+					Scheme:       "bearer",
+					BearerFormat: "JWT",
+					Description:  "JWT, выданный /v1/auth/login или /v1/auth/register",
+				},
+				APIKeyScheme: {
+					Type:        "apiKey",
+					In:          "header",
+					Name:        "X-API-Key",
+					Description: "Ключ API сервисных клиентов. Зарезервировано: операции пока принимают только Bearer",
+				},
+			},
+		},
+	}
+
+	return &generator{doc: doc, publicMethods: publicMethods}
+}
+
+// addFile добавляет операции всех сервисов файла
+func (g *generator) addFile(file protoreflect.FileDescriptor) error {
+	g.pkg = file.Package()
+	services := file.Services()
+	for i := 0; i < services.Len(); i++ {
+		service := services.Get(i)
+		g.doc.Tags = append(g.doc.Tags, Tag{
+			Name:        string(service.Name()),
+			Description: comment(service),
+		})
+		if g.doc.Info.Description == "" {
+			g.doc.Info.Description = comment(service)
+		}
+
+		methods := service.Methods()
+		for j := 0; j < methods.Len(); j++ {
+			if err := g.addMethod(service, methods.Get(j)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// addMethod добавляет операцию для метода с аннотацией google.api.http
+func (g *generator) addMethod(service protoreflect.ServiceDescriptor, method protoreflect.MethodDescriptor) error {
+	rule, err := httpRule(method)
+	if err != nil {
+		return fmt.Errorf("метод %s: %w", method.FullName(), err)
+	}
+	if rule == nil {
+		return nil
+	}
+
+	httpMethod, template := httpPattern(rule)
+	if template == "" {
+		return fmt.Errorf("метод %s: неподдерживаемый шаблон google.api.http", method.FullName())
+	}
+
+	// google.api.HttpBody gateway отдает как есть, с типом содержимого из content_type
+	var content map[string]MediaType
+	if method.Output().FullName() == httpBodyMessage {
+		content = map[string]MediaType{"*/*": {Schema: &Schema{Type: "string", Format: "binary"}}}
+	} else {
+		content = jsonContent(&Schema{Ref: g.messageSchema(method.Output())})
+	}
+
+	fullMethod := fmt.Sprintf("/%s/%s", service.FullName(), method.Name())
+	op := &Operation{
+		OperationID: fmt.Sprintf("%s_%s", service.Name(), method.Name()),
+		Summary:     comment(method),
+		Tags:        []string{string(service.Name())},
+		Responses: map[string]Response{
+			"200": {
+				Description: "Успешный ответ",
+				Content:     content,
+			},
+			"default": {
+				Description: "Ошибка",
+				Content:     jsonContent(&Schema{Ref: schemaRef(statusSchema)}),
+			},
+		},
+	}
+
+	if g.publicMethods[fullMethod] {
+		op.Security = &[]SecurityRequirement{}
+	} else {
+		op.Responses["401"] = Response{
+			Description: "Токен не предоставлен или недействителен",
+			Content:     jsonContent(&Schema{Ref: schemaRef(statusSchema)}),
+		}
+	}
+
+	// Параметры пути
+	input := method.Input()
+	pathFields := make(map[string]bool)
+	for _, match := range pathParamPattern.FindAllStringSubmatch(template, -1) {
+		name := match[1]
+		field := input.Fields().ByName(protoreflect.Name(name))
+		if field == nil {
+			return fmt.Errorf("метод %s: поле %s из шаблона пути не найдено", method.FullName(), name)
+		}
+		pathFields[name] = true
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   g.fieldSchema(field),
+		})
+	}
+
+	// Тело запроса или параметры строки запроса
+	switch rule.GetBody() {
+	case "*":
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(&Schema{Ref: g.messageSchema(input)}),
+		}
+	case "":
+		fields := input.Fields()
+		for i := 0; i < fields.Len(); i++ {
+			field := fields.Get(i)
+			if pathFields[string(field.Name())] || field.Kind() == protoreflect.MessageKind || field.IsMap() {
+				continue
+			}
+			op.Parameters = append(op.Parameters, Parameter{
+				Name:   string(field.Name()),
+				In:     "query",
+				Schema: g.fieldSchema(field),
+			})
+		}
+	default:
+		field := input.Fields().ByName(protoreflect.Name(rule.GetBody()))
+		if field == nil {
+			return fmt.Errorf("метод %s: поле тела %s не найдено", method.FullName(), rule.GetBody())
+		}
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  jsonContent(g.fieldSchema(field)),
+		}
+	}
+
+	path := pathParamPattern.ReplaceAllString(template, "{$1}")
+	item := g.doc.Paths[path]
+	if item == nil {
+		item = &PathItem{}
+		g.doc.Paths[path] = item
+	}
+
+	switch httpMethod {
+	case "get":
+		item.Get = op
+	case "put":
+		item.Put = op
+	case "post":
+		item.Post = op
+	case "delete":
+		item.Delete = op
+	case "patch":
+		item.Patch = op
+	}
+	return nil
+}
+
+// messageSchema регистрирует схему сообщения и возвращает ссылку на нее
+func (g *generator) messageSchema(message protoreflect.MessageDescriptor) string {
+	name := g.schemaName(message)
+	if _, ok := g.doc.Components.Schemas[name]; ok {
+		return schemaRef(name)
+	}
+
+	schema := &Schema{
+		Type:        "object",
+		Description: comment(message),
+		Properties:  make(map[string]*Schema),
+	}
+	// Регистрируем до обхода полей, чтобы рекурсивные сообщения не зацикливались
+	g.doc.Components.Schemas[name] = schema
+
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		fieldSchema := g.fieldSchema(field)
+		if description := comment(field); description != "" && fieldSchema.Ref == "" {
+			fieldSchema.Description = description
+		}
+		schema.Properties[string(field.Name())] = fieldSchema
+	}
+	return schemaRef(name)
+}
+
+// fieldSchema возвращает схему поля с учетом правил сериализации protojson
+func (g *generator) fieldSchema(field protoreflect.FieldDescriptor) *Schema {
+	if field.IsMap() {
+		return &Schema{
+			Type:                 "object",
+			AdditionalProperties: g.singularSchema(field.MapValue()),
+		}
+	}
+	if field.IsList() {
+		return &Schema{Type: "array", Items: g.singularSchema(field)}
+	}
+	return g.singularSchema(field)
+}
+
+// singularSchema возвращает схему одиночного значения поля
+func (g *generator) singularSchema(field protoreflect.FieldDescriptor) *Schema {
+	switch field.Kind() {
+	case protoreflect.BoolKind:
+		return &Schema{Type: "boolean"}
+	case protoreflect.StringKind:
+		return &Schema{Type: "string"}
+	case protoreflect.BytesKind:
+		return &Schema{Type: "string", Format: "byte"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+		return &Schema{Type: "integer", Format: "int32"}
+	case protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return &Schema{Type: "integer", Format: "int64"}
+	// protojson сериализует 64-битные целые строками
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+		return &Schema{Type: "string", Format: "int64"}
+	case protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return &Schema{Type: "string", Format: "uint64"}
+	case protoreflect.FloatKind:
+		return &Schema{Type: "number", Format: "float"}
+	case protoreflect.DoubleKind:
+		return &Schema{Type: "number", Format: "double"}
+	case protoreflect.EnumKind:
+		values := field.Enum().Values()
+		schema := &Schema{Type: "string"}
+		for i := 0; i < values.Len(); i++ {
+			schema.Enum = append(schema.Enum, string(values.Get(i).Name()))
+		}
+		return schema
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		switch field.Message().FullName() {
+		case "google.protobuf.Timestamp":
+			return &Schema{Type: "string", Format: "date-time"}
+		case "google.protobuf.Duration":
+			return &Schema{Type: "string"}
+		}
+		return &Schema{Ref: g.messageSchema(field.Message())}
+	default:
+		return &Schema{}
+	}
+}
+
+// httpRule возвращает аннотацию google.api.http метода или nil.
+// protocompile хранит опции как динамические сообщения, поэтому они
+// перечитываются в сгенерированный тип через глобальный реестр.
+func httpRule(method protoreflect.MethodDescriptor) (*annotations.HttpRule, error) {
+	data, err := proto.Marshal(method.Options())
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &descriptorpb.MethodOptions{}
+	if err := proto.Unmarshal(data, opts); err != nil {
+		return nil, err
+	}
+
+	rule, _ := proto.GetExtension(opts, annotations.E_Http).(*annotations.HttpRule)
+	return rule, nil
+}
+
+// httpPattern возвращает HTTP метод в нижнем регистре и шаблон пути
+func httpPattern(rule *annotations.HttpRule) (string, string) {
+	switch {
+	case rule.GetGet() != "":
+		return "get", rule.GetGet()
+	case rule.GetPost() != "":
+		return "post", rule.GetPost()
+	case rule.GetPut() != "":
+		return "put", rule.GetPut()
+	case rule.GetDelete() != "":
+		return "delete", rule.GetDelete()
+	case rule.GetPatch() != "":
+		return "patch", rule.GetPatch()
+	default:
+		return "", ""
+	}
+}
+
+// schemaName возвращает имя схемы сообщения: короткое для сообщений пакета сервиса, полное для остальных
+func (g *generator) schemaName(message protoreflect.MessageDescriptor) string {
+	if message.ParentFile().Package() == g.pkg {
+		return string(message.Name())
+	}
+	return string(message.FullName())
+}
+
+// schemaRef возвращает ссылку на схему в components
+func schemaRef(name string) string {
+	return "#/components/schemas/" + name
+}
+
+// jsonContent возвращает содержимое application/json со схемой
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{jsonContentType: {Schema: schema}}
+}
+
+// comment возвращает ведущий комментарий элемента proto
+func comment(desc protoreflect.Descriptor) string {
+	loc := desc.ParentFile().SourceLocations().ByDescriptor(desc)
+	lines := strings.Split(strings.TrimSpace(loc.LeadingComments), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimSpace(line)
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,21 @@
+package generator
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/openapi"
+)
+
+// TestSpecMatchesProto падает, если встроенная спецификация разошлась с proto.
+// Обновить спецификацию: go generate ./internal/openapi
+func TestSpecMatchesProto(t *testing.T) {
+	data, err := Generate(context.Background(), DefaultOptions("../../.."))
+	require.NoError(t, err)
+
+	assert.Equal(t, string(data), string(openapi.Spec()),
+		"internal/openapi/openapi.json устарел, выполните go generate ./internal/openapi")
+}
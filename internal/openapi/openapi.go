@@ -0,0 +1,137 @@
+// Package openapi отдает спецификацию OpenAPI REST API и страницу Swagger UI.
+// Спецификация генерируется из proto/user.proto командой cmd/openapi-gen
+// и встраивается в бинарный файл; расхождение с proto проверяется тестом.
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+)
+
+//go:generate go run ../../cmd/openapi-gen -root ../.. -out openapi.json
+
+//go:embed openapi.json
+var spec []byte
+
+// Spec возвращает сгенерированную спецификацию с именами полей как в proto
+func Spec() []byte {
+	return spec
+}
+
+// SpecHandler отдает спецификацию в JSON. Если gateway выводит поля в camelCase,
+// имена свойств и параметров в спецификации преобразуются так же, как в protojson.
+func SpecHandler(useProtoNames bool) (http.Handler, error) {
+	body := spec
+	if !useProtoNames {
+		var doc map[string]interface{}
+		if err := json.Unmarshal(spec, &doc); err != nil {
+			return nil, err
+		}
+		camelCaseNames(doc)
+
+		var err error
+		if body, err = json.MarshalIndent(doc, "", "  "); err != nil {
+			return nil, err
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(body)
+	}), nil
+}
+
+// uiTemplate страница Swagger UI; ресурсы загружаются с CDN фиксированной версии
+var uiTemplate = template.Must(template.New("ui").Parse(`<!DOCTYPE html>
+<html lang="ru">
+<head>
+  <meta charset="utf-8">
+  <title>UserService REST API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5.17.14/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({
+      url: {{.SpecURL}},
+      dom_id: "#swagger-ui",
+      persistAuthorization: true
+    });
+  </script>
+</body>
+</html>
+`))
+
+// UIHandler отдает страницу Swagger UI для спецификации по адресу specURL
+func UIHandler(specURL string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = uiTemplate.Execute(w, struct{ SpecURL string }{specURL})
+	})
+}
+
+// camelCaseNames переименовывает свойства схем и параметры запроса в lowerCamelCase
+func camelCaseNames(doc map[string]interface{}) {
+	if components, ok := doc["components"].(map[string]interface{}); ok {
+		if schemas, ok := components["schemas"].(map[string]interface{}); ok {
+			for _, schema := range schemas {
+				renameProperties(schema)
+			}
+		}
+	}
+
+	paths, _ := doc["paths"].(map[string]interface{})
+	for _, item := range paths {
+		operations, _ := item.(map[string]interface{})
+		for _, op := range operations {
+			params, _ := op.(map[string]interface{})["parameters"].([]interface{})
+			for _, param := range params {
+				if p, ok := param.(map[string]interface{}); ok && p["in"] == "query" {
+					p["name"] = jsonName(p["name"].(string))
+				}
+			}
+		}
+	}
+}
+
+// renameProperties переименовывает свойства схемы и вложенных схем
+func renameProperties(schema interface{}) {
+	s, ok := schema.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	if props, ok := s["properties"].(map[string]interface{}); ok {
+		renamed := make(map[string]interface{}, len(props))
+		for name, prop := range props {
+			renameProperties(prop)
+			renamed[jsonName(name)] = prop
+		}
+		s["properties"] = renamed
+	}
+	renameProperties(s["items"])
+	renameProperties(s["additionalProperties"])
+}
+
+// jsonName возвращает имя поля JSON по правилам protoc: is_active -> isActive
+func jsonName(name string) string {
+	var b strings.Builder
+	upper := false
+	for _, r := range name {
+		switch {
+		case r == '_':
+			upper = true
+		case upper && 'a' <= r && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+			upper = false
+		default:
+			b.WriteRune(r)
+			upper = false
+		}
+	}
+	return b.String()
+}
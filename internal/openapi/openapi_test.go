@@ -0,0 +1,40 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecHandler_CamelCase(t *testing.T) {
+	handler, err := SpecHandler(false)
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var doc struct {
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &doc))
+
+	user := doc.Components.Schemas["User"].Properties
+	assert.Contains(t, user, "isActive")
+	assert.Contains(t, user, "createdAt")
+	assert.NotContains(t, user, "is_active")
+}
+
+func TestJSONName(t *testing.T) {
+	assert.Equal(t, "isActive", jsonName("is_active"))
+	assert.Equal(t, "id", jsonName("id"))
+	assert.Equal(t, "@type", jsonName("@type"))
+}
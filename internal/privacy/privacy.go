@@ -0,0 +1,201 @@
+// Package privacy выполняет запросы пользователей о персональных данных (GDPR):
+// выгрузку всех данных пользователя в JSON или ZIP архиве. Удаление персональных
+// данных выполняет UserService.EraseUser (см. repository.UserRepository.Erase).
+//
+// Платформа не хранит серверных сессий: JWT токены проверяются без обращения к
+// базе данных. Поэтому сессиями в выгрузке считаются успешные входы из журнала
+// аудита, а связанными учетными записями - учетная запись с паролем и клиентские
+// приложения OIDC, для которых выдан и еще не обменян код авторизации. Данные других
+// людей из событий аудита (администратора, выполнившего действие, его IP и User-Agent)
+// в выгрузку не попадают.
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/models"
+)
+
+// Форматы выгрузки
+const (
+	// FormatJSON один JSON документ
+	FormatJSON = "json"
+	// FormatZIP ZIP архив с отдельным JSON файлом для каждого раздела
+	FormatZIP = "zip"
+)
+
+// RedactedValue значение email другого человека в событиях аудита выгрузки
+const RedactedValue = "***"
+
+// DispositionMetadataKey ключ метаданных ответа gRPC с именем файла выгрузки;
+// gateway передает его в заголовке Content-Disposition
+const DispositionMetadataKey = "content-disposition"
+
+// Типы связанных учетных записей
+const (
+	// IdentityPassword вход по email и паролю
+	IdentityPassword = "password"
+	// IdentityOIDCClient вход в клиентское приложение через OIDC провайдер платформы
+	IdentityOIDCClient = "oidc_client"
+)
+
+// Profile профиль пользователя
+type Profile struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	IsActive  bool      `json:"is_active"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Session успешный вход пользователя
+type Session struct {
+	StartedAt time.Time `json:"started_at"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// Identity учетная запись, через которую пользователь входит
+type Identity struct {
+	Type         string     `json:"type"`
+	Subject      string     `json:"subject"` // Email для password, client_id для oidc_client
+	Scope        string     `json:"scope,omitempty"`
+	AuthorizedAt *time.Time `json:"authorized_at,omitempty"`
+}
+
+// Export данные пользователя для выгрузки
+type Export struct {
+	ExportedAt  time.Time            `json:"exported_at"`
+	Profile     Profile              `json:"profile"`
+	Sessions    []Session            `json:"sessions"`
+	Identities  []Identity           `json:"identities"`
+	AuditEvents []*models.AuditEvent `json:"audit_events"`
+}
+
+// NewExport собирает выгрузку из пользователя, событий аудита, в которых он
+// инициатор или цель, и его кодов авторизации OIDC. События выгружаются копиями
+// без данных других людей (см. redact), исходные события не изменяются.
+func NewExport(user *models.User, events []*models.AuditEvent, codes []*models.OAuthAuthorizationCode, now time.Time) *Export {
+	export := &Export{
+		ExportedAt: now.UTC(),
+		Profile: Profile{
+			ID:        user.ID,
+			Name:      user.Name,
+			Email:     user.Email,
+			Role:      user.Role,
+			IsActive:  user.IsActive,
+			CreatedAt: user.CreatedAt.UTC(),
+			UpdatedAt: user.UpdatedAt.UTC(),
+		},
+		Sessions:    []Session{},
+		Identities:  []Identity{{Type: IdentityPassword, Subject: user.Email}},
+		AuditEvents: make([]*models.AuditEvent, 0, len(events)),
+	}
+
+	for _, event := range events {
+		export.AuditEvents = append(export.AuditEvents, redact(event, user.ID))
+		if event.Action == audit.ActionLogin && isUser(event.ActorID, user.ID) {
+			export.Sessions = append(export.Sessions, Session{
+				StartedAt: event.CreatedAt.UTC(),
+				IP:        event.IP,
+				UserAgent: event.UserAgent,
+				RequestID: event.RequestID,
+			})
+		}
+	}
+	for _, code := range codes {
+		authorizedAt := code.AuthTime.UTC()
+		export.Identities = append(export.Identities, Identity{
+			Type:         IdentityOIDCClient,
+			Subject:      code.ClientID,
+			Scope:        code.Scope,
+			AuthorizedAt: &authorizedAt,
+		})
+	}
+	return export
+}
+
+// redact возвращает копию события без данных других людей. Если инициатор не
+// пользователь, скрываются инициатор, IP и User-Agent: это данные администратора
+// или того, кто пытался войти под учетной записью пользователя. Если цель -
+// другой пользователь, скрываются цель и изменения ее полей.
+func redact(event *models.AuditEvent, userID uint) *models.AuditEvent {
+	redacted := *event
+	if !isUser(event.ActorID, userID) {
+		if event.ActorID != nil || event.ActorEmail != "" {
+			redacted.ActorEmail = RedactedValue
+		}
+		redacted.ActorID = nil
+		redacted.IP, redacted.UserAgent = "", ""
+	}
+	if event.TargetID != nil && *event.TargetID != userID {
+		redacted.TargetID, redacted.TargetEmail = nil, RedactedValue
+		redacted.Changes = ""
+	}
+	return &redacted
+}
+
+// isUser сообщает, что id - ID пользователя userID
+func isUser(id *uint, userID uint) bool {
+	return id != nil && *id == userID
+}
+
+// Encode кодирует выгрузку в формате format и возвращает данные, тип содержимого
+// и имя файла для сохранения
+func (e *Export) Encode(format string) ([]byte, string, string, error) {
+	name := fmt.Sprintf("user-%d-export-%s", e.Profile.ID, e.ExportedAt.Format("20060102T150405Z"))
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(e, "", "  ")
+		if err != nil {
+			return nil, "", "", fmt.Errorf("ошибка кодирования выгрузки: %w", err)
+		}
+		return data, "application/json", name + ".json", nil
+	case FormatZIP:
+		data, err := e.zip()
+		if err != nil {
+			return nil, "", "", fmt.Errorf("ошибка создания архива выгрузки: %w", err)
+		}
+		return data, "application/zip", name + ".zip", nil
+	default:
+		return nil, "", "", fmt.Errorf("неизвестный формат выгрузки %q", format)
+	}
+}
+
+// zip создает архив с файлом на каждый раздел выгрузки
+func (e *Export) zip() ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	files := []struct {
+		name    string
+		content interface{}
+	}{
+		{"profile.json", e.Profile},
+		{"sessions.json", e.Sessions},
+		{"identities.json", e.Identities},
+		{"audit_events.json", e.AuditEvents},
+	}
+	for _, file := range files {
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: file.name, Method: zip.Deflate, Modified: e.ExportedAt})
+		if err != nil {
+			return nil, err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.content); err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
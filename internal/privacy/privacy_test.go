@@ -0,0 +1,167 @@
+package privacy_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/privacy"
+)
+
+func id(v uint) *uint {
+	return &v
+}
+
+var (
+	exportedAt = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	loginAt    = time.Date(2026, 2, 1, 9, 0, 0, 0, time.UTC)
+	testUser   = &models.User{ID: 7, Name: "Ann", Email: "ann@example.com", Role: "user", IsActive: true}
+)
+
+func TestNewExport_Sessions(t *testing.T) {
+	tests := []struct {
+		name     string
+		event    *models.AuditEvent
+		expected []privacy.Session
+	}{
+		{
+			name:  "вход пользователя",
+			event: &models.AuditEvent{Action: audit.ActionLogin, CreatedAt: loginAt, ActorID: id(7), IP: "10.0.0.1", UserAgent: "Firefox", RequestID: "req-1"},
+			expected: []privacy.Session{
+				{StartedAt: loginAt, IP: "10.0.0.1", UserAgent: "Firefox", RequestID: "req-1"},
+			},
+		},
+		{
+			name:     "неудачная попытка входа",
+			event:    &models.AuditEvent{Action: audit.ActionLoginFailed, CreatedAt: loginAt, TargetID: id(7), IP: "10.0.0.2"},
+			expected: []privacy.Session{},
+		},
+		{
+			name:     "вход другого пользователя",
+			event:    &models.AuditEvent{Action: audit.ActionLogin, CreatedAt: loginAt, ActorID: id(8), TargetID: id(7)},
+			expected: []privacy.Session{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := privacy.NewExport(testUser, []*models.AuditEvent{tt.event}, nil, exportedAt)
+			assert.Equal(t, tt.expected, export.Sessions)
+		})
+	}
+}
+
+func TestNewExport_Identities(t *testing.T) {
+	authTime := time.Date(2026, 2, 2, 10, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	authTimeUTC := authTime.UTC()
+
+	tests := []struct {
+		name     string
+		codes    []*models.OAuthAuthorizationCode
+		expected []privacy.Identity
+	}{
+		{
+			name:     "только пароль",
+			expected: []privacy.Identity{{Type: privacy.IdentityPassword, Subject: "ann@example.com"}},
+		},
+		{
+			name:  "клиент OIDC",
+			codes: []*models.OAuthAuthorizationCode{{ClientID: "grafana", Scope: "openid email", UserID: 7, AuthTime: authTime}},
+			expected: []privacy.Identity{
+				{Type: privacy.IdentityPassword, Subject: "ann@example.com"},
+				{Type: privacy.IdentityOIDCClient, Subject: "grafana", Scope: "openid email", AuthorizedAt: &authTimeUTC},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export := privacy.NewExport(testUser, nil, tt.codes, exportedAt)
+			assert.Equal(t, tt.expected, export.Identities)
+		})
+	}
+}
+
+func TestNewExport_RedactsOtherPeople(t *testing.T) {
+	changes := `[{"field":"role","old":"user","new":"admin"}]`
+	tests := []struct {
+		name     string
+		event    models.AuditEvent
+		expected models.AuditEvent
+	}{
+		{
+			name: "действие пользователя над собой",
+			event: models.AuditEvent{Action: audit.ActionUserUpdate, ActorID: id(7), ActorEmail: "ann@example.com", TargetID: id(7),
+				TargetEmail: "ann@example.com", IP: "10.0.0.1", UserAgent: "Firefox", Changes: changes},
+			expected: models.AuditEvent{Action: audit.ActionUserUpdate, ActorID: id(7), ActorEmail: "ann@example.com", TargetID: id(7),
+				TargetEmail: "ann@example.com", IP: "10.0.0.1", UserAgent: "Firefox", Changes: changes},
+		},
+		{
+			name: "действие администратора над пользователем",
+			event: models.AuditEvent{Action: audit.ActionUserRoleChange, ActorID: id(1), ActorEmail: "admin@example.com", TargetID: id(7),
+				TargetEmail: "ann@example.com", IP: "10.0.0.9", UserAgent: "curl", RequestID: "req-2", Changes: changes},
+			expected: models.AuditEvent{Action: audit.ActionUserRoleChange, ActorEmail: privacy.RedactedValue, TargetID: id(7),
+				TargetEmail: "ann@example.com", RequestID: "req-2", Changes: changes},
+		},
+		{
+			name:     "неудачный вход без аутентификации",
+			event:    models.AuditEvent{Action: audit.ActionLoginFailed, TargetID: id(7), TargetEmail: "ann@example.com", IP: "203.0.113.5", UserAgent: "python-requests", Reason: "invalid_password"},
+			expected: models.AuditEvent{Action: audit.ActionLoginFailed, TargetID: id(7), TargetEmail: "ann@example.com", Reason: "invalid_password"},
+		},
+		{
+			name: "действие пользователя над другим пользователем",
+			event: models.AuditEvent{Action: audit.ActionUserUpdate, ActorID: id(7), ActorEmail: "ann@example.com", TargetID: id(8),
+				TargetEmail: "bob@example.com", IP: "10.0.0.1", Changes: `[{"field":"name","old":"Bob","new":"Robert"}]`},
+			expected: models.AuditEvent{Action: audit.ActionUserUpdate, ActorID: id(7), ActorEmail: "ann@example.com",
+				TargetEmail: privacy.RedactedValue, IP: "10.0.0.1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := tt.event
+			export := privacy.NewExport(testUser, []*models.AuditEvent{&tt.event}, nil, exportedAt)
+			require.Len(t, export.AuditEvents, 1)
+			assert.Equal(t, &tt.expected, export.AuditEvents[0])
+			assert.Equal(t, original, tt.event, "исходное событие не изменяется")
+		})
+	}
+}
+
+func TestExport_Encode(t *testing.T) {
+	events := []*models.AuditEvent{
+		{Action: audit.ActionLogin, CreatedAt: loginAt, ActorID: id(7), IP: "10.0.0.1"},
+		{Action: audit.ActionUserDeactivate, ActorID: id(1), ActorEmail: "admin@example.com", TargetID: id(7), IP: "10.0.0.9"},
+	}
+	export := privacy.NewExport(testUser, events, nil, exportedAt)
+
+	data, contentType, filename, err := export.Encode(privacy.FormatJSON)
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", contentType)
+	assert.Equal(t, "user-7-export-20260301T120000Z.json", filename)
+	assert.NotContains(t, string(data), "admin@example.com")
+	assert.NotContains(t, string(data), "10.0.0.9")
+	var decoded privacy.Export
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, export.Profile, decoded.Profile)
+	assert.Len(t, decoded.AuditEvents, 2)
+
+	data, contentType, filename, err = export.Encode(privacy.FormatZIP)
+	require.NoError(t, err)
+	assert.Equal(t, "application/zip", contentType)
+	assert.Equal(t, "user-7-export-20260301T120000Z.zip", filename)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	var names []string
+	for _, file := range archive.File {
+		names = append(names, file.Name)
+	}
+	assert.Equal(t, []string{"profile.json", "sessions.json", "identities.json", "audit_events.json"}, names)
+
+	_, _, _, err = export.Encode("xml")
+	assert.Error(t, err)
+}
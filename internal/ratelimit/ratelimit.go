@@ -0,0 +1,73 @@
+// Package ratelimit ограничивает частоту запросов к процессу.
+// Лимиты можно менять на лету при перезагрузке конфигурации.
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Limiter ограничивает общую частоту запросов по алгоритму token bucket
+type Limiter struct {
+	limiter *rate.Limiter
+}
+
+// New создает Limiter. Нулевое значение rps отключает ограничение.
+func New(rps, burst int) *Limiter {
+	return &Limiter{limiter: rate.NewLimiter(limitFor(rps), burst)}
+}
+
+// Update изменяет лимиты, не сбрасывая накопленные токены
+func (l *Limiter) Update(rps, burst int) {
+	l.limiter.SetLimit(limitFor(rps))
+	l.limiter.SetBurst(burst)
+}
+
+// limitFor переводит значение из конфигурации в лимит rate
+func limitFor(rps int) rate.Limit {
+	if rps <= 0 {
+		return rate.Inf
+	}
+	return rate.Limit(rps)
+}
+
+// allow проверяет, можно ли выполнить запрос, и возвращает время ожидания при отказе
+func (l *Limiter) allow() (bool, time.Duration) {
+	reservation := l.limiter.Reserve()
+	if !reservation.OK() {
+		return false, time.Second
+	}
+	if delay := reservation.Delay(); delay > 0 {
+		reservation.Cancel()
+		return false, delay
+	}
+	return true, 0
+}
+
+// UnaryInterceptor отклоняет gRPC запросы сверх лимита с кодом ResourceExhausted
+func (l *Limiter) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if ok, _ := l.allow(); !ok {
+		return nil, status.Error(codes.ResourceExhausted, "Превышен лимит запросов")
+	}
+	return handler(ctx, req)
+}
+
+// Middleware отклоняет HTTP запросы сверх лимита со статусом 429
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ok, delay := l.allow(); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			http.Error(w, "Too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
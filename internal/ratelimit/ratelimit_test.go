@@ -0,0 +1,33 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMiddleware_LimitAndUpdate(t *testing.T) {
+	limiter := New(1, 2)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		return rec
+	}
+
+	assert.Equal(t, http.StatusOK, serve().Code)
+	assert.Equal(t, http.StatusOK, serve().Code)
+
+	rec := serve()
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Отключение лимита применяется сразу
+	limiter.Update(0, 0)
+	assert.Equal(t, http.StatusOK, serve().Code)
+}
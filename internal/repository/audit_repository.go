@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// AuditFilter условия выборки событий аудита. Нулевые значения не ограничивают выборку.
+type AuditFilter struct {
+	Action   string
+	ActorID  uint
+	TargetID uint
+	Since    time.Time // Включительно
+	Until    time.Time // Не включительно
+	BeforeID uint      // Только события с ID меньше заданного, для постраничного чтения
+	Limit    int
+}
+
+// AuditRepository интерфейс журнала аудита. Изменение записей не предусмотрено.
+type AuditRepository interface {
+	// Create добавляет событие в конец цепочки, заполняя CreatedAt, PrevHash и Hash
+	Create(ctx context.Context, event *models.AuditEvent) error
+	List(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error)
+	// Scan возвращает до limit событий с ID больше afterID по возрастанию ID
+	Scan(ctx context.Context, afterID uint, limit int) ([]*models.AuditEvent, error)
+	// DeleteBefore удаляет начало цепочки до последней записи, созданной раньше
+	// before, и сохраняет якорь на удаленную запись, подписанный ключом key
+	DeleteBefore(ctx context.Context, before time.Time, key []byte) (int64, error)
+	// Anchor возвращает якорь начала цепочки или nil, если записи не удалялись
+	Anchor(ctx context.Context) (*models.AuditRetentionAnchor, error)
+
+	// Head возвращает последнюю запись цепочки или nil, если журнал пуст
+	Head(ctx context.Context) (*models.AuditChainHead, error)
+	CreateCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error
+	LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error)
+	ListCheckpoints(ctx context.Context) ([]*models.AuditCheckpoint, error)
+}
+
+// auditChainHeadID идентификатор единственной строки audit_chain_head
+const auditChainHeadID = 1
+
+// auditRetentionAnchorID идентификатор единственной строки audit_retention_anchor
+const auditRetentionAnchorID = 1
+
+// auditRepository реализация журнала аудита
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository создает новый экземпляр журнала аудита
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create добавляет событие в конец цепочки. Строка audit_chain_head блокируется
+// до конца транзакции, поэтому записи разных процессов не ссылаются на один PrevHash.
+func (r *auditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.AuditChainHead{ID: auditChainHeadID}).Error; err != nil {
+			return err
+		}
+		var head models.AuditChainHead
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, auditChainHeadID).Error; err != nil {
+			return err
+		}
+
+		// Время округляется до точности базы данных, чтобы хеш совпадал после чтения
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+		event.CreatedAt = event.CreatedAt.UTC().Truncate(time.Microsecond)
+		event.PrevHash = head.Hash
+		event.Hash = event.ComputeHash()
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&head).Updates(map[string]interface{}{"event_id": event.ID, "hash": event.Hash}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка при записи события аудита: %w", err)
+	}
+	return nil
+}
+
+// List возвращает события по фильтру, начиная с новых
+func (r *auditRepository) List(ctx context.Context, filter AuditFilter) ([]*models.AuditEvent, error) {
+	query := conn(ctx, r.db).Order("id DESC")
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if !filter.Since.IsZero() {
+		query = query.Where("created_at >= ?", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		query = query.Where("created_at < ?", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var events []*models.AuditEvent
+	if err := query.Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении событий аудита: %w", err)
+	}
+	return events, nil
+}
+
+// Scan возвращает события по возрастанию ID для проверки цепочки
+func (r *auditRepository) Scan(ctx context.Context, afterID uint, limit int) ([]*models.AuditEvent, error) {
+	var events []*models.AuditEvent
+	if err := conn(ctx, r.db).Where("id > ?", afterID).Order("id").Limit(limit).Find(&events).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при чтении событий аудита: %w", err)
+	}
+	return events, nil
+}
+
+// DeleteBefore удаляет события и контрольные точки до последней записи, созданной
+// раньше before, и возвращает количество удаленных событий. Удаляется всегда начало
+// цепочки целиком, а хеш последней удаленной записи сохраняется в подписанном якоре
+// в той же транзакции: по нему проверка цепочки отличает удаление по сроку хранения
+// от удаления записей в обход журнала.
+func (r *auditRepository) DeleteBefore(ctx context.Context, before time.Time, key []byte) (int64, error) {
+	var deleted int64
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.AuditRetentionAnchor{ID: auditRetentionAnchorID}).Error; err != nil {
+			return err
+		}
+		var anchor models.AuditRetentionAnchor
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&anchor, auditRetentionAnchorID).Error; err != nil {
+			return err
+		}
+
+		var last models.AuditEvent
+		err := tx.Where("created_at < ?", before).Order("id DESC").Limit(1).Find(&last).Error
+		if err != nil || last.ID == 0 {
+			return err
+		}
+
+		result := tx.Where("id <= ?", last.ID).Delete(&models.AuditEvent{})
+		if result.Error != nil {
+			return result.Error
+		}
+		deleted = result.RowsAffected
+		if err := tx.Where("event_id <= ?", last.ID).Delete(&models.AuditCheckpoint{}).Error; err != nil {
+			return err
+		}
+
+		anchor.EventID, anchor.Hash = last.ID, last.Hash
+		anchor.Signature = anchor.Sign(key)
+		return tx.Save(&anchor).Error
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ошибка при удалении устаревших событий аудита: %w", err)
+	}
+	return deleted, nil
+}
+
+// Anchor возвращает якорь начала цепочки
+func (r *auditRepository) Anchor(ctx context.Context) (*models.AuditRetentionAnchor, error) {
+	var anchor models.AuditRetentionAnchor
+	if err := conn(ctx, r.db).First(&anchor, auditRetentionAnchorID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении якоря цепочки аудита: %w", err)
+	}
+	if anchor.EventID == 0 {
+		return nil, nil
+	}
+	return &anchor, nil
+}
+
+// Head возвращает последнюю запись цепочки
+func (r *auditRepository) Head(ctx context.Context) (*models.AuditChainHead, error) {
+	var head models.AuditChainHead
+	if err := conn(ctx, r.db).First(&head, auditChainHeadID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении последней записи цепочки аудита: %w", err)
+	}
+	return &head, nil
+}
+
+// CreateCheckpoint сохраняет контрольную точку цепочки
+func (r *auditRepository) CreateCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	if err := conn(ctx, r.db).Create(checkpoint).Error; err != nil {
+		return fmt.Errorf("ошибка при сохранении контрольной точки аудита: %w", err)
+	}
+	return nil
+}
+
+// LastCheckpoint возвращает последнюю контрольную точку или nil, если их нет
+func (r *auditRepository) LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error) {
+	var checkpoint models.AuditCheckpoint
+	if err := conn(ctx, r.db).Order("event_id DESC").First(&checkpoint).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка при получении контрольной точки аудита: %w", err)
+	}
+	return &checkpoint, nil
+}
+
+// ListCheckpoints возвращает контрольные точки по возрастанию EventID
+func (r *auditRepository) ListCheckpoints(ctx context.Context) ([]*models.AuditCheckpoint, error) {
+	var checkpoints []*models.AuditCheckpoint
+	if err := conn(ctx, r.db).Order("event_id").Find(&checkpoints).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении контрольных точек аудита: %w", err)
+	}
+	return checkpoints, nil
+}
@@ -0,0 +1,111 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// newAuditChain создает журнал из n записей с интервалом в минуту после start
+// и контрольной точкой на последней записи
+func newAuditChain(t *testing.T, key []byte, start time.Time, n int) (*gorm.DB, repository.AuditRepository) {
+	t.Helper()
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	repo := repository.NewAuditRepository(db)
+	for i := 1; i <= n; i++ {
+		event := &models.AuditEvent{Action: audit.ActionLogin, TargetEmail: "user@example.com", CreatedAt: start.Add(time.Duration(i) * time.Minute)}
+		require.NoError(t, repo.Create(context.Background(), event))
+	}
+	_, err = audit.NewCheckpointer(repo, key, logger.Discard()).Checkpoint(context.Background())
+	require.NoError(t, err)
+	return db, repo
+}
+
+func TestAuditRepository_DeleteBeforeKeepsChainVerifiable(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("checkpoint-key")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	db, repo := newAuditChain(t, key, start, 5)
+	deleted, err := repo.DeleteBefore(ctx, start.Add(3*time.Minute), key)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+
+	anchor, err := repo.Anchor(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, anchor)
+	assert.Equal(t, uint(2), anchor.EventID)
+	assert.True(t, anchor.VerifySignature(key))
+
+	result, err := audit.Verify(ctx, repo, key)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+	assert.Equal(t, uint(3), result.FirstEventID)
+
+	// Повторное удаление без устаревших записей не меняет якорь
+	deleted, err = repo.DeleteBefore(ctx, start.Add(3*time.Minute), key)
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+
+	// Удаление всех записей по сроку хранения оставляет журнал проверяемым
+	_, err = repo.DeleteBefore(ctx, start.Add(time.Hour), key)
+	require.NoError(t, err)
+	var count int64
+	require.NoError(t, db.Model(&models.AuditCheckpoint{}).Count(&count).Error)
+	assert.Zero(t, count, "контрольные точки удаляются вместе с записями")
+	result, err = audit.Verify(ctx, repo, key)
+	require.NoError(t, err)
+	assert.True(t, result.Valid, result.Reason)
+}
+
+func TestAuditRepository_VerifyDetectsDeletedPrefix(t *testing.T) {
+	ctx := context.Background()
+	key := []byte("checkpoint-key")
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("без удаления по сроку хранения", func(t *testing.T) {
+		db, repo := newAuditChain(t, key, start, 5)
+		require.NoError(t, db.Where("id <= ?", 2).Delete(&models.AuditEvent{}).Error)
+
+		result, err := audit.Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(3), result.BrokenEventID)
+	})
+
+	t.Run("после удаления по сроку хранения", func(t *testing.T) {
+		db, repo := newAuditChain(t, key, start, 5)
+		_, err := repo.DeleteBefore(ctx, start.Add(2*time.Minute), key)
+		require.NoError(t, err)
+		require.NoError(t, db.Where("id <= ?", 3).Delete(&models.AuditEvent{}).Error)
+
+		result, err := audit.Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(4), result.BrokenEventID)
+	})
+
+	t.Run("вместе с контрольной точкой", func(t *testing.T) {
+		db, repo := newAuditChain(t, key, start, 5)
+		require.NoError(t, db.Where("id <= ?", 5).Delete(&models.AuditEvent{}).Error)
+
+		result, err := audit.Verify(ctx, repo, key)
+		require.NoError(t, err)
+		assert.False(t, result.Valid)
+		assert.Equal(t, uint(5), result.BrokenEventID)
+	})
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Ошибки репозиториев. Реализации оборачивают их с подробностями, вызывающая
+// сторона проверяет errors.Is.
+var (
+	// ErrNotFound запись не найдена или удалена
+	ErrNotFound = errors.New("запись не найдена")
+	// ErrDuplicateEmail email уже занят другим пользователем, в том числе удаленным
+	ErrDuplicateEmail = errors.New("email уже используется")
+	// ErrConflict запись изменена параллельным запросом
+	ErrConflict = errors.New("запись изменена параллельным запросом")
+	// ErrUnavailable база данных недоступна: нет соединения, сервер перезапускается
+	// или исчерпан лимит соединений. Запрос можно повторить позже.
+	ErrUnavailable = errors.New("база данных недоступна")
+)
+
+// uniqueViolation код SQLSTATE нарушения уникальности в Postgres
+const uniqueViolation = "23505"
+
+// isUniqueViolation проверяет, что ошибка вызвана нарушением уникального индекса
+func isUniqueViolation(db *gorm.DB, err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolation
+	}
+	// Остальные драйверы (SQLite) распознают нарушение сами
+	if translator, ok := db.Dialector.(gorm.ErrorTranslator); ok {
+		err = translator.Translate(err)
+	}
+	return errors.Is(err, gorm.ErrDuplicatedKey)
+}
+
+// isSerializationFailure проверяет, что транзакция отклонена из-за параллельных
+// транзакций и ее можно повторить: 40001 - ошибка сериализации, 40P01 - взаимная блокировка
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01")
+}
+
+// isUnavailable проверяет, что ошибка вызвана недоступностью базы данных, а не запросом
+func isUnavailable(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return true
+	}
+	var connectErr *pgconn.ConnectError
+	if errors.As(err, &connectErr) {
+		return true
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// 08 - ошибки соединения, 57P01-57P03 - остановка сервера, 53300 - лимит соединений
+		return strings.HasPrefix(pgErr.Code, "08") ||
+			pgErr.Code == "57P01" || pgErr.Code == "57P02" || pgErr.Code == "57P03" ||
+			pgErr.Code == "53300"
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// dbError добавляет ErrUnavailable к ошибкам соединения с базой данных
+func dbError(err error) error {
+	if isUnavailable(err) {
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDBError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"нет соединения", &pgconn.ConnectError{}, true},
+		{"разорванное соединение", driver.ErrBadConn, true},
+		{"остановка сервера", &pgconn.PgError{Code: "57P01"}, true},
+		{"ошибка соединения", &pgconn.PgError{Code: "08006"}, true},
+		{"синтаксическая ошибка", &pgconn.PgError{Code: "42601"}, false},
+		{"запись не найдена", gorm.ErrRecordNotFound, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := dbError(tt.err)
+			assert.Equal(t, tt.unavailable, errors.Is(err, ErrUnavailable))
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+}
+
+func TestIsUniqueViolation(t *testing.T) {
+	db := &gorm.DB{Config: &gorm.Config{}}
+	assert.True(t, isUniqueViolation(db, &pgconn.PgError{Code: "23505"}))
+	assert.False(t, isUniqueViolation(db, &pgconn.PgError{Code: "23503"}))
+	assert.True(t, isUniqueViolation(db, gorm.ErrDuplicatedKey))
+}
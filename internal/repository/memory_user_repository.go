@@ -0,0 +1,332 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// memoryUserRepository хранит пользователей в памяти процесса. Повторяет поведение
+// реализации на GORM: email нормализуется и уникален среди неудаленных пользователей,
+// удаление мягкое, значения по умолчанию как в схеме таблицы. Безопасен для параллельного
+// использования; вызывающая сторона получает копии, а не хранимые объекты.
+// Транзакции TxManager на него не распространяются: изменения видны сразу и не
+// откатываются, поэтому с ним сервис работает без TxManager.
+type memoryUserRepository struct {
+	mu             sync.RWMutex
+	users          map[uint]*models.User
+	byEmail        map[string]uint // Только неудаленные пользователи
+	nextID         uint
+	deleteUserData UserDataDeleter
+}
+
+// NewMemoryUserRepository создает пустой репозиторий пользователей в памяти.
+// deleteUserData удаляет данные пользователей при окончательном удалении и удалении
+// персональных данных, как реализация на GORM; nil - других данных нет.
+func NewMemoryUserRepository(deleteUserData UserDataDeleter) UserRepository {
+	return &memoryUserRepository{
+		users:          make(map[uint]*models.User),
+		byEmail:        make(map[string]uint),
+		nextID:         1,
+		deleteUserData: deleteUserData,
+	}
+}
+
+// Create создает нового пользователя
+func (r *memoryUserRepository) Create(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user.Email = models.NormalizeEmail(user.Email)
+	user.Version = 1
+	if _, exists := r.byEmail[user.Email]; exists {
+		return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+	}
+	if user.ID != 0 {
+		if _, exists := r.users[user.ID]; exists {
+			return fmt.Errorf("ошибка при создании пользователя: ID %d уже используется", user.ID)
+		}
+	} else {
+		user.ID = r.nextID
+	}
+	if user.ID >= r.nextID {
+		r.nextID = user.ID + 1
+	}
+
+	now := time.Now()
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = now
+	}
+	if user.UpdatedAt.IsZero() {
+		user.UpdatedAt = now
+	}
+	if user.Role == "" {
+		user.Role = "user"
+	}
+
+	r.store(user)
+	return nil
+}
+
+// GetByID получает пользователя по ID
+func (r *memoryUserRepository) GetByID(_ context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return nil, fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, id)
+	}
+	return copyUser(user), nil
+}
+
+// GetByEmail получает пользователя по email
+func (r *memoryUserRepository) GetByEmail(_ context.Context, email string) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.byEmail[models.NormalizeEmail(email)]
+	if !ok {
+		return nil, fmt.Errorf("%w: пользователь с email %s", ErrNotFound, email)
+	}
+	return copyUser(r.users[id]), nil
+}
+
+// List получает список пользователей с пагинацией в порядке ID
+func (r *memoryUserRepository) List(_ context.Context, limit, offset int) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0, len(r.users))
+	for _, user := range r.users {
+		if !user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if offset > 0 {
+		if offset > len(users) {
+			offset = len(users)
+		}
+		users = users[offset:]
+	}
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+
+	result := make([]*models.User, len(users))
+	for i, user := range users {
+		result[i] = copyUser(user)
+	}
+	return result, nil
+}
+
+// Update обновляет пользователя, если его версия не изменилась
+func (r *memoryUserRepository) Update(_ context.Context, user *models.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	current, ok := r.users[user.ID]
+	if !ok || current.DeletedAt.Valid {
+		return fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, user.ID)
+	}
+	if current.Version != user.Version {
+		return fmt.Errorf("%w: пользователь с ID %d, версия %d", ErrConflict, user.ID, user.Version)
+	}
+	user.Email = models.NormalizeEmail(user.Email)
+	if id, exists := r.byEmail[user.Email]; exists && id != user.ID {
+		return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+	}
+
+	delete(r.byEmail, current.Email)
+	user.CreatedAt = current.CreatedAt
+	user.DeletedAt = current.DeletedAt
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.store(user)
+	return nil
+}
+
+// Delete удаляет пользователя (soft delete)
+func (r *memoryUserRepository) Delete(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if user, ok := r.users[id]; ok && !user.DeletedAt.Valid {
+		user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+		delete(r.byEmail, user.Email)
+	}
+	return nil
+}
+
+// Count возвращает общее количество пользователей
+func (r *memoryUserRepository) Count(_ context.Context) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, user := range r.users {
+		if !user.DeletedAt.Valid {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// ListDeleted получает список удаленных пользователей с пагинацией в порядке ID
+func (r *memoryUserRepository) ListDeleted(_ context.Context, limit, offset int) ([]*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]*models.User, 0)
+	for _, user := range r.users {
+		if user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	if offset > 0 {
+		if offset > len(users) {
+			offset = len(users)
+		}
+		users = users[offset:]
+	}
+	if limit > 0 && limit < len(users) {
+		users = users[:limit]
+	}
+
+	result := make([]*models.User, len(users))
+	for i, user := range users {
+		result[i] = copyUser(user)
+	}
+	return result, nil
+}
+
+// GetDeleted получает удаленного пользователя по ID
+func (r *memoryUserRepository) GetDeleted(_ context.Context, id uint) (*models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return nil, fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+	}
+	return copyUser(user), nil
+}
+
+// Restore снимает с пользователя отметку удаления, если его email свободен
+func (r *memoryUserRepository) Restore(_ context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+	}
+	if _, exists := r.byEmail[user.Email]; exists {
+		return fmt.Errorf("%w: email удаленного пользователя с ID %d занят", ErrDuplicateEmail, id)
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version++
+	user.UpdatedAt = time.Now()
+	r.byEmail[user.Email] = user.ID
+	return nil
+}
+
+// Purge окончательно удаляет удаленного пользователя и его данные
+func (r *memoryUserRepository) Purge(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+	}
+	if err := r.deleteData(ctx, []uint{id}); err != nil {
+		return fmt.Errorf("ошибка при окончательном удалении пользователя: %w", err)
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// PurgeDeletedBefore окончательно удаляет пользователей, удаленных раньше before,
+// и их данные
+func (r *memoryUserRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ids []uint
+	for id, user := range r.users {
+		if user.DeletedAt.Valid && user.DeletedAt.Time.Before(before) {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	// Пользователь удаляется только после своих данных, чтобы при ошибке удаление можно было повторить
+	if err := r.deleteData(ctx, ids); err != nil {
+		return 0, fmt.Errorf("ошибка при окончательном удалении пользователей: %w", err)
+	}
+	for _, id := range ids {
+		delete(r.users, id)
+	}
+	return int64(len(ids)), nil
+}
+
+// Erase заменяет персональные данные пользователя заглушками и удаляет его данные
+func (r *memoryUserRepository) Erase(ctx context.Context, id uint, now time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.Erased() {
+		return fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, id)
+	}
+	if err := r.deleteData(ctx, []uint{id}); err != nil {
+		return fmt.Errorf("ошибка при удалении персональных данных пользователя: %w", err)
+	}
+
+	if !user.DeletedAt.Valid {
+		delete(r.byEmail, user.Email)
+	}
+	user.Name = models.ErasedName
+	user.Email = models.ErasedEmail(id)
+	user.PasswordHash = ""
+	user.IsActive = false
+	user.Version++
+	user.UpdatedAt = now
+	user.ErasedAt = &now
+	if !user.DeletedAt.Valid {
+		r.byEmail[user.Email] = user.ID
+	}
+	return nil
+}
+
+// deleteData удаляет данные пользователей ids, если они хранятся отдельно
+func (r *memoryUserRepository) deleteData(ctx context.Context, ids []uint) error {
+	if r.deleteUserData == nil {
+		return nil
+	}
+	return r.deleteUserData(ctx, ids)
+}
+
+// store сохраняет копию пользователя; вызывается под блокировкой на запись
+func (r *memoryUserRepository) store(user *models.User) {
+	r.users[user.ID] = copyUser(user)
+	r.byEmail[user.Email] = user.ID
+}
+
+func copyUser(user *models.User) *models.User {
+	copied := *user
+	return &copied
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// ErrAuthorizationCodeNotFound возвращается, если код авторизации не найден, истек или уже использован
+var ErrAuthorizationCodeNotFound = errors.New("код авторизации не найден")
+
+// OAuthRepository интерфейс для хранения данных OIDC провайдера
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	ListClients(ctx context.Context) ([]*models.OAuthClient, error)
+	DeleteClient(ctx context.Context, clientID string) error
+
+	SaveAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+	ListAuthorizationCodes(ctx context.Context, userID uint) ([]*models.OAuthAuthorizationCode, error)
+	DeleteExpiredAuthorizationCodes(ctx context.Context, now time.Time) error
+
+	// CreateSigningKeyIfNoneActiveAfter сохраняет ключ, созданный newKey, если нет ключа,
+	// подписывающего после момента after, и возвращает true, если ключ создан. Проверка
+	// и запись выполняются под блокировкой, поэтому реплики, одновременно начавшие
+	// ротацию, создают один ключ.
+	CreateSigningKeyIfNoneActiveAfter(ctx context.Context, after time.Time, newKey func() (*models.OIDCSigningKey, error)) (bool, error)
+	ListSigningKeys(ctx context.Context, now time.Time) ([]*models.OIDCSigningKey, error)
+	DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error
+}
+
+// oauthRepository реализация репозитория OIDC провайдера
+type oauthRepository struct {
+	db *gorm.DB
+}
+
+// NewOAuthRepository создает новый экземпляр репозитория OIDC провайдера
+func NewOAuthRepository(db *gorm.DB) OAuthRepository {
+	return &oauthRepository{db: db}
+}
+
+// CreateClient регистрирует новое клиентское приложение
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	if err := conn(ctx, r.db).Create(client).Error; err != nil {
+		return fmt.Errorf("ошибка при создании OAuth клиента: %w", err)
+	}
+	return nil
+}
+
+// GetClient получает клиентское приложение по client_id
+func (r *oauthRepository) GetClient(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := conn(ctx, r.db).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("OAuth клиент %s не найден", clientID)
+		}
+		return nil, fmt.Errorf("ошибка при получении OAuth клиента: %w", err)
+	}
+	return &client, nil
+}
+
+// ListClients возвращает все зарегистрированные клиентские приложения
+func (r *oauthRepository) ListClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+	if err := conn(ctx, r.db).Order("id").Find(&clients).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении списка OAuth клиентов: %w", err)
+	}
+	return clients, nil
+}
+
+// DeleteClient удаляет клиентское приложение (soft delete)
+func (r *oauthRepository) DeleteClient(ctx context.Context, clientID string) error {
+	if err := conn(ctx, r.db).Where("client_id = ?", clientID).Delete(&models.OAuthClient{}).Error; err != nil {
+		return fmt.Errorf("ошибка при удалении OAuth клиента: %w", err)
+	}
+	return nil
+}
+
+// SaveAuthorizationCode сохраняет выданный код авторизации
+func (r *oauthRepository) SaveAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	if err := conn(ctx, r.db).Create(code).Error; err != nil {
+		return fmt.Errorf("ошибка при сохранении кода авторизации: %w", err)
+	}
+	return nil
+}
+
+// ConsumeAuthorizationCode атомарно извлекает и удаляет код авторизации,
+// гарантируя, что код можно обменять на токены только один раз
+func (r *oauthRepository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+			return err
+		}
+		res := tx.Where("code_hash = ?", codeHash).Delete(&models.OAuthAuthorizationCode{})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAuthorizationCodeNotFound
+		}
+		return nil, fmt.Errorf("ошибка при получении кода авторизации: %w", err)
+	}
+	return &code, nil
+}
+
+// ListAuthorizationCodes возвращает необмененные коды авторизации пользователя
+func (r *oauthRepository) ListAuthorizationCodes(ctx context.Context, userID uint) ([]*models.OAuthAuthorizationCode, error) {
+	var codes []*models.OAuthAuthorizationCode
+	if err := conn(ctx, r.db).Where("user_id = ?", userID).Order("auth_time").Find(&codes).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении кодов авторизации: %w", err)
+	}
+	return codes, nil
+}
+
+// DeleteExpiredAuthorizationCodes удаляет просроченные коды авторизации
+func (r *oauthRepository) DeleteExpiredAuthorizationCodes(ctx context.Context, now time.Time) error {
+	if err := conn(ctx, r.db).Where("expires_at < ?", now).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+		return fmt.Errorf("ошибка при удалении просроченных кодов авторизации: %w", err)
+	}
+	return nil
+}
+
+// oidcKeyRotationLockID идентификатор единственной строки oidc_key_rotation_lock
+const oidcKeyRotationLockID = 1
+
+// CreateSigningKeyIfNoneActiveAfter создает ключ подписи под блокировкой строки
+// oidc_key_rotation_lock. SQLite не поддерживает блокировку строк, но допускает
+// одного писателя, поэтому транзакции ротации и там выполняются по очереди.
+func (r *oauthRepository) CreateSigningKeyIfNoneActiveAfter(ctx context.Context, after time.Time, newKey func() (*models.OIDCSigningKey, error)) (bool, error) {
+	created := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.OIDCKeyRotationLock{ID: oidcKeyRotationLockID}).Error; err != nil {
+			return err
+		}
+		var lock models.OIDCKeyRotationLock
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&lock, oidcKeyRotationLockID).Error; err != nil {
+			return err
+		}
+
+		var active int64
+		if err := tx.Model(&models.OIDCSigningKey{}).Where("active_until > ?", after).Count(&active).Error; err != nil {
+			return err
+		}
+		if active > 0 {
+			return nil
+		}
+
+		key, err := newKey()
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("ошибка при сохранении ключа подписи: %w", err)
+	}
+	return created, nil
+}
+
+// ListSigningKeys возвращает неистекшие ключи подписи, начиная с самого нового
+func (r *oauthRepository) ListSigningKeys(ctx context.Context, now time.Time) ([]*models.OIDCSigningKey, error) {
+	var keys []*models.OIDCSigningKey
+	if err := conn(ctx, r.db).Where("expires_at > ?", now).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении ключей подписи: %w", err)
+	}
+	return keys, nil
+}
+
+// DeleteExpiredSigningKeys удаляет ключи, которые больше не публикуются в JWKS
+func (r *oauthRepository) DeleteExpiredSigningKeys(ctx context.Context, now time.Time) error {
+	if err := conn(ctx, r.db).Where("expires_at <= ?", now).Delete(&models.OIDCSigningKey{}).Error; err != nil {
+		return fmt.Errorf("ошибка при удалении просроченных ключей подписи: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// OutboxRepository интерфейс очереди событий для публикации (transactional outbox).
+// Create вызывается в транзакции изменения пользователя, остальные методы - relay
+// вне транзакций.
+type OutboxRepository interface {
+	Create(ctx context.Context, event *models.OutboxEvent) error
+	// ClaimPending выбирает до limit неопубликованных событий, срок попытки которых
+	// наступил, по возрастанию ID, и откладывает их на lease, чтобы другие экземпляры
+	// сервера не опубликовали их одновременно. Событие не выбирается, пока не
+	// опубликованы более ранние события того же пользователя, чтобы получатели
+	// видели их по порядку; события, исчерпавшие попытки, порядок не задерживают.
+	ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id uint, at time.Time) error
+	// MarkFailed увеличивает счетчик попыток и откладывает следующую попытку до next
+	MarkFailed(ctx context.Context, id uint, lastError string, next time.Time) error
+	// MarkDeadLettered увеличивает счетчик попыток и прекращает публикацию события
+	MarkDeadLettered(ctx context.Context, id uint, lastError string, at time.Time) error
+	// CountPending возвращает количество событий, ожидающих публикации
+	CountPending(ctx context.Context) (int64, error)
+	// DeletePublishedBefore удаляет события, опубликованные раньше before
+	DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// maxOutboxErrorLength ограничение длины текста ошибки публикации, как в модели
+const maxOutboxErrorLength = 1024
+
+// outboxRepository реализация очереди событий на GORM
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+// NewOutboxRepository создает новый экземпляр очереди событий
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+// Create сохраняет событие; срок первой попытки - время создания
+func (r *outboxRepository) Create(ctx context.Context, event *models.OutboxEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	if event.NextAttemptAt.IsZero() {
+		event.NextAttemptAt = event.CreatedAt
+	}
+	if err := conn(ctx, r.db).Create(event).Error; err != nil {
+		return dbError(fmt.Errorf("ошибка при сохранении события для публикации: %w", err))
+	}
+	return nil
+}
+
+// ClaimPending выбирает события для публикации. Публикация выполняется после
+// фиксации транзакции, чтобы медленный брокер не держал блокировку строк.
+// SQLite не поддерживает блокировку строк, GORM опускает ее для этого диалекта.
+func (r *outboxRepository) ClaimPending(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.OutboxEvent, error) {
+	var events []*models.OutboxEvent
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND failed_at IS NULL AND next_attempt_at <= ?", now).
+			Where("NOT EXISTS (SELECT 1 FROM outbox_events earlier WHERE earlier.user_id = outbox_events.user_id AND earlier.published_at IS NULL AND earlier.failed_at IS NULL AND earlier.id < outbox_events.id)").
+			Order("id").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(events))
+		for i, event := range events {
+			ids[i] = event.ID
+		}
+		return tx.Model(&models.OutboxEvent{}).Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, dbError(fmt.Errorf("ошибка при получении событий для публикации: %w", err))
+	}
+	return events, nil
+}
+
+// MarkPublished отмечает событие опубликованным
+func (r *outboxRepository) MarkPublished(ctx context.Context, id uint, at time.Time) error {
+	err := conn(ctx, r.db).Model(&models.OutboxEvent{}).Where("id = ?", id).
+		Update("published_at", at).Error
+	if err != nil {
+		return dbError(fmt.Errorf("ошибка при отметке публикации события %d: %w", id, err))
+	}
+	return nil
+}
+
+// MarkFailed сохраняет ошибку публикации и срок следующей попытки
+func (r *outboxRepository) MarkFailed(ctx context.Context, id uint, lastError string, next time.Time) error {
+	return r.markFailed(ctx, id, lastError, map[string]interface{}{"next_attempt_at": next})
+}
+
+// MarkDeadLettered сохраняет последнюю ошибку публикации и время отказа от публикации
+func (r *outboxRepository) MarkDeadLettered(ctx context.Context, id uint, lastError string, at time.Time) error {
+	return r.markFailed(ctx, id, lastError, map[string]interface{}{"failed_at": at})
+}
+
+// markFailed увеличивает счетчик попыток и сохраняет ошибку вместе с полями updates
+func (r *outboxRepository) markFailed(ctx context.Context, id uint, lastError string, updates map[string]interface{}) error {
+	if len(lastError) > maxOutboxErrorLength {
+		lastError = strings.ToValidUTF8(lastError[:maxOutboxErrorLength], "")
+	}
+	updates["attempts"] = gorm.Expr("attempts + 1")
+	updates["last_error"] = lastError
+	err := conn(ctx, r.db).Model(&models.OutboxEvent{}).Where("id = ?", id).Updates(updates).Error
+	if err != nil {
+		return dbError(fmt.Errorf("ошибка при сохранении ошибки публикации события %d: %w", id, err))
+	}
+	return nil
+}
+
+// CountPending возвращает размер очереди
+func (r *outboxRepository) CountPending(ctx context.Context) (int64, error) {
+	var count int64
+	if err := conn(ctx, r.db).Model(&models.OutboxEvent{}).Where("published_at IS NULL AND failed_at IS NULL").Count(&count).Error; err != nil {
+		return 0, dbError(fmt.Errorf("ошибка при подсчете событий для публикации: %w", err))
+	}
+	return count, nil
+}
+
+// DeletePublishedBefore удаляет опубликованные события старше before
+func (r *outboxRepository) DeletePublishedBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("published_at IS NOT NULL AND published_at < ?", before).Delete(&models.OutboxEvent{})
+	if result.Error != nil {
+		return 0, dbError(fmt.Errorf("ошибка при удалении опубликованных событий: %w", result.Error))
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,540 @@
+// Package repositorytest общие тесты поведения репозиториев. Каждая реализация
+// repository.UserRepository (Postgres, SQLite, память) должна проходить TestUserRepository.
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"gorm.io/gorm"
+
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// NewUserRepository создает пустой репозиторий для одного теста и базу данных
+// с таблицами данных пользователей (коды авторизации, outbox, webhook), которые
+// репозиторий удаляет вместе с пользователем
+type NewUserRepository func(t *testing.T) (repository.UserRepository, *gorm.DB)
+
+// TestUserRepository проверяет поведение репозитория пользователей
+func TestUserRepository(t *testing.T, newRepo NewUserRepository) {
+	repo := func(t *testing.T) repository.UserRepository {
+		users, _ := newRepo(t)
+		return users
+	}
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, repo(t)) })
+	t.Run("UniqueEmail", func(t *testing.T) { testUniqueEmail(t, repo(t)) })
+	t.Run("NotFound", func(t *testing.T) { testNotFound(t, repo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, repo(t)) })
+	t.Run("OptimisticLocking", func(t *testing.T) { testOptimisticLocking(t, repo(t)) })
+	t.Run("SoftDelete", func(t *testing.T) { testSoftDelete(t, repo(t)) })
+	t.Run("ListPagination", func(t *testing.T) { testListPagination(t, repo(t)) })
+	t.Run("ConcurrentCreate", func(t *testing.T) { testConcurrentCreate(t, repo(t)) })
+	t.Run("CaseInsensitiveEmail", func(t *testing.T) { testCaseInsensitiveEmail(t, repo(t)) })
+	t.Run("ConcurrentSameEmail", func(t *testing.T) { testConcurrentSameEmail(t, repo(t)) })
+	t.Run("Restore", func(t *testing.T) { testRestore(t, repo(t)) })
+	t.Run("Purge", func(t *testing.T) { testPurge(t, repo(t)) })
+	t.Run("Erase", func(t *testing.T) { testErase(t, repo(t)) })
+	t.Run("PurgeDeletesUserData", func(t *testing.T) { testPurgeDeletesUserData(t, newRepo) })
+	t.Run("EraseDeletesUserData", func(t *testing.T) { testEraseDeletesUserData(t, newRepo) })
+}
+
+// newUser создает пользователя с уникальным email
+func newUser(n int) *models.User {
+	return &models.User{
+		Name:         fmt.Sprintf("User %d", n),
+		Email:        fmt.Sprintf("user%d@example.com", n),
+		PasswordHash: "hash",
+		Role:         "user",
+		IsActive:     true,
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	user.Role = ""
+	require.NoError(t, repo.Create(ctx, user))
+	assert.NotZero(t, user.ID)
+	assert.False(t, user.CreatedAt.IsZero())
+
+	byID, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, byID.Email)
+	assert.Equal(t, "user", byID.Role, "роль по умолчанию")
+	assert.True(t, byID.IsActive)
+
+	byEmail, err := repo.GetByEmail(ctx, user.Email)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, byEmail.ID)
+	assert.Equal(t, user.PasswordHash, byEmail.PasswordHash)
+
+	// Возвращается копия: изменение результата не меняет хранимые данные
+	byID.Name = "Changed"
+	again, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "User 1", again.Name)
+}
+
+func testUniqueEmail(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	require.NoError(t, repo.Create(ctx, newUser(1)))
+
+	duplicate := newUser(2)
+	duplicate.Email = "user1@example.com"
+	assert.ErrorIs(t, repo.Create(ctx, duplicate), repository.ErrDuplicateEmail)
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Email нельзя занять и изменением другого пользователя
+	other := newUser(3)
+	require.NoError(t, repo.Create(ctx, other))
+	other.Email = "user1@example.com"
+	assert.ErrorIs(t, repo.Update(ctx, other), repository.ErrDuplicateEmail)
+}
+
+func testNotFound(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	_, err := repo.GetByID(ctx, 999)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByEmail(ctx, "missing@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	missing := newUser(1)
+	missing.ID = 999
+	assert.ErrorIs(t, repo.Update(ctx, missing), repository.ErrNotFound)
+}
+
+func testUpdate(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	require.NoError(t, repo.Create(ctx, user))
+
+	user.Name = "Renamed"
+	user.Role = "admin"
+	user.Email = "renamed@example.com"
+	require.NoError(t, repo.Update(ctx, user))
+
+	updated, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.Name)
+	assert.Equal(t, "admin", updated.Role)
+
+	// Старый email освобождается, новый находится
+	_, err = repo.GetByEmail(ctx, "user1@example.com")
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByEmail(ctx, "renamed@example.com")
+	assert.NoError(t, err)
+}
+
+func testOptimisticLocking(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	require.NoError(t, repo.Create(ctx, user))
+	assert.Equal(t, uint(1), user.Version)
+
+	// Два администратора открыли одного пользователя
+	first, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	second, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+
+	first.Name = "First"
+	require.NoError(t, repo.Update(ctx, first))
+	assert.Equal(t, uint(2), first.Version)
+
+	// Второе изменение сделано по устаревшей версии и не перезаписывает первое
+	second.Role = "admin"
+	assert.ErrorIs(t, repo.Update(ctx, second), repository.ErrConflict)
+
+	stored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", stored.Name)
+	assert.Equal(t, "user", stored.Role)
+	assert.Equal(t, uint(2), stored.Version)
+
+	// После перечитывания изменение проходит
+	stored.Role = "admin"
+	require.NoError(t, repo.Update(ctx, stored))
+	assert.Equal(t, uint(3), stored.Version)
+}
+
+func testSoftDelete(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.Create(ctx, newUser(2)))
+
+	require.NoError(t, repo.Delete(ctx, user.ID))
+
+	_, err := repo.GetByID(ctx, user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByEmail(ctx, user.Email)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	// Удаленного пользователя нельзя изменить
+	assert.ErrorIs(t, repo.Update(ctx, user), repository.ErrNotFound)
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	users, err := repo.List(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "user2@example.com", users[0].Email)
+
+	// Удаленный пользователь остается в таблице, но email освобождается
+	again := newUser(1)
+	require.NoError(t, repo.Create(ctx, again))
+	assert.NotEqual(t, user.ID, again.ID)
+}
+
+func testRestore(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	require.NoError(t, repo.Create(ctx, user))
+	assert.ErrorIs(t, repo.Restore(ctx, user.ID), repository.ErrNotFound, "пользователь не удален")
+
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	deleted, err := repo.ListDeleted(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, deleted, 1)
+	assert.True(t, deleted[0].DeletedAt.Valid)
+	stored, err := repo.GetDeleted(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, stored.Email)
+
+	require.NoError(t, repo.Restore(ctx, user.ID))
+	restored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), restored.Version, "восстановление меняет версию")
+	_, err = repo.GetDeleted(ctx, user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+
+	// Email удаленного пользователя занял другой пользователь
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	require.NoError(t, repo.Create(ctx, newUser(1)))
+	assert.ErrorIs(t, repo.Restore(ctx, user.ID), repository.ErrDuplicateEmail)
+	_, err = repo.GetDeleted(ctx, user.ID)
+	assert.NoError(t, err, "пользователь остается удаленным")
+}
+
+func testPurge(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	users := make([]*models.User, 3)
+	for i := range users {
+		users[i] = newUser(i + 1)
+		require.NoError(t, repo.Create(ctx, users[i]))
+	}
+	assert.ErrorIs(t, repo.Purge(ctx, users[0].ID), repository.ErrNotFound, "неудаленного пользователя нельзя удалить окончательно")
+
+	require.NoError(t, repo.Delete(ctx, users[0].ID))
+	require.NoError(t, repo.Purge(ctx, users[0].ID))
+	_, err := repo.GetDeleted(ctx, users[0].ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	assert.ErrorIs(t, repo.Restore(ctx, users[0].ID), repository.ErrNotFound)
+
+	require.NoError(t, repo.Delete(ctx, users[1].ID))
+	purged, err := repo.PurgeDeletedBefore(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged, "срок хранения не истек")
+	purged, err = repo.PurgeDeletedBefore(ctx, time.Now().Add(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	deleted, err := repo.ListDeleted(ctx, 0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, deleted)
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "неудаленные пользователи остаются")
+}
+
+func testErase(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	require.NoError(t, repo.Create(ctx, user))
+	now := time.Now()
+
+	require.NoError(t, repo.Erase(ctx, user.ID, now))
+	erased, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err, "запись пользователя остается")
+	assert.Equal(t, models.ErasedName, erased.Name)
+	assert.Equal(t, models.ErasedEmail(user.ID), erased.Email)
+	assert.Empty(t, erased.PasswordHash)
+	assert.False(t, erased.IsActive)
+	assert.True(t, erased.Erased())
+	assert.Equal(t, uint(2), erased.Version)
+	assert.ErrorIs(t, repo.Erase(ctx, user.ID, now), repository.ErrNotFound, "данные уже удалены")
+
+	// Прежний email свободен
+	_, err = repo.GetByEmail(ctx, user.Email)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+	require.NoError(t, repo.Create(ctx, newUser(1)))
+
+	// Данные удаленного пользователя тоже можно удалить
+	deleted := newUser(2)
+	require.NoError(t, repo.Create(ctx, deleted))
+	require.NoError(t, repo.Delete(ctx, deleted.ID))
+	require.NoError(t, repo.Erase(ctx, deleted.ID, now))
+	stored, err := repo.GetDeleted(ctx, deleted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasedEmail(deleted.ID), stored.Email)
+
+	assert.ErrorIs(t, repo.Erase(ctx, 999, now), repository.ErrNotFound)
+}
+
+// userData данные пользователей в db: коды авторизации, события outbox и доставки webhook
+type userData struct {
+	db *gorm.DB
+}
+
+// userRow владелец и тип события outbox или доставки webhook
+type userRow struct {
+	UserID uint
+	Type   string
+}
+
+// create создает для пользователя код авторизации, события о создании и удалении
+// и доставки webhook: отправленную, ожидающую и ожидающую без персональных данных
+func (d userData) create(t *testing.T, subscriptionID uint, user *models.User) {
+	ctx := context.Background()
+	require.NoError(t, repository.NewOAuthRepository(d.db).SaveAuthorizationCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:  user.Email,
+		ClientID:  "client",
+		UserID:    user.ID,
+		AuthTime:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	outbox := events.NewOutbox(repository.NewOutboxRepository(d.db))
+	require.NoError(t, outbox.UserCreated(ctx, user))
+	require.NoError(t, outbox.UserDeleted(ctx, user.ID))
+	webhooks := repository.NewWebhookRepository(d.db)
+	for _, delivery := range []*models.WebhookDelivery{
+		{EventID: user.Email + "-created", EventType: events.TypeUserCreated, Payload: []byte(user.Email), Status: models.WebhookDeliverySucceeded},
+		{EventID: user.Email + "-updated", EventType: events.TypeUserUpdated, Payload: []byte(user.Email)},
+		{EventID: user.Email + "-deleted", EventType: events.TypeUserDeleted, Payload: []byte("{}"), Anonymous: true},
+	} {
+		delivery.SubscriptionID, delivery.UserID = subscriptionID, user.ID
+		require.NoError(t, webhooks.CreateDelivery(ctx, delivery))
+	}
+}
+
+// subscribe создает подписку webhook для доставок
+func (d userData) subscribe(t *testing.T) uint {
+	subscription := &models.WebhookSubscription{URL: "https://example.com/hook", Secret: "secret", Enabled: true}
+	require.NoError(t, repository.NewWebhookRepository(d.db).CreateSubscription(context.Background(), subscription))
+	return subscription.ID
+}
+
+func (d userData) codeOwners(t *testing.T) []uint {
+	var userIDs []uint
+	require.NoError(t, d.db.Model(&models.OAuthAuthorizationCode{}).Order("user_id").Pluck("user_id", &userIDs).Error)
+	return userIDs
+}
+
+func (d userData) rows(t *testing.T, model interface{}, typeColumn string) []userRow {
+	var rows []userRow
+	require.NoError(t, d.db.Model(model).Select("user_id, "+typeColumn+" AS type").Order("id").Scan(&rows).Error)
+	return rows
+}
+
+func testPurgeDeletesUserData(t *testing.T, newRepo NewUserRepository) {
+	ctx := context.Background()
+	repo, db := newRepo(t)
+	data := userData{db: db}
+	subscriptionID := data.subscribe(t)
+
+	var ids []uint
+	for i := 1; i <= 2; i++ {
+		user := newUser(i)
+		require.NoError(t, repo.Create(ctx, user))
+		data.create(t, subscriptionID, user)
+		ids = append(ids, user.ID)
+	}
+	// Опубликованное событие без персональных данных тоже удаляется
+	require.NoError(t, db.Model(&models.OutboxEvent{}).Where("user_id = ? AND anonymous = ?", ids[1], true).
+		Update("published_at", time.Now()).Error)
+
+	require.NoError(t, repo.Delete(ctx, ids[0]))
+	require.NoError(t, repo.Purge(ctx, ids[0]))
+
+	assert.Equal(t, []uint{ids[1]}, data.codeOwners(t), "данные удаленного пользователя удалены, остальных - нет")
+	// Остается только неотправленное событие об удалении, без персональных данных
+	assert.Equal(t, []userRow{
+		{ids[0], events.TypeUserDeleted},
+		{ids[1], events.TypeUserCreated},
+		{ids[1], events.TypeUserDeleted},
+	}, data.rows(t, &models.OutboxEvent{}, "type"))
+	assert.Equal(t, []userRow{
+		{ids[0], events.TypeUserDeleted},
+		{ids[1], events.TypeUserCreated},
+		{ids[1], events.TypeUserUpdated},
+		{ids[1], events.TypeUserDeleted},
+	}, data.rows(t, &models.WebhookDelivery{}, "event_type"))
+
+	// Удаление по сроку хранения удаляет те же данные
+	require.NoError(t, repo.Delete(ctx, ids[1]))
+	purged, err := repo.PurgeDeletedBefore(ctx, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	assert.Empty(t, data.codeOwners(t))
+	assert.Equal(t, []userRow{{ids[0], events.TypeUserDeleted}}, data.rows(t, &models.OutboxEvent{}, "type"))
+	assert.Equal(t, []userRow{
+		{ids[0], events.TypeUserDeleted},
+		{ids[1], events.TypeUserDeleted},
+	}, data.rows(t, &models.WebhookDelivery{}, "event_type"))
+}
+
+func testEraseDeletesUserData(t *testing.T, newRepo NewUserRepository) {
+	ctx := context.Background()
+	repo, db := newRepo(t)
+	data := userData{db: db}
+	subscriptionID := data.subscribe(t)
+
+	var ids []uint
+	for i := 1; i <= 2; i++ {
+		user := newUser(i)
+		require.NoError(t, repo.Create(ctx, user))
+		data.create(t, subscriptionID, user)
+		ids = append(ids, user.ID)
+	}
+
+	require.NoError(t, repo.Erase(ctx, ids[0], time.Now()))
+	assert.Equal(t, []uint{ids[1]}, data.codeOwners(t))
+	assert.Equal(t, []userRow{
+		{ids[0], events.TypeUserDeleted},
+		{ids[1], events.TypeUserCreated},
+		{ids[1], events.TypeUserDeleted},
+	}, data.rows(t, &models.OutboxEvent{}, "type"))
+	assert.Equal(t, []userRow{
+		{ids[0], events.TypeUserDeleted},
+		{ids[1], events.TypeUserCreated},
+		{ids[1], events.TypeUserUpdated},
+		{ids[1], events.TypeUserDeleted},
+	}, data.rows(t, &models.WebhookDelivery{}, "event_type"))
+}
+
+func testListPagination(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		require.NoError(t, repo.Create(ctx, newUser(i)))
+	}
+
+	all, err := repo.List(ctx, 0, 0)
+	require.NoError(t, err)
+	require.Len(t, all, 5)
+	for i, user := range all {
+		assert.Equal(t, fmt.Sprintf("user%d@example.com", i+1), user.Email, "порядок по ID")
+	}
+
+	page, err := repo.List(ctx, 2, 1)
+	require.NoError(t, err)
+	require.Len(t, page, 2)
+	assert.Equal(t, all[1].ID, page[0].ID)
+	assert.Equal(t, all[2].ID, page[1].ID)
+
+	tail, err := repo.List(ctx, 10, 4)
+	require.NoError(t, err)
+	assert.Len(t, tail, 1)
+
+	empty, err := repo.List(ctx, 10, 10)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func testConcurrentCreate(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	const workers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			errs <- repo.Create(ctx, newUser(n))
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(workers), count)
+}
+
+func testCaseInsensitiveEmail(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	user := newUser(1)
+	user.Email = "  Bob@Example.COM "
+	require.NoError(t, repo.Create(ctx, user))
+	assert.Equal(t, "bob@example.com", user.Email)
+
+	found, err := repo.GetByEmail(ctx, "BOB@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, found.ID)
+	assert.Equal(t, "bob@example.com", found.Email)
+
+	duplicate := newUser(2)
+	duplicate.Email = "bob@example.com"
+	assert.ErrorIs(t, repo.Create(ctx, duplicate), repository.ErrDuplicateEmail)
+
+	other := newUser(3)
+	require.NoError(t, repo.Create(ctx, other))
+	other.Email = "BOB@EXAMPLE.COM"
+	assert.ErrorIs(t, repo.Update(ctx, other), repository.ErrDuplicateEmail)
+}
+
+// testConcurrentSameEmail проверяет, что из параллельных регистраций с одним email,
+// записанным в разном регистре, успешна ровно одна
+func testConcurrentSameEmail(t *testing.T, repo repository.UserRepository) {
+	ctx := context.Background()
+	const workers = 20
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			user := newUser(n)
+			if n%2 == 0 {
+				user.Email = "same@example.com"
+			} else {
+				user.Email = "Same@Example.com"
+			}
+			errs <- repo.Create(ctx, user)
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	created := 0
+	for err := range errs {
+		if err == nil {
+			created++
+			continue
+		}
+		assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+	}
+	assert.Equal(t, 1, created)
+
+	count, err := repo.Count(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
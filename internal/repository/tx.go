@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// maxTxAttempts наибольшее число попыток транзакции при конфликте сериализации
+	maxTxAttempts = 4
+	// txRetryBaseDelay пауза перед первым повтором; удваивается с каждой попыткой
+	txRetryBaseDelay = 10 * time.Millisecond
+)
+
+// TxManager выполняет несколько операций репозиториев атомарно
+type TxManager interface {
+	// WithinTx выполняет fn в транзакции. Репозитории, вызванные с переданным в fn
+	// контекстом, работают внутри нее. Ошибка fn откатывает транзакцию. Вложенный
+	// вызов создает точку сохранения: его ошибка откатывает только вложенные изменения.
+	// При конфликте сериализации или взаимной блокировке внешняя транзакция
+	// повторяется целиком, поэтому fn не должна иметь побочных эффектов вне базы данных.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// txContextKey ключ контекста с текущей транзакцией
+type txContextKey struct{}
+
+// txManager реализация TxManager на GORM
+type txManager struct {
+	db        *gorm.DB
+	attempts  int
+	baseDelay time.Duration
+}
+
+// NewTxManager создает менеджер транзакций для репозиториев, созданных с тем же db
+func NewTxManager(db *gorm.DB) TxManager {
+	return &txManager{db: db, attempts: maxTxAttempts, baseDelay: txRetryBaseDelay}
+}
+
+// WithinTx выполняет fn в транзакции или точке сохранения текущей транзакции
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		// GORM создает точку сохранения для транзакции внутри транзакции
+		return tx.Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txContextKey{}, tx))
+		})
+	}
+
+	delay := m.baseDelay
+	for attempt := 1; ; attempt++ {
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return fn(context.WithValue(ctx, txContextKey{}, tx))
+		})
+		if err == nil || attempt >= m.attempts || !isSerializationFailure(err) {
+			return err
+		}
+
+		// Параллельные транзакции повторяются в разное время, чтобы не столкнуться снова
+		wait := delay/2 + rand.N(delay)
+		select {
+		case <-ctx.Done():
+			return errors.Join(err, ctx.Err())
+		case <-time.After(wait):
+		}
+		delay *= 2
+	}
+}
+
+// conn возвращает транзакцию из контекста или db, если транзакции нет
+func conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := ctx.Value(txContextKey{}).(*gorm.DB); ok {
+		return tx.WithContext(ctx)
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,104 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+func newSQLiteTx(t *testing.T) (repository.TxManager, repository.UserRepository) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+	return repository.NewTxManager(db), repository.NewUserRepository(db)
+}
+
+func createUser(ctx context.Context, repo repository.UserRepository, email string) error {
+	return repo.Create(ctx, &models.User{Name: email, Email: email, PasswordHash: "hash", Role: "user", IsActive: true})
+}
+
+func countUsers(t *testing.T, repo repository.UserRepository) int64 {
+	count, err := repo.Count(context.Background())
+	require.NoError(t, err)
+	return count
+}
+
+func TestTxManager_WithinTx(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("фиксация", func(t *testing.T) {
+		tx, repo := newSQLiteTx(t)
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, createUser(ctx, repo, "a@example.com"))
+			return createUser(ctx, repo, "b@example.com")
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), countUsers(t, repo))
+	})
+
+	t.Run("откат при ошибке", func(t *testing.T) {
+		tx, repo := newSQLiteTx(t)
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, createUser(ctx, repo, "a@example.com"))
+			return createUser(ctx, repo, "A@example.com")
+		})
+		assert.ErrorIs(t, err, repository.ErrDuplicateEmail)
+		assert.Zero(t, countUsers(t, repo))
+	})
+
+	t.Run("вложенная транзакция откатывается до точки сохранения", func(t *testing.T) {
+		tx, repo := newSQLiteTx(t)
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			require.NoError(t, createUser(ctx, repo, "a@example.com"))
+			inner := tx.WithinTx(ctx, func(ctx context.Context) error {
+				require.NoError(t, createUser(ctx, repo, "b@example.com"))
+				return errors.New("отказ")
+			})
+			assert.Error(t, inner)
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), countUsers(t, repo))
+		_, err = repo.GetByEmail(ctx, "b@example.com")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("повтор при конфликте сериализации", func(t *testing.T) {
+		tx, repo := newSQLiteTx(t)
+		attempts := 0
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			attempts++
+			if err := createUser(ctx, repo, "a@example.com"); err != nil {
+				return err
+			}
+			if attempts < 3 {
+				return &pgconn.PgError{Code: "40001"}
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 3, attempts)
+		assert.Equal(t, int64(1), countUsers(t, repo))
+	})
+
+	t.Run("другие ошибки не повторяются", func(t *testing.T) {
+		tx, _ := newSQLiteTx(t)
+		attempts := 0
+		err := tx.WithinTx(ctx, func(ctx context.Context) error {
+			attempts++
+			return &pgconn.PgError{Code: "23505"}
+		})
+		assert.Error(t, err)
+		assert.Equal(t, 1, attempts)
+	})
+}
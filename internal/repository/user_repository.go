@@ -2,13 +2,27 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
 	"k8s-go-grpc-react/internal/models"
 
 	"gorm.io/gorm"
 )
 
-// UserRepository интерфейс для работы с пользователями
+// UserRepository интерфейс для работы с пользователями. Отсутствующий пользователь -
+// ErrNotFound, занятый email - ErrDuplicateEmail, сбой соединения - ErrUnavailable.
+// Email сохраняется и ищется в виде models.NormalizeEmail; уникальность email
+// обеспечивает хранилище, а не проверка перед записью, и удаленные пользователи
+// email не занимают. Update выполняется только для текущей версии пользователя,
+// иначе возвращается ErrConflict.
+//
+// Удаленных пользователей возвращают только ListDeleted и GetDeleted. Restore
+// снимает отметку удаления и возвращает ErrDuplicateEmail, если email уже занят;
+// Purge и PurgeDeletedBefore удаляют удаленных пользователей окончательно вместе
+// с зависящими от них данными. Erase заменяет персональные данные пользователя,
+// в том числе удаленного, заглушками, запись пользователя при этом остается.
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	GetByID(ctx context.Context, id uint) (*models.User, error)
@@ -17,8 +31,18 @@ type UserRepository interface {
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uint) error
 	Count(ctx context.Context) (int64, error)
+
+	ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error)
+	GetDeleted(ctx context.Context, id uint) (*models.User, error)
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, id uint) error
+	PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error)
+	Erase(ctx context.Context, id uint, now time.Time) error
 }
 
+// purgeBatchSize наибольшее число пользователей, удаляемых окончательно в одной транзакции
+const purgeBatchSize = 500
+
 // userRepository реализация репозитория пользователей
 type userRepository struct {
 	db *gorm.DB
@@ -31,8 +55,13 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 
 // Create создает нового пользователя
 func (r *userRepository) Create(ctx context.Context, user *models.User) error {
-	if err := r.db.WithContext(ctx).Create(user).Error; err != nil {
-		return fmt.Errorf("ошибка при создании пользователя: %w", err)
+	user.Email = models.NormalizeEmail(user.Email)
+	user.Version = 1
+	if err := conn(ctx, r.db).Create(user).Error; err != nil {
+		if isUniqueViolation(r.db, err) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+		}
+		return fmt.Errorf("ошибка при создании пользователя: %w", dbError(err))
 	}
 	return nil
 }
@@ -40,11 +69,11 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 // GetByID получает пользователя по ID
 func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
 	var user models.User
-	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("пользователь с ID %d не найден", id)
+	if err := conn(ctx, r.db).First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, id)
 		}
-		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", dbError(err))
 	}
 	return &user, nil
 }
@@ -52,19 +81,20 @@ func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, er
 // GetByEmail получает пользователя по email
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fmt.Errorf("пользователь с email %s не найден", email)
+	email = models.NormalizeEmail(email)
+	if err := conn(ctx, r.db).Where("email = ?", email).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: пользователь с email %s", ErrNotFound, email)
 		}
-		return nil, fmt.Errorf("ошибка при получении пользователя: %w", err)
+		return nil, fmt.Errorf("ошибка при получении пользователя: %w", dbError(err))
 	}
 	return &user, nil
 }
 
-// List получает список пользователей с пагинацией
+// List получает список пользователей с пагинацией в порядке ID
 func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
 	var users []*models.User
-	query := r.db.WithContext(ctx)
+	query := conn(ctx, r.db).Order("id")
 
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -74,23 +104,55 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models
 	}
 
 	if err := query.Find(&users).Error; err != nil {
-		return nil, fmt.Errorf("ошибка при получении списка пользователей: %w", err)
+		return nil, fmt.Errorf("ошибка при получении списка пользователей: %w", dbError(err))
 	}
 	return users, nil
 }
 
-// Update обновляет пользователя
+// Update сохраняет пользователя, если его версия в базе равна user.Version, и увеличивает
+// версию. Если пользователя изменили после чтения, возвращается ErrConflict.
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
-	if err := r.db.WithContext(ctx).Save(user).Error; err != nil {
-		return fmt.Errorf("ошибка при обновлении пользователя: %w", err)
+	user.Email = models.NormalizeEmail(user.Email)
+	now := time.Now()
+	result := conn(ctx, r.db).Model(&models.User{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"name":          user.Name,
+			"email":         user.Email,
+			"password_hash": user.PasswordHash,
+			"role":          user.Role,
+			"is_active":     user.IsActive,
+			"version":       gorm.Expr("version + 1"),
+			"updated_at":    now,
+		})
+	if err := result.Error; err != nil {
+		if isUniqueViolation(r.db, err) {
+			return fmt.Errorf("%w: %s", ErrDuplicateEmail, user.Email)
+		}
+		return fmt.Errorf("ошибка при обновлении пользователя: %w", dbError(err))
 	}
+
+	if result.RowsAffected == 0 {
+		// Строка не обновлена: пользователя нет или версия устарела
+		var count int64
+		if err := conn(ctx, r.db).Model(&models.User{}).Where("id = ?", user.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("ошибка при обновлении пользователя: %w", dbError(err))
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, user.ID)
+		}
+		return fmt.Errorf("%w: пользователь с ID %d, версия %d", ErrConflict, user.ID, user.Version)
+	}
+
+	user.Version++
+	user.UpdatedAt = now
 	return nil
 }
 
 // Delete удаляет пользователя (soft delete)
 func (r *userRepository) Delete(ctx context.Context, id uint) error {
-	if err := r.db.WithContext(ctx).Delete(&models.User{}, id).Error; err != nil {
-		return fmt.Errorf("ошибка при удалении пользователя: %w", err)
+	if err := conn(ctx, r.db).Delete(&models.User{}, id).Error; err != nil {
+		return fmt.Errorf("ошибка при удалении пользователя: %w", dbError(err))
 	}
 	return nil
 }
@@ -98,8 +160,184 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 // Count возвращает общее количество пользователей
 func (r *userRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
-		return 0, fmt.Errorf("ошибка при подсчете пользователей: %w", err)
+	if err := conn(ctx, r.db).Model(&models.User{}).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("ошибка при подсчете пользователей: %w", dbError(err))
 	}
 	return count, nil
 }
+
+// ListDeleted получает список удаленных пользователей с пагинацией в порядке ID
+func (r *userRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	var users []*models.User
+	query := conn(ctx, r.db).Unscoped().Where("deleted_at IS NOT NULL").Order("id")
+
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	if offset > 0 {
+		query = query.Offset(offset)
+	}
+
+	if err := query.Find(&users).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении списка удаленных пользователей: %w", dbError(err))
+	}
+	return users, nil
+}
+
+// GetDeleted получает удаленного пользователя по ID
+func (r *userRepository) GetDeleted(ctx context.Context, id uint) (*models.User, error) {
+	var user models.User
+	if err := conn(ctx, r.db).Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при получении удаленного пользователя: %w", dbError(err))
+	}
+	return &user, nil
+}
+
+// Restore снимает с пользователя отметку удаления и увеличивает версию. Email
+// удаленного пользователя мог занять другой пользователь, тогда возвращается
+// ErrDuplicateEmail: уникальный индекс учитывает только неудаленных пользователей.
+func (r *userRepository) Restore(ctx context.Context, id uint) error {
+	result := conn(ctx, r.db).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Updates(map[string]interface{}{
+			"deleted_at": nil,
+			"version":    gorm.Expr("version + 1"),
+			"updated_at": time.Now(),
+		})
+	if err := result.Error; err != nil {
+		if isUniqueViolation(r.db, err) {
+			return fmt.Errorf("%w: email удаленного пользователя с ID %d занят", ErrDuplicateEmail, id)
+		}
+		return fmt.Errorf("ошибка при восстановлении пользователя: %w", dbError(err))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+	}
+	return nil
+}
+
+// Purge окончательно удаляет удаленного пользователя и его данные
+func (r *userRepository) Purge(ctx context.Context, id uint) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Where("deleted_at IS NOT NULL").Delete(&models.User{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: удаленный пользователь с ID %d", ErrNotFound, id)
+		}
+		return deleteUserData(tx, []uint{id})
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("ошибка при окончательном удалении пользователя: %w", dbError(err))
+	}
+	return err
+}
+
+// PurgeDeletedBefore окончательно удаляет пользователей, удаленных раньше before,
+// и их данные. Пользователи удаляются частями по purgeBatchSize в отдельных транзакциях.
+func (r *userRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		var purged int64
+		err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+			var ids []uint
+			if err := tx.Unscoped().Model(&models.User{}).
+				Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+				Order("id").Limit(purgeBatchSize).
+				Pluck("id", &ids).Error; err != nil {
+				return err
+			}
+			if len(ids) == 0 {
+				return nil
+			}
+			if err := deleteUserData(tx, ids); err != nil {
+				return err
+			}
+			result := tx.Unscoped().Delete(&models.User{}, ids)
+			purged = result.RowsAffected
+			return result.Error
+		})
+		if err != nil {
+			return total, fmt.Errorf("ошибка при окончательном удалении пользователей: %w", dbError(err))
+		}
+		total += purged
+		if purged < purgeBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// Erase заменяет имя и email пользователя заглушками models.ErasedName и
+// models.ErasedEmail, удаляет хеш пароля, блокирует пользователя и удаляет его
+// данные. ID сохраняется, поэтому ссылки на пользователя остаются корректными.
+// Для отсутствующего пользователя и пользователя, данные которого уже удалены,
+// возвращается ErrNotFound.
+func (r *userRepository) Erase(ctx context.Context, id uint, now time.Time) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Unscoped().Model(&models.User{}).
+			Where("id = ? AND erased_at IS NULL", id).
+			Updates(map[string]interface{}{
+				"name":          models.ErasedName,
+				"email":         models.ErasedEmail(id),
+				"password_hash": "",
+				"is_active":     false,
+				"version":       gorm.Expr("version + 1"),
+				"updated_at":    now,
+				"erased_at":     now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: пользователь с ID %d", ErrNotFound, id)
+		}
+		return deleteUserData(tx, []uint{id})
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("ошибка при удалении персональных данных пользователя: %w", dbError(err))
+	}
+	return err
+}
+
+// UserDataDeleter удаляет данные, которые принадлежат пользователям ids
+// (см. deleteUserData). Нужен реализациям UserRepository, хранящим пользователей
+// отдельно от остальных таблиц.
+type UserDataDeleter func(ctx context.Context, ids []uint) error
+
+// NewUserDataDeleter создает UserDataDeleter для таблиц в db. Внутри транзакции
+// TxManager данные удаляются в ней.
+func NewUserDataDeleter(db *gorm.DB) UserDataDeleter {
+	return func(ctx context.Context, ids []uint) error {
+		err := conn(ctx, db).Transaction(func(tx *gorm.DB) error {
+			return deleteUserData(tx, ids)
+		})
+		if err != nil {
+			return fmt.Errorf("ошибка при удалении данных пользователей: %w", dbError(err))
+		}
+		return nil
+	}
+}
+
+// deleteUserData удаляет данные, которые принадлежат пользователям ids, включая
+// события outbox и доставки webhook со снимками пользователя: повторная отправка
+// не должна передать удаленные данные. Неотправленные события без персональных
+// данных (удаление, обезличивание) остаются, чтобы получатели узнали об удалении.
+// Записи аудита остаются: журнал защищен цепочкой хешей и удаляется только по
+// сроку хранения.
+func deleteUserData(tx *gorm.DB, ids []uint) error {
+	if err := tx.Where("user_id IN ?", ids).Delete(&models.OAuthAuthorizationCode{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id IN ?", ids).
+		Where("NOT (anonymous = ? AND published_at IS NULL AND failed_at IS NULL)", true).
+		Delete(&models.OutboxEvent{}).Error; err != nil {
+		return err
+	}
+	return tx.Where("user_id IN ?", ids).
+		Where("NOT (anonymous = ? AND status = ?)", true, models.WebhookDeliveryPending).
+		Delete(&models.WebhookDelivery{}).Error
+}
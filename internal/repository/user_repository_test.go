@@ -0,0 +1,57 @@
+package repository_test
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/repository/repositorytest"
+)
+
+// newTestDB создает базу SQLite в памяти с таблицами приложения
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+	return db
+}
+
+func TestMemoryUserRepository(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) (repository.UserRepository, *gorm.DB) {
+		db := newTestDB(t)
+		return repository.NewMemoryUserRepository(repository.NewUserDataDeleter(db)), db
+	})
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	repositorytest.TestUserRepository(t, func(t *testing.T) (repository.UserRepository, *gorm.DB) {
+		db := newTestDB(t)
+		return repository.NewUserRepository(db), db
+	})
+}
+
+// TestPostgresUserRepository выполняется, если задан TEST_DATABASE_URL.
+// Таблица users и таблицы данных пользователей очищаются перед каждым тестом.
+func TestPostgresUserRepository(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL не задан")
+	}
+
+	db, err := database.Connect(dsn, logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	repositorytest.TestUserRepository(t, func(t *testing.T) (repository.UserRepository, *gorm.DB) {
+		require.NoError(t, db.Exec("TRUNCATE users, oauth_authorization_codes, outbox_events, "+
+			"webhook_deliveries, webhook_subscriptions RESTART IDENTITY").Error)
+		return repository.NewUserRepository(db), db
+	})
+}
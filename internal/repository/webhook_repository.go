@@ -0,0 +1,261 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"k8s-go-grpc-react/internal/models"
+)
+
+// WebhookDeliveryFilter условия выборки доставок webhook. Нулевые значения не ограничивают выборку.
+type WebhookDeliveryFilter struct {
+	SubscriptionID uint
+	Status         string
+	BeforeID       uint // Только доставки с ID меньше заданного, для постраничного чтения
+	Limit          int
+}
+
+// WebhookRepository интерфейс подписок на webhook и журнала доставок
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error)
+	UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error
+	// DeleteSubscription удаляет подписку вместе с журналом ее доставок
+	DeleteSubscription(ctx context.Context, id uint) error
+	// RecordSubscriptionFailure увеличивает счетчик неудачных попыток подряд и отключает
+	// подписку, когда он достигает threshold. Возвращает true, если подписка отключена этим вызовом.
+	RecordSubscriptionFailure(ctx context.Context, id uint, threshold int, reason string, now time.Time) (bool, error)
+	// ResetSubscriptionFailures обнуляет счетчик неудачных попыток подряд
+	ResetSubscriptionFailures(ctx context.Context, id uint) error
+
+	// CreateDelivery добавляет доставку; повтор события для той же подписки пропускается
+	CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error)
+	// ListDeliveries возвращает доставки по фильтру, начиная с новых
+	ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error)
+	// ClaimDueDeliveries выбирает до limit ожидающих доставок включенных подписок,
+	// срок попытки которых наступил, и откладывает их на lease, чтобы другие
+	// экземпляры сервера не отправили их одновременно
+	ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error)
+	// UpdateDelivery сохраняет результат попытки доставки
+	UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error
+	// DeleteDeliveriesBefore удаляет завершенные доставки, созданные раньше before
+	DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// webhookRepository реализация репозитория webhook на GORM
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository создает новый экземпляр репозитория webhook
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+// CreateSubscription создает подписку
+func (r *webhookRepository) CreateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	if err := conn(ctx, r.db).Create(subscription).Error; err != nil {
+		return fmt.Errorf("ошибка при создании подписки webhook: %w", dbError(err))
+	}
+	return nil
+}
+
+// GetSubscription получает подписку по ID
+func (r *webhookRepository) GetSubscription(ctx context.Context, id uint) (*models.WebhookSubscription, error) {
+	var subscription models.WebhookSubscription
+	if err := conn(ctx, r.db).First(&subscription, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: подписка webhook с ID %d", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при получении подписки webhook: %w", dbError(err))
+	}
+	return &subscription, nil
+}
+
+// ListSubscriptions возвращает все подписки по возрастанию ID
+func (r *webhookRepository) ListSubscriptions(ctx context.Context) ([]*models.WebhookSubscription, error) {
+	var subscriptions []*models.WebhookSubscription
+	if err := conn(ctx, r.db).Order("id").Find(&subscriptions).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении подписок webhook: %w", dbError(err))
+	}
+	return subscriptions, nil
+}
+
+// UpdateSubscription сохраняет все поля подписки
+func (r *webhookRepository) UpdateSubscription(ctx context.Context, subscription *models.WebhookSubscription) error {
+	result := conn(ctx, r.db).Model(subscription).Select("*").Omit("created_at").Updates(subscription)
+	if result.Error != nil {
+		return fmt.Errorf("ошибка при обновлении подписки webhook: %w", dbError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: подписка webhook с ID %d", ErrNotFound, subscription.ID)
+	}
+	return nil
+}
+
+// DeleteSubscription удаляет подписку и ее доставки
+func (r *webhookRepository) DeleteSubscription(ctx context.Context, id uint) error {
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&models.WebhookSubscription{}, id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: подписка webhook с ID %d", ErrNotFound, id)
+		}
+		return tx.Where("subscription_id = ?", id).Delete(&models.WebhookDelivery{}).Error
+	})
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		return fmt.Errorf("ошибка при удалении подписки webhook: %w", dbError(err))
+	}
+	return err
+}
+
+// RecordSubscriptionFailure учитывает неудачную попытку доставки по подписке
+func (r *webhookRepository) RecordSubscriptionFailure(ctx context.Context, id uint, threshold int, reason string, now time.Time) (bool, error) {
+	disabled := false
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.WebhookSubscription{}).Where("id = ?", id).
+			UpdateColumn("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+		if err != nil || threshold <= 0 {
+			return err
+		}
+
+		// Подписку отключает только один из параллельных вызовов
+		result := tx.Model(&models.WebhookSubscription{}).
+			Where("id = ? AND enabled = ? AND consecutive_failures >= ?", id, true, threshold).
+			Updates(map[string]interface{}{
+				"enabled":         false,
+				"disabled_at":     now,
+				"disabled_reason": reason,
+			})
+		disabled = result.RowsAffected > 0
+		return result.Error
+	})
+	if err != nil {
+		return false, fmt.Errorf("ошибка при учете неудачной доставки webhook: %w", dbError(err))
+	}
+	return disabled, nil
+}
+
+// ResetSubscriptionFailures обнуляет счетчик после успешной доставки
+func (r *webhookRepository) ResetSubscriptionFailures(ctx context.Context, id uint) error {
+	err := conn(ctx, r.db).Model(&models.WebhookSubscription{}).
+		Where("id = ? AND consecutive_failures <> 0", id).
+		UpdateColumn("consecutive_failures", 0).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при сбросе счетчика ошибок подписки webhook: %w", dbError(err))
+	}
+	return nil
+}
+
+// CreateDelivery добавляет доставку, если ее еще нет
+func (r *webhookRepository) CreateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	if delivery.Status == "" {
+		delivery.Status = models.WebhookDeliveryPending
+	}
+	if delivery.NextAttemptAt.IsZero() {
+		delivery.NextAttemptAt = time.Now()
+	}
+	err := conn(ctx, r.db).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subscription_id"}, {Name: "event_id"}},
+		DoNothing: true,
+	}).Create(delivery).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при создании доставки webhook: %w", dbError(err))
+	}
+	return nil
+}
+
+// GetDelivery получает доставку по ID
+func (r *webhookRepository) GetDelivery(ctx context.Context, id uint) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	if err := conn(ctx, r.db).First(&delivery, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: доставка webhook с ID %d", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("ошибка при получении доставки webhook: %w", dbError(err))
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries возвращает доставки по фильтру
+func (r *webhookRepository) ListDeliveries(ctx context.Context, filter WebhookDeliveryFilter) ([]*models.WebhookDelivery, error) {
+	query := conn(ctx, r.db).Order("id DESC")
+	if filter.SubscriptionID != 0 {
+		query = query.Where("subscription_id = ?", filter.SubscriptionID)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.BeforeID != 0 {
+		query = query.Where("id < ?", filter.BeforeID)
+	}
+	if filter.Limit > 0 {
+		query = query.Limit(filter.Limit)
+	}
+
+	var deliveries []*models.WebhookDelivery
+	if err := query.Find(&deliveries).Error; err != nil {
+		return nil, fmt.Errorf("ошибка при получении доставок webhook: %w", dbError(err))
+	}
+	return deliveries, nil
+}
+
+// ClaimDueDeliveries выбирает доставки для отправки. Запрос по HTTP выполняется
+// после фиксации транзакции, чтобы медленный адрес не держал блокировку строк.
+func (r *webhookRepository) ClaimDueDeliveries(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*models.WebhookDelivery, error) {
+	var deliveries []*models.WebhookDelivery
+	err := conn(ctx, r.db).Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "webhook_deliveries"}, Options: "SKIP LOCKED"}).
+			Joins("JOIN webhook_subscriptions ON webhook_subscriptions.id = webhook_deliveries.subscription_id").
+			Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+			Where("webhook_subscriptions.enabled = ?", true).
+			Order("webhook_deliveries.id").
+			Limit(limit).
+			Find(&deliveries).Error
+		if err != nil || len(deliveries) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(deliveries))
+		for i, delivery := range deliveries {
+			ids[i] = delivery.ID
+		}
+		return tx.Model(&models.WebhookDelivery{}).Where("id IN ?", ids).
+			UpdateColumn("next_attempt_at", now.Add(lease)).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка при выборе доставок webhook: %w", dbError(err))
+	}
+	return deliveries, nil
+}
+
+// UpdateDelivery сохраняет состояние доставки
+func (r *webhookRepository) UpdateDelivery(ctx context.Context, delivery *models.WebhookDelivery) error {
+	err := conn(ctx, r.db).Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "last_attempt_at", "response_status", "last_error", "delivered_at", "updated_at",
+	).Updates(delivery).Error
+	if err != nil {
+		return fmt.Errorf("ошибка при обновлении доставки webhook: %w", dbError(err))
+	}
+	return nil
+}
+
+// DeleteDeliveriesBefore удаляет доставленные и исчерпавшие попытки доставки старше before
+func (r *webhookRepository) DeleteDeliveriesBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := conn(ctx, r.db).Where("status <> ? AND created_at < ?", models.WebhookDeliveryPending, before).
+		Delete(&models.WebhookDelivery{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("ошибка при удалении доставок webhook: %w", dbError(result.Error))
+	}
+	return result.RowsAffected, nil
+}
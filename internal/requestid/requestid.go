@@ -0,0 +1,129 @@
+// Package requestid передает идентификатор запроса от HTTP клиента до gRPC сервера,
+// чтобы записи логов gateway и сервера по одному запросу можно было связать.
+// Идентификатор возвращается клиенту в заголовке X-Request-ID и в теле ошибок.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+const (
+	// Header HTTP заголовок запроса и ответа
+	Header = "X-Request-ID"
+	// MetadataKey ключ gRPC метаданных
+	MetadataKey = "x-request-id"
+	// LogField имя поля в логах
+	LogField = "request_id"
+
+	// maxLength ограничивает длину идентификатора, принятого от клиента
+	maxLength = 128
+)
+
+type contextKey struct{}
+
+// New генерирует новый идентификатор запроса
+func New() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Valid проверяет идентификатор, полученный от клиента. Допускаются только
+// буквы, цифры и символы -_.: чтобы значение безопасно попадало в логи и заголовки.
+func Valid(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext сохраняет идентификатор запроса в контексте
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext возвращает идентификатор запроса или пустую строку
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Middleware принимает идентификатор из заголовка X-Request-ID или генерирует новый,
+// сохраняет его в контексте и возвращает в заголовке ответа.
+// Если идентификатор уже есть в контексте, он используется повторно.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := FromContext(r.Context())
+		if id == "" {
+			if id = r.Header.Get(Header); !Valid(id) {
+				id = New()
+			}
+			r = r.WithContext(NewContext(r.Context(), id))
+		}
+
+		w.Header().Set(Header, id)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// UnaryServerInterceptor принимает идентификатор из метаданных x-request-id или генерирует
+// новый, добавляет его в логер контекста и в заголовок ответа.
+// Ошибки дополняются деталью google.rpc.RequestInfo с идентификатором.
+func UnaryServerInterceptor(log *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		id := ""
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if values := md.Get(MetadataKey); len(values) > 0 {
+				id = values[0]
+			}
+		}
+		if !Valid(id) {
+			id = New()
+		}
+
+		ctx = NewContext(ctx, id)
+		ctx = logger.NewContext(ctx, log.With(LogField, id))
+		_ = grpc.SetHeader(ctx, metadata.Pairs(MetadataKey, id))
+
+		resp, err := handler(ctx, req)
+		if err != nil {
+			err = WithRequestInfo(err, id)
+		}
+		return resp, err
+	}
+}
+
+// WithRequestInfo добавляет к gRPC ошибке деталь RequestInfo, если ее еще нет
+func WithRequestInfo(err error, id string) error {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		if _, ok := detail.(*errdetails.RequestInfo); ok {
+			return err
+		}
+	}
+
+	withInfo, detailErr := st.WithDetails(&errdetails.RequestInfo{RequestId: id})
+	if detailErr != nil {
+		return err
+	}
+	return withInfo.Err()
+}
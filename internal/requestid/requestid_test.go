@@ -0,0 +1,68 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/logger"
+)
+
+func TestMiddleware(t *testing.T) {
+	var got string
+	h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = FromContext(r.Context())
+	}))
+
+	// Корректный идентификатор клиента сохраняется
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "support-42")
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Equal(t, "support-42", got)
+	assert.Equal(t, "support-42", rec.Header().Get(Header))
+
+	// Некорректный заменяется новым
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(Header, "bad id\nwith newline")
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	assert.Len(t, got, 32)
+	assert.Equal(t, got, rec.Header().Get(Header))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	out := &bytes.Buffer{}
+	interceptor := UnaryServerInterceptor(slog.New(slog.NewJSONHandler(out, nil)))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(MetadataKey, "req-1"))
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, "req-1", FromContext(ctx))
+		logger.FromContext(ctx, logger.Discard()).Info("обработка")
+		return nil, status.Error(codes.NotFound, "не найдено")
+	})
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(out.Bytes(), &entry))
+	assert.Equal(t, "req-1", entry[LogField])
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.NotFound, st.Code())
+	require.Len(t, st.Details(), 1)
+	assert.Equal(t, "req-1", st.Details()[0].(*errdetails.RequestInfo).RequestId)
+
+	// Повторное добавление не дублирует деталь
+	assert.Len(t, status.Convert(WithRequestInfo(err, "req-1")).Details(), 1)
+}
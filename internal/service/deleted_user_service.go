@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	pb "k8s-go-grpc-react/proto"
+)
+
+// DefaultPurgeInterval период окончательного удаления пользователей с истекшим сроком хранения
+const DefaultPurgeInterval = time.Hour
+
+// ListDeletedUsers возвращает удаленных пользователей, которых можно восстановить
+// (только для админов)
+func (s *UserService) ListDeletedUsers(ctx context.Context, req *pb.Empty) (*pb.DeletedUserListResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("ListDeletedUsers", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics("ListDeletedUsers", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Удаленные пользователи доступны только администраторам")
+	}
+
+	users, err := s.userRepo.ListDeleted(ctx, 0, 0)
+	if err != nil {
+		return nil, s.repositoryError("ListDeletedUsers", start, err, "Ошибка при получении списка удаленных пользователей")
+	}
+
+	deleted := make([]*pb.DeletedUser, len(users))
+	for i, user := range users {
+		deleted[i] = &pb.DeletedUser{
+			User:      s.modelToProto(user),
+			DeletedAt: user.DeletedAt.Time.Unix(),
+		}
+	}
+
+	return &pb.DeletedUserListResponse{
+		Users: deleted,
+		Total: int32(len(deleted)),
+	}, nil
+}
+
+// RestoreUser восстанавливает удаленного пользователя (только для админов).
+// Пока пользователь был удален, его email мог занять другой пользователь:
+// тогда восстановление отклоняется, email нужно сначала освободить.
+func (s *UserService) RestoreUser(ctx context.Context, req *pb.RestoreUserRequest) (*pb.UserResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("RestoreUser", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics("RestoreUser", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Восстанавливать пользователей может только администратор")
+	}
+
+	var user *models.User
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		if err := s.userRepo.Restore(ctx, uint(req.Id)); err != nil {
+			return err
+		}
+		var err error
+		user, err = s.userRepo.GetByID(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionUserRestore,
+			TargetID:    &user.ID,
+			TargetEmail: user.Email,
+		})
+		return s.events.UserRestored(ctx, user)
+	})
+	if errors.Is(err, repository.ErrDuplicateEmail) {
+		s.recordMetrics("RestoreUser", "already_exists", time.Since(start))
+		return nil, status.Error(codes.AlreadyExists, "Email пользователя занят другим пользователем")
+	}
+	if err != nil {
+		return nil, s.repositoryError("RestoreUser", start, err, "Ошибка при восстановлении пользователя")
+	}
+
+	// Обновляем счетчик пользователей
+	if s.usersCount != nil {
+		go func() {
+			if count, err := s.userRepo.Count(context.Background()); err == nil {
+				s.usersCount.Set(float64(count))
+			}
+		}()
+	}
+
+	return &pb.UserResponse{
+		User:    s.modelToProto(user),
+		Message: "Пользователь успешно восстановлен",
+	}, nil
+}
+
+// PurgeUser окончательно удаляет удаленного пользователя и его данные, не дожидаясь
+// срока хранения (только для админов). Записи аудита о пользователе сохраняются,
+// сама запись об удалении не содержит его email.
+func (s *UserService) PurgeUser(ctx context.Context, req *pb.PurgeUserRequest) (*pb.Empty, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("PurgeUser", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics("PurgeUser", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Удалять пользователей может только администратор")
+	}
+
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetDeleted(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+		if err := s.userRepo.Purge(ctx, user.ID); err != nil {
+			return err
+		}
+
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionUserPurge,
+			TargetID:    &user.ID,
+			TargetEmail: models.ErasedEmail(user.ID),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, s.repositoryError("PurgeUser", start, err, "Ошибка при окончательном удалении пользователя")
+	}
+
+	return &pb.Empty{}, nil
+}
+
+// DeletedUsersRetention периодически удаляет окончательно пользователей, удаленных
+// раньше срока хранения. Срок можно менять на лету; нулевой срок отключает удаление.
+type DeletedUsersRetention struct {
+	repo   repository.UserRepository
+	log    *slog.Logger
+	period atomic.Int64
+}
+
+// NewDeletedUsersRetention создает задачу удаления пользователей, удаленных раньше period
+func NewDeletedUsersRetention(repo repository.UserRepository, period time.Duration, log *slog.Logger) *DeletedUsersRetention {
+	r := &DeletedUsersRetention{repo: repo, log: logger.Component(log, "users")}
+	r.Update(period)
+	return r
+}
+
+// Update атомарно заменяет срок хранения
+func (r *DeletedUsersRetention) Update(period time.Duration) {
+	r.period.Store(int64(period))
+}
+
+// Run удаляет пользователей с истекшим сроком хранения сразу и затем каждые
+// interval до отмены ctx
+func (r *DeletedUsersRetention) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Cleanup(ctx, time.Now()); err != nil && ctx.Err() == nil {
+			r.log.Warn("Ошибка окончательного удаления пользователей", logger.Err(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Cleanup удаляет окончательно пользователей, удаленных раньше now минус срок хранения
+func (r *DeletedUsersRetention) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	period := time.Duration(r.period.Load())
+	if period <= 0 {
+		return 0, nil
+	}
+
+	purged, err := r.repo.PurgeDeletedBefore(ctx, now.Add(-period))
+	if err != nil {
+		return purged, err
+	}
+	if purged > 0 {
+		r.log.Info("Удаленные пользователи удалены окончательно", "purged", purged, "retention", period.String())
+	}
+	return purged, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// repositoryCode возвращает код gRPC для ошибки репозитория
+func repositoryCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, repository.ErrNotFound):
+		return codes.NotFound
+	case errors.Is(err, repository.ErrDuplicateEmail):
+		return codes.AlreadyExists
+	case errors.Is(err, repository.ErrConflict):
+		return codes.Aborted
+	case errors.Is(err, repository.ErrUnavailable):
+		return codes.Unavailable
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	case errors.Is(err, context.DeadlineExceeded):
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
+
+// repositoryError преобразует ошибку репозитория в статус gRPC и записывает метрику.
+// message описывает внутреннюю ошибку; для остальных кодов используется текст,
+// понятный клиенту, без подробностей из базы данных.
+func (s *UserService) repositoryError(method string, start time.Time, err error, message string) error {
+	code := repositoryCode(err)
+	s.recordMetrics(method, metricStatus(code), time.Since(start))
+
+	switch code {
+	case codes.NotFound:
+		message = "Пользователь не найден"
+	case codes.AlreadyExists:
+		message = "Пользователь с таким email уже существует"
+	case codes.Aborted:
+		message = "Пользователь изменен другим запросом, повторите операцию"
+	case codes.Unavailable:
+		message = "База данных временно недоступна, повторите запрос позже"
+	case codes.Canceled:
+		message = "Запрос отменен"
+	case codes.DeadlineExceeded:
+		message = "Истекло время ожидания ответа базы данных"
+	}
+	return status.Error(code, message)
+}
+
+// metricStatus возвращает значение метки status метрик запросов для кода gRPC
+func metricStatus(code codes.Code) string {
+	switch code {
+	case codes.NotFound:
+		return "not_found"
+	case codes.AlreadyExists:
+		return "already_exists"
+	case codes.Aborted:
+		return "aborted"
+	case codes.Unavailable:
+		return "unavailable"
+	case codes.Canceled:
+		return "canceled"
+	case codes.DeadlineExceeded:
+		return "deadline_exceeded"
+	default:
+		return "internal_error"
+	}
+}
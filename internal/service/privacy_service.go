@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/privacy"
+	"k8s-go-grpc-react/internal/repository"
+	pb "k8s-go-grpc-react/proto"
+)
+
+// errAlreadyErased персональные данные пользователя уже удалены
+var errAlreadyErased = errors.New("персональные данные пользователя уже удалены")
+
+// erasedValue значение поля до удаления в записи аудита: удаленные данные
+// не должны сохраняться в журнале
+const erasedValue = "***"
+
+// ExportMyData выгружает все данные текущего пользователя: профиль, входы,
+// связанные учетные записи и события аудита, в которых он инициатор или цель.
+// Имя файла передается в метаданных content-disposition.
+func (s *UserService) ExportMyData(ctx context.Context, req *pb.ExportMyDataRequest) (*httpbody.HttpBody, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("ExportMyData", "success", time.Since(start))
+	}()
+
+	userID, ok := ctx.Value("user_id").(uint)
+	if !ok || userID == 0 {
+		s.recordMetrics("ExportMyData", "unauthenticated", time.Since(start))
+		return nil, status.Error(codes.Unauthenticated, "Пользователь не аутентифицирован")
+	}
+
+	format := req.Format
+	if format == "" {
+		format = privacy.FormatJSON
+	}
+	if format != privacy.FormatJSON && format != privacy.FormatZIP {
+		s.recordMetrics("ExportMyData", "invalid_argument", time.Since(start))
+		return nil, status.Errorf(codes.InvalidArgument, "Формат выгрузки должен быть %s или %s", privacy.FormatJSON, privacy.FormatZIP)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, s.repositoryError("ExportMyData", start, err, "Ошибка при получении пользователя")
+	}
+	events, err := s.userAuditEvents(ctx, userID)
+	if err != nil {
+		return nil, s.repositoryError("ExportMyData", start, err, "Ошибка при получении журнала аудита")
+	}
+	var authCodes []*models.OAuthAuthorizationCode
+	if s.oauth != nil {
+		if authCodes, err = s.oauth.ListAuthorizationCodes(ctx, userID); err != nil {
+			return nil, s.repositoryError("ExportMyData", start, err, "Ошибка при получении кодов авторизации")
+		}
+	}
+
+	data, contentType, filename, err := privacy.NewExport(user, events, authCodes, time.Now()).Encode(format)
+	if err != nil {
+		s.recordMetrics("ExportMyData", "error", time.Since(start))
+		return nil, status.Error(codes.Internal, "Ошибка при создании выгрузки")
+	}
+
+	s.recordAudit(ctx, &models.AuditEvent{
+		Action:      audit.ActionUserExport,
+		TargetID:    &user.ID,
+		TargetEmail: user.Email,
+	})
+
+	disposition := fmt.Sprintf("attachment; filename=%q", filename)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(privacy.DispositionMetadataKey, disposition))
+	return &httpbody.HttpBody{ContentType: contentType, Data: data}, nil
+}
+
+// userAuditEvents возвращает все события аудита, в которых пользователь инициатор
+// или цель, начиная с новых
+func (s *UserService) userAuditEvents(ctx context.Context, userID uint) ([]*models.AuditEvent, error) {
+	if s.audit == nil {
+		return nil, nil
+	}
+
+	byID := make(map[uint]*models.AuditEvent)
+	for _, filter := range []repository.AuditFilter{{ActorID: userID}, {TargetID: userID}} {
+		filter.Limit = maxAuditPageSize
+		for {
+			page, err := s.audit.List(ctx, filter)
+			if err != nil {
+				return nil, err
+			}
+			for _, event := range page {
+				byID[event.ID] = event
+			}
+			if len(page) < filter.Limit {
+				break
+			}
+			filter.BeforeID = page[len(page)-1].ID
+		}
+	}
+
+	events := make([]*models.AuditEvent, 0, len(byID))
+	for _, event := range byID {
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	return events, nil
+}
+
+// EraseUser удаляет персональные данные пользователя по запросу (право на забвение).
+// Имя и email заменяются заглушками, пароль удаляется, пользователь блокируется;
+// запись пользователя остается, поэтому ссылки на него не нарушаются. События outbox
+// и доставки webhook со снимками пользователя удаляются в той же транзакции, записи
+// аудита сохраняются до истечения срока хранения журнала. Удаление подтверждается записью
+// аудита, ID которой возвращается в ответе, и записью в логе.
+// Доступно администратору и самому пользователю.
+func (s *UserService) EraseUser(ctx context.Context, req *pb.EraseUserRequest) (*pb.EraseUserResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("EraseUser", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		callerID, _ := ctx.Value("user_id").(uint)
+		if callerID == 0 || uint(req.Id) != callerID {
+			s.recordMetrics("EraseUser", "permission_denied", time.Since(start))
+			return nil, status.Error(codes.PermissionDenied, "Удалить можно только свои персональные данные")
+		}
+	}
+
+	id := uint(req.Id)
+	now := time.Now().UTC()
+	var event *models.AuditEvent
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			user, err = s.userRepo.GetDeleted(ctx, id)
+		}
+		if err != nil {
+			return err
+		}
+		if user.Erased() {
+			return errAlreadyErased
+		}
+		if err := s.userRepo.Erase(ctx, id, now); err != nil {
+			return err
+		}
+
+		event = &models.AuditEvent{
+			Action:      audit.ActionUserErase,
+			TargetID:    &user.ID,
+			TargetEmail: models.ErasedEmail(id),
+		}
+		// При удалении по запросу самого пользователя его email не должен попасть
+		// в журнал как email инициатора
+		if callerID, _ := ctx.Value("user_id").(uint); callerID == id {
+			event.ActorID, event.ActorEmail = &user.ID, models.ErasedEmail(id)
+		}
+		event.SetChanges([]models.AuditChange{
+			{Field: "name", Old: erasedValue, New: models.ErasedName},
+			{Field: "email", Old: erasedValue, New: models.ErasedEmail(id)},
+		})
+		s.recordAudit(ctx, event)
+		return s.events.UserErased(ctx, id)
+	})
+	if errors.Is(err, errAlreadyErased) {
+		s.recordMetrics("EraseUser", "failed_precondition", time.Since(start))
+		return nil, status.Error(codes.FailedPrecondition, "Персональные данные пользователя уже удалены")
+	}
+	if err != nil {
+		return nil, s.repositoryError("EraseUser", start, err, "Ошибка при удалении персональных данных")
+	}
+
+	s.log.Info("Персональные данные пользователя удалены",
+		"user_id", id,
+		"audit_event_id", event.ID,
+		"erased_at", now.Format(time.RFC3339),
+	)
+	return &pb.EraseUserResponse{
+		Id:           req.Id,
+		ErasedAt:     now.Unix(),
+		AuditEventId: int64(event.ID),
+	}, nil
+}
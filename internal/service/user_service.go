@@ -2,13 +2,21 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"k8s-go-grpc-react/internal/audit"
 	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/etag"
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/logger"
 	"k8s-go-grpc-react/internal/models"
 	"k8s-go-grpc-react/internal/repository"
 	pb "k8s-go-grpc-react/proto"
@@ -22,21 +30,42 @@ type UserService struct {
 	requestsTotal   *prometheus.CounterVec
 	requestDuration *prometheus.HistogramVec
 	usersCount      prometheus.Gauge
+	audit           *audit.Recorder
+	tx              repository.TxManager
+	events          *events.Outbox
+	webhooks        repository.WebhookRepository
+	webhooksPrivate bool // Подписки на адреса внутренней сети разрешены
+	oauth           repository.OAuthRepository
+	log             *slog.Logger
 }
 
+// errETagMismatch версия пользователя не совпадает с etag запроса
+var errETagMismatch = errors.New("версия пользователя устарела")
+
+// auditRoles роли, которым доступен журнал аудита
+var auditRoles = []string{"admin", "auditor"}
+
+const (
+	// defaultAuditPageSize размер страницы журнала аудита по умолчанию
+	defaultAuditPageSize = 50
+	// maxAuditPageSize наибольший размер страницы журнала аудита
+	maxAuditPageSize = 500
+)
+
 // NewUserService создает новый экземпляр UserService без метрик
-func NewUserService(userRepo repository.UserRepository) *UserService {
-	return NewUserServiceWithMetrics(userRepo, nil, nil, nil)
+func NewUserService(userRepo repository.UserRepository, jwtService auth.JWTService) *UserService {
+	return NewUserServiceWithMetrics(userRepo, jwtService, nil, nil, nil)
 }
 
 // NewUserServiceWithMetrics создает новый экземпляр UserService с метриками
-func NewUserServiceWithMetrics(userRepo repository.UserRepository, requestsTotal *prometheus.CounterVec, requestDuration *prometheus.HistogramVec, usersCount prometheus.Gauge) *UserService {
+func NewUserServiceWithMetrics(userRepo repository.UserRepository, jwtService auth.JWTService, requestsTotal *prometheus.CounterVec, requestDuration *prometheus.HistogramVec, usersCount prometheus.Gauge) *UserService {
 	service := &UserService{
 		userRepo:        userRepo,
-		jwtService:      auth.NewJWTService(),
+		jwtService:      jwtService,
 		requestsTotal:   requestsTotal,
 		requestDuration: requestDuration,
 		usersCount:      usersCount,
+		log:             logger.Discard(),
 	}
 
 	// Обновляем счетчик пользователей
@@ -51,6 +80,61 @@ func NewUserServiceWithMetrics(userRepo repository.UserRepository, requestsTotal
 	return service
 }
 
+// WithAudit включает запись событий в журнал аудита и API чтения журнала
+func (s *UserService) WithAudit(recorder *audit.Recorder) *UserService {
+	s.audit = recorder
+	return s
+}
+
+// WithTx включает выполнение изменений пользователя вместе с записью аудита в одной транзакции
+func (s *UserService) WithTx(tx repository.TxManager) *UserService {
+	s.tx = tx
+	return s
+}
+
+// WithEvents включает запись событий пользователей для публикации другим сервисам.
+// Событие сохраняется в транзакции изменения, поэтому вместе с WithEvents нужен WithTx.
+func (s *UserService) WithEvents(outbox *events.Outbox) *UserService {
+	s.events = outbox
+	return s
+}
+
+// WithWebhooks включает методы управления подписками на webhook. Подписки на
+// адреса внутренней сети принимаются, только если задан allowPrivateNetworks.
+func (s *UserService) WithWebhooks(repo repository.WebhookRepository, allowPrivateNetworks bool) *UserService {
+	s.webhooks = repo
+	s.webhooksPrivate = allowPrivateNetworks
+	return s
+}
+
+// WithOAuth включает в выгрузку данных пользователя его коды авторизации OIDC
+func (s *UserService) WithOAuth(repo repository.OAuthRepository) *UserService {
+	s.oauth = repo
+	return s
+}
+
+// WithLogger задает логер для событий, которые нужно подтверждать записью в логе
+func (s *UserService) WithLogger(log *slog.Logger) *UserService {
+	s.log = log
+	return s
+}
+
+// withinTx выполняет fn в транзакции, если менеджер транзакций задан. fn может
+// выполниться повторно, поэтому объекты для записи создаются внутри нее.
+func (s *UserService) withinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.tx == nil {
+		return fn(ctx)
+	}
+	return s.tx.WithinTx(ctx, fn)
+}
+
+// recordAudit записывает событие в журнал аудита, если он включен
+func (s *UserService) recordAudit(ctx context.Context, event *models.AuditEvent) {
+	if s.audit != nil {
+		s.audit.Record(ctx, event)
+	}
+}
+
 // recordMetrics записывает метрики для запроса
 func (s *UserService) recordMetrics(method string, status string, duration time.Duration) {
 	if s.requestsTotal != nil {
@@ -70,6 +154,7 @@ func (s *UserService) modelToProto(user *models.User) *pb.User {
 		Role:      user.Role,
 		IsActive:  user.IsActive,
 		CreatedAt: user.CreatedAt.Unix(),
+		Etag:      user.ETag(),
 	}
 }
 
@@ -85,7 +170,8 @@ func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 		return nil, status.Error(codes.InvalidArgument, "Имя пользователя не может быть пустым")
 	}
 
-	if req.Email == "" {
+	email := models.NormalizeEmail(req.Email)
+	if email == "" {
 		s.recordMetrics("Register", "invalid_argument", time.Since(start))
 		return nil, status.Error(codes.InvalidArgument, "Email не может быть пустым")
 	}
@@ -95,13 +181,6 @@ func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 		return nil, status.Error(codes.InvalidArgument, "Пароль не может быть пустым")
 	}
 
-	// Проверяем, существует ли пользователь с таким email
-	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
-	if err == nil && existingUser != nil {
-		s.recordMetrics("Register", "already_exists", time.Since(start))
-		return nil, status.Error(codes.AlreadyExists, "Пользователь с таким email уже существует")
-	}
-
 	// Хешируем пароль
 	hashedPassword, err := s.jwtService.HashPassword(req.Password)
 	if err != nil {
@@ -109,18 +188,37 @@ func (s *UserService) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 		return nil, status.Error(codes.Internal, "Ошибка при хешировании пароля")
 	}
 
-	// Создаем нового пользователя
-	newUser := &models.User{
-		Name:         req.Name,
-		Email:        req.Email,
-		PasswordHash: hashedPassword,
-		Role:         "user", // По умолчанию роль пользователя
-		IsActive:     true,
-	}
-
-	if err := s.userRepo.Create(ctx, newUser); err != nil {
-		s.recordMetrics("Register", "internal_error", time.Since(start))
-		return nil, status.Error(codes.Internal, "Ошибка при создании пользователя")
+	var newUser *models.User
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		// Создаем нового пользователя
+		newUser = &models.User{
+			Name:         req.Name,
+			Email:        email,
+			PasswordHash: hashedPassword,
+			Role:         "user", // По умолчанию роль пользователя
+			IsActive:     true,
+		}
+
+		// Уникальность email проверяет индекс базы данных: параллельные запросы с одним
+		// email не проходят оба, и занятый email возвращается как AlreadyExists
+		if err := s.userRepo.Create(ctx, newUser); err != nil {
+			return err
+		}
+
+		// Пользователь регистрируется сам, поэтому он же инициатор события
+		event := &models.AuditEvent{
+			Action:      audit.ActionUserRegister,
+			ActorID:     &newUser.ID,
+			ActorEmail:  newUser.Email,
+			TargetID:    &newUser.ID,
+			TargetEmail: newUser.Email,
+		}
+		event.SetChanges(audit.UserChanges(nil, newUser))
+		s.recordAudit(ctx, event)
+		return s.events.UserCreated(ctx, newUser)
+	})
+	if err != nil {
+		return nil, s.repositoryError("Register", start, err, "Ошибка при создании пользователя")
 	}
 
 	// Генерируем JWT токен
@@ -153,7 +251,8 @@ func (s *UserService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Auth
 		s.recordMetrics("Login", "success", time.Since(start))
 	}()
 
-	if req.Email == "" {
+	email := models.NormalizeEmail(req.Email)
+	if email == "" {
 		s.recordMetrics("Login", "invalid_argument", time.Since(start))
 		return nil, status.Error(codes.InvalidArgument, "Email не может быть пустым")
 	}
@@ -164,20 +263,40 @@ func (s *UserService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Auth
 	}
 
 	// Ищем пользователя по email
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, s.repositoryError("Login", start, err, "Ошибка при поиске пользователя")
+	}
 	if err != nil {
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionLoginFailed,
+			TargetEmail: email,
+			Reason:      audit.ReasonUserNotFound,
+		})
 		s.recordMetrics("Login", "not_found", time.Since(start))
 		return nil, status.Error(codes.NotFound, "Неверный email или пароль")
 	}
 
 	// Проверяем активность пользователя
 	if !user.IsActive {
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionLoginFailed,
+			TargetID:    &user.ID,
+			TargetEmail: user.Email,
+			Reason:      audit.ReasonInactive,
+		})
 		s.recordMetrics("Login", "permission_denied", time.Since(start))
 		return nil, status.Error(codes.PermissionDenied, "Аккаунт заблокирован")
 	}
 
 	// Проверяем пароль
 	if err := s.jwtService.CheckPassword(user.PasswordHash, req.Password); err != nil {
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionLoginFailed,
+			TargetID:    &user.ID,
+			TargetEmail: user.Email,
+			Reason:      audit.ReasonInvalidPassword,
+		})
 		s.recordMetrics("Login", "unauthenticated", time.Since(start))
 		return nil, status.Error(codes.Unauthenticated, "Неверный email или пароль")
 	}
@@ -189,6 +308,14 @@ func (s *UserService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.Auth
 		return nil, status.Error(codes.Internal, "Ошибка при генерации токена")
 	}
 
+	s.recordAudit(ctx, &models.AuditEvent{
+		Action:      audit.ActionLogin,
+		ActorID:     &user.ID,
+		ActorEmail:  user.Email,
+		TargetID:    &user.ID,
+		TargetEmail: user.Email,
+	})
+
 	return &pb.AuthResponse{
 		Token:   token,
 		User:    s.modelToProto(user),
@@ -205,10 +332,10 @@ func (s *UserService) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.
 
 	user, err := s.userRepo.GetByID(ctx, uint(req.Id))
 	if err != nil {
-		s.recordMetrics("GetUser", "not_found", time.Since(start))
-		return nil, status.Error(codes.NotFound, "Пользователь не найден")
+		return nil, s.repositoryError("GetUser", start, err, "Ошибка при получении пользователя")
 	}
 
+	etag.SetHeader(ctx, user.ETag())
 	return &pb.UserResponse{
 		User:    s.modelToProto(user),
 		Message: "Пользователь успешно найден",
@@ -227,7 +354,8 @@ func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		return nil, status.Error(codes.InvalidArgument, "Имя пользователя не может быть пустым")
 	}
 
-	if req.Email == "" {
+	email := models.NormalizeEmail(req.Email)
+	if email == "" {
 		s.recordMetrics("CreateUser", "invalid_argument", time.Since(start))
 		return nil, status.Error(codes.InvalidArgument, "Email не может быть пустым")
 	}
@@ -237,13 +365,6 @@ func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		return nil, status.Error(codes.InvalidArgument, "Пароль не может быть пустым")
 	}
 
-	// Проверяем, существует ли пользователь с таким email
-	existingUser, err := s.userRepo.GetByEmail(ctx, req.Email)
-	if err == nil && existingUser != nil {
-		s.recordMetrics("CreateUser", "already_exists", time.Since(start))
-		return nil, status.Error(codes.AlreadyExists, "Пользователь с таким email уже существует")
-	}
-
 	// Хешируем пароль
 	hashedPassword, err := s.jwtService.HashPassword(req.Password)
 	if err != nil {
@@ -257,18 +378,34 @@ func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		role = "user"
 	}
 
-	// Создаем нового пользователя
-	newUser := &models.User{
-		Name:         req.Name,
-		Email:        req.Email,
-		PasswordHash: hashedPassword,
-		Role:         role,
-		IsActive:     true,
-	}
-
-	if err := s.userRepo.Create(ctx, newUser); err != nil {
-		s.recordMetrics("CreateUser", "internal_error", time.Since(start))
-		return nil, status.Error(codes.Internal, "Ошибка при создании пользователя")
+	var newUser *models.User
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		// Создаем нового пользователя
+		newUser = &models.User{
+			Name:         req.Name,
+			Email:        email,
+			PasswordHash: hashedPassword,
+			Role:         role,
+			IsActive:     true,
+		}
+
+		// Уникальность email проверяет индекс базы данных: параллельные запросы с одним
+		// email не проходят оба, и занятый email возвращается как AlreadyExists
+		if err := s.userRepo.Create(ctx, newUser); err != nil {
+			return err
+		}
+
+		event := &models.AuditEvent{
+			Action:      audit.ActionUserCreate,
+			TargetID:    &newUser.ID,
+			TargetEmail: newUser.Email,
+		}
+		event.SetChanges(audit.UserChanges(nil, newUser))
+		s.recordAudit(ctx, event)
+		return s.events.UserCreated(ctx, newUser)
+	})
+	if err != nil {
+		return nil, s.repositoryError("CreateUser", start, err, "Ошибка при создании пользователя")
 	}
 
 	// Обновляем счетчик пользователей
@@ -280,12 +417,167 @@ func (s *UserService) CreateUser(ctx context.Context, req *pb.CreateUserRequest)
 		}()
 	}
 
+	etag.SetHeader(ctx, newUser.ETag())
 	return &pb.UserResponse{
 		User:    s.modelToProto(newUser),
 		Message: "Пользователь успешно создан",
 	}, nil
 }
 
+// UpdateUser изменяет пользователя. Администратор меняет любые поля любого пользователя,
+// остальные - только имя и email своей учетной записи. Если задан etag (в поле запроса
+// или заголовке If-Match) и пользователь с тех пор изменился, запрос отклоняется.
+func (s *UserService) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.UserResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("UpdateUser", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		callerID, _ := ctx.Value("user_id").(uint)
+		if callerID == 0 || uint(req.Id) != callerID {
+			s.recordMetrics("UpdateUser", "permission_denied", time.Since(start))
+			return nil, status.Error(codes.PermissionDenied, "Изменять можно только свою учетную запись")
+		}
+		if req.Role != nil || req.IsActive != nil {
+			s.recordMetrics("UpdateUser", "permission_denied", time.Since(start))
+			return nil, status.Error(codes.PermissionDenied, "Роль и активность меняет только администратор")
+		}
+	}
+
+	if req.Name != nil && *req.Name == "" {
+		s.recordMetrics("UpdateUser", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "Имя пользователя не может быть пустым")
+	}
+	if req.Email != nil && models.NormalizeEmail(*req.Email) == "" {
+		s.recordMetrics("UpdateUser", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "Email не может быть пустым")
+	}
+	if req.Role != nil && *req.Role == "" {
+		s.recordMetrics("UpdateUser", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "Роль не может быть пустой")
+	}
+
+	condition := req.Etag
+	if condition == "" {
+		condition = etag.IfMatch(ctx)
+	}
+
+	var user *models.User
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		user, err = s.userRepo.GetByID(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+		if condition != "" && !etag.Matches(condition, user.ETag()) {
+			return errETagMismatch
+		}
+		if user.Erased() {
+			return errAlreadyErased
+		}
+
+		before := *user
+		if req.Name != nil {
+			user.Name = *req.Name
+		}
+		if req.Email != nil {
+			user.Email = models.NormalizeEmail(*req.Email)
+		}
+		if req.Role != nil {
+			user.Role = *req.Role
+		}
+		if req.IsActive != nil {
+			user.IsActive = *req.IsActive
+		}
+
+		changes := audit.UserChanges(&before, user)
+		if len(changes) == 0 {
+			return nil
+		}
+		// Версия проверяется еще раз при записи: пользователь мог измениться после чтения
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			if condition != "" && errors.Is(err, repository.ErrConflict) {
+				return errETagMismatch
+			}
+			return err
+		}
+
+		event := &models.AuditEvent{
+			Action:      audit.UserUpdateAction(changes),
+			TargetID:    &user.ID,
+			TargetEmail: user.Email,
+		}
+		event.SetChanges(changes)
+		s.recordAudit(ctx, event)
+		return s.events.UserUpdated(ctx, user, changes)
+	})
+	if errors.Is(err, errETagMismatch) {
+		s.recordMetrics("UpdateUser", "failed_precondition", time.Since(start))
+		return nil, userETagMismatch(uint(req.Id))
+	}
+	if errors.Is(err, errAlreadyErased) {
+		s.recordMetrics("UpdateUser", "failed_precondition", time.Since(start))
+		return nil, status.Error(codes.FailedPrecondition, "Персональные данные пользователя удалены, изменение невозможно")
+	}
+	if err != nil {
+		return nil, s.repositoryError("UpdateUser", start, err, "Ошибка при обновлении пользователя")
+	}
+
+	etag.SetHeader(ctx, user.ETag())
+	return &pb.UserResponse{
+		User:    s.modelToProto(user),
+		Message: "Пользователь успешно обновлен",
+	}, nil
+}
+
+// DeleteUser удаляет пользователя (только для админов). Запись остается в базе
+// данных с отметкой удаления, email освобождается для новой регистрации.
+// Пользователя можно восстановить методом RestoreUser, пока он не удален
+// окончательно (PurgeUser или DeletedUsersRetention).
+func (s *UserService) DeleteUser(ctx context.Context, req *pb.DeleteUserRequest) (*pb.Empty, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("DeleteUser", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics("DeleteUser", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Удалять пользователей может только администратор")
+	}
+
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		user, err := s.userRepo.GetByID(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+		if err := s.userRepo.Delete(ctx, user.ID); err != nil {
+			return err
+		}
+
+		s.recordAudit(ctx, &models.AuditEvent{
+			Action:      audit.ActionUserDelete,
+			TargetID:    &user.ID,
+			TargetEmail: user.Email,
+		})
+		return s.events.UserDeleted(ctx, user.ID)
+	})
+	if err != nil {
+		return nil, s.repositoryError("DeleteUser", start, err, "Ошибка при удалении пользователя")
+	}
+
+	// Обновляем счетчик пользователей
+	if s.usersCount != nil {
+		go func() {
+			if count, err := s.userRepo.Count(context.Background()); err == nil {
+				s.usersCount.Set(float64(count))
+			}
+		}()
+	}
+
+	return &pb.Empty{}, nil
+}
+
 // ListUsers возвращает список всех пользователей
 func (s *UserService) ListUsers(ctx context.Context, req *pb.Empty) (*pb.UserListResponse, error) {
 	start := time.Now()
@@ -295,8 +587,7 @@ func (s *UserService) ListUsers(ctx context.Context, req *pb.Empty) (*pb.UserLis
 
 	users, err := s.userRepo.List(ctx, 0, 0) // Без ограничений
 	if err != nil {
-		s.recordMetrics("ListUsers", "internal_error", time.Since(start))
-		return nil, status.Error(codes.Internal, "Ошибка при получении списка пользователей")
+		return nil, s.repositoryError("ListUsers", start, err, "Ошибка при получении списка пользователей")
 	}
 
 	protoUsers := make([]*pb.User, len(users))
@@ -309,3 +600,155 @@ func (s *UserService) ListUsers(ctx context.Context, req *pb.Empty) (*pb.UserLis
 		Total: int32(len(protoUsers)),
 	}, nil
 }
+
+// ListAuditEvents возвращает события журнала аудита (только для админов и аудиторов)
+func (s *UserService) ListAuditEvents(ctx context.Context, req *pb.ListAuditEventsRequest) (*pb.ListAuditEventsResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("ListAuditEvents", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, auditRoles...) {
+		s.recordMetrics("ListAuditEvents", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Журнал аудита доступен только администраторам и аудиторам")
+	}
+
+	if s.audit == nil {
+		s.recordMetrics("ListAuditEvents", "unimplemented", time.Since(start))
+		return nil, status.Error(codes.Unimplemented, "Журнал аудита не включен")
+	}
+
+	if req.PageSize < 0 || req.ActorId < 0 || req.TargetId < 0 {
+		s.recordMetrics("ListAuditEvents", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "page_size, actor_id и target_id не могут быть отрицательными")
+	}
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize == 0:
+		pageSize = defaultAuditPageSize
+	case pageSize > maxAuditPageSize:
+		pageSize = maxAuditPageSize
+	}
+
+	filter := repository.AuditFilter{
+		Action:   req.Action,
+		ActorID:  uint(req.ActorId),
+		TargetID: uint(req.TargetId),
+		Limit:    pageSize + 1, // Лишняя запись показывает, что есть следующая страница
+	}
+	if req.Since > 0 {
+		filter.Since = time.Unix(req.Since, 0)
+	}
+	if req.Until > 0 {
+		filter.Until = time.Unix(req.Until, 0)
+	}
+	if req.PageToken != "" {
+		beforeID, err := strconv.ParseUint(req.PageToken, 10, 64)
+		if err != nil || beforeID == 0 {
+			s.recordMetrics("ListAuditEvents", "invalid_argument", time.Since(start))
+			return nil, status.Error(codes.InvalidArgument, "Неверный page_token")
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	events, err := s.audit.List(ctx, filter)
+	if err != nil {
+		s.recordMetrics("ListAuditEvents", "internal_error", time.Since(start))
+		return nil, status.Error(codes.Internal, "Ошибка при получении журнала аудита")
+	}
+
+	resp := &pb.ListAuditEventsResponse{}
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(uint64(events[pageSize-1].ID), 10)
+	}
+	resp.Events = make([]*pb.AuditEvent, len(events))
+	for i, event := range events {
+		resp.Events[i] = auditEventToProto(event)
+	}
+	return resp, nil
+}
+
+// VerifyAuditChain проверяет целостность цепочки журнала аудита (только для админов)
+func (s *UserService) VerifyAuditChain(ctx context.Context, req *pb.VerifyAuditChainRequest) (*pb.VerifyAuditChainResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("VerifyAuditChain", "success", time.Since(start))
+	}()
+
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics("VerifyAuditChain", "permission_denied", time.Since(start))
+		return nil, status.Error(codes.PermissionDenied, "Проверка журнала аудита доступна только администраторам")
+	}
+
+	if s.audit == nil {
+		s.recordMetrics("VerifyAuditChain", "unimplemented", time.Since(start))
+		return nil, status.Error(codes.Unimplemented, "Журнал аудита не включен")
+	}
+
+	result, err := s.audit.Verify(ctx)
+	if err != nil {
+		s.recordMetrics("VerifyAuditChain", "internal_error", time.Since(start))
+		return nil, status.Error(codes.Internal, "Ошибка при проверке журнала аудита")
+	}
+
+	return &pb.VerifyAuditChainResponse{
+		Valid:              result.Valid,
+		EventsChecked:      result.EventsChecked,
+		CheckpointsChecked: int32(result.CheckpointsChecked),
+		FirstEventId:       int64(result.FirstEventID),
+		LastEventId:        int64(result.LastEventID),
+		BrokenEventId:      int64(result.BrokenEventID),
+		Reason:             result.Reason,
+		SignaturesVerified: result.SignaturesVerified,
+	}, nil
+}
+
+// auditEventToProto конвертирует событие аудита в protobuf
+func auditEventToProto(event *models.AuditEvent) *pb.AuditEvent {
+	result := &pb.AuditEvent{
+		Id:          int64(event.ID),
+		CreatedAt:   event.CreatedAt.Unix(),
+		Action:      event.Action,
+		ActorEmail:  event.ActorEmail,
+		TargetEmail: event.TargetEmail,
+		Ip:          event.IP,
+		UserAgent:   event.UserAgent,
+		RequestId:   event.RequestID,
+		Reason:      event.Reason,
+	}
+	if event.ActorID != nil {
+		result.ActorId = int32(*event.ActorID)
+	}
+	if event.TargetID != nil {
+		result.TargetId = int32(*event.TargetID)
+	}
+	for _, change := range event.ChangeList() {
+		result.Changes = append(result.Changes, &pb.AuditChange{
+			Field:    change.Field,
+			OldValue: change.Old,
+			NewValue: change.New,
+		})
+	}
+	return result
+}
+
+// userETagMismatch возвращает ошибку устаревшей версии пользователя
+func userETagMismatch(id uint) error {
+	return etag.Mismatch(fmt.Sprintf("users/%d", id), "Пользователь изменен после получения, загрузите его заново")
+}
+
+// hasRole проверяет, что роль аутентифицированного пользователя входит в roles
+func hasRole(ctx context.Context, roles ...string) bool {
+	role, ok := ctx.Value("user_role").(string)
+	if !ok {
+		return false
+	}
+	for _, allowed := range roles {
+		if role == allowed {
+			return true
+		}
+	}
+	return false
+}
@@ -1,14 +1,33 @@
 package service
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
-
+	"time"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/auth"
+	"k8s-go-grpc-react/internal/database"
+	"k8s-go-grpc-react/internal/etag"
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/logger"
 	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/privacy"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/webhook"
 	pb "k8s-go-grpc-react/proto"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // MockUserRepository - мок репозитория для тестирования
@@ -60,10 +79,119 @@ func (m *MockUserRepository) Count(ctx context.Context) (int64, error) {
 	return args.Get(0).(int64), args.Error(1)
 }
 
+func (m *MockUserRepository) ListDeleted(ctx context.Context, limit, offset int) ([]*models.User, error) {
+	args := m.Called(ctx, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetDeleted(ctx context.Context, id uint) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserRepository) Restore(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Purge(ctx context.Context, id uint) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Erase(ctx context.Context, id uint, now time.Time) error {
+	args := m.Called(ctx, id, now)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) PurgeDeletedBefore(ctx context.Context, before time.Time) (int64, error) {
+	args := m.Called(ctx, before)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// MockAuditRepository - мок журнала аудита для тестирования
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	args := m.Called(ctx, event)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditRepository) DeleteBefore(ctx context.Context, before time.Time, key []byte) (int64, error) {
+	args := m.Called(ctx, before, key)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockAuditRepository) Anchor(ctx context.Context) (*models.AuditRetentionAnchor, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditRetentionAnchor), args.Error(1)
+}
+
+func (m *MockAuditRepository) Scan(ctx context.Context, afterID uint, limit int) ([]*models.AuditEvent, error) {
+	args := m.Called(ctx, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditEvent), args.Error(1)
+}
+
+func (m *MockAuditRepository) Head(ctx context.Context) (*models.AuditChainHead, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditChainHead), args.Error(1)
+}
+
+func (m *MockAuditRepository) CreateCheckpoint(ctx context.Context, checkpoint *models.AuditCheckpoint) error {
+	args := m.Called(ctx, checkpoint)
+	return args.Error(0)
+}
+
+func (m *MockAuditRepository) LastCheckpoint(ctx context.Context) (*models.AuditCheckpoint, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AuditCheckpoint), args.Error(1)
+}
+
+func (m *MockAuditRepository) ListCheckpoints(ctx context.Context) ([]*models.AuditCheckpoint, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.AuditCheckpoint), args.Error(1)
+}
+
+// newTestJWTService создает JWT сервис для тестов
+func newTestJWTService() auth.JWTService {
+	return auth.NewJWTService("test-secret", time.Hour)
+}
+
 func TestUserService_CreateUser(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 	req := &pb.CreateUserRequest{
@@ -73,9 +201,6 @@ func TestUserService_CreateUser(t *testing.T) {
 		Role:     "user",
 	}
 
-	// Настраиваем мок - пользователь с таким email не существует
-	mockRepo.On("GetByEmail", ctx, req.Email).Return(nil, assert.AnError)
-
 	// Настраиваем мок для создания пользователя
 	mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil)
 
@@ -97,7 +222,7 @@ func TestUserService_CreateUser(t *testing.T) {
 func TestUserService_GetUser(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 	userID := uint(1)
@@ -132,7 +257,7 @@ func TestUserService_GetUser(t *testing.T) {
 func TestUserService_ListUsers(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 	expectedUsers := []*models.User{
@@ -179,7 +304,7 @@ func TestUserService_ListUsers(t *testing.T) {
 func TestUserService_CreateUser_InvalidInput(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 
@@ -232,7 +357,7 @@ func TestUserService_CreateUser_InvalidInput(t *testing.T) {
 func TestUserService_CreateUser_DuplicateEmail(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 	email := "test@example.com"
@@ -245,14 +370,9 @@ func TestUserService_CreateUser_DuplicateEmail(t *testing.T) {
 		Role:     "admin",
 	}
 
-	// Настраиваем мок - пользователь с таким email уже существует
-	existingUser := &models.User{
-		ID:    1,
-		Name:  "User 1",
-		Email: email,
-		Role:  "user",
-	}
-	mockRepo.On("GetByEmail", ctx, email).Return(existingUser, nil)
+	// Настраиваем мок - email занят, уникальный индекс отклоняет запись
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).
+		Return(fmt.Errorf("%w: %s", repository.ErrDuplicateEmail, email))
 
 	// Act
 	resp, err := service.CreateUser(ctx, req)
@@ -260,6 +380,7 @@ func TestUserService_CreateUser_DuplicateEmail(t *testing.T) {
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, resp)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
 	assert.Contains(t, err.Error(), "Пользователь с таким email уже существует")
 
 	mockRepo.AssertExpectations(t)
@@ -268,13 +389,13 @@ func TestUserService_CreateUser_DuplicateEmail(t *testing.T) {
 func TestUserService_GetUser_NotFound(t *testing.T) {
 	// Arrange
 	mockRepo := new(MockUserRepository)
-	service := NewUserService(mockRepo)
+	service := NewUserService(mockRepo, newTestJWTService())
 
 	ctx := context.Background()
 	userID := uint(999)
 
 	// Настраиваем мок - пользователь не найден
-	mockRepo.On("GetByID", ctx, userID).Return(nil, assert.AnError)
+	mockRepo.On("GetByID", ctx, userID).Return(nil, fmt.Errorf("%w: пользователь с ID %d", repository.ErrNotFound, userID))
 
 	req := &pb.GetUserRequest{Id: int32(userID)}
 
@@ -284,7 +405,577 @@ func TestUserService_GetUser_NotFound(t *testing.T) {
 	// Assert
 	assert.Error(t, err)
 	assert.Nil(t, resp)
+	assert.Equal(t, codes.NotFound, status.Code(err))
 	assert.Contains(t, err.Error(), "Пользователь не найден")
 
 	mockRepo.AssertExpectations(t)
 }
+
+func TestUserService_RepositoryErrors(t *testing.T) {
+	ctx := context.Background()
+	outage := fmt.Errorf("ошибка при получении пользователя: %w", repository.ErrUnavailable)
+
+	t.Run("недоступная база данных при получении пользователя", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, newTestJWTService())
+		mockRepo.On("GetByID", ctx, uint(1)).Return(nil, outage)
+
+		_, err := service.GetUser(ctx, &pb.GetUserRequest{Id: 1})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+		assert.NotContains(t, err.Error(), "не найден")
+	})
+
+	t.Run("недоступная база данных при входе", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo, newTestJWTService())
+		mockRepo.On("GetByEmail", ctx, "user@example.com").Return(nil, outage)
+
+		_, err := service.Login(ctx, &pb.LoginRequest{Email: "user@example.com", Password: "password123"})
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	})
+}
+
+func TestUserService_Register_ConcurrentSameEmail(t *testing.T) {
+	service := NewUserService(repository.NewMemoryUserRepository(nil), newTestJWTService())
+	ctx := context.Background()
+	const workers = 10
+
+	var wg sync.WaitGroup
+	codesCh := make(chan codes.Code, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			email := "bob@example.com"
+			if n%2 == 1 {
+				email = "Bob@Example.com"
+			}
+			_, err := service.Register(ctx, &pb.RegisterRequest{Name: "Bob", Email: email, Password: "password123"})
+			codesCh <- status.Code(err)
+		}(i)
+	}
+	wg.Wait()
+	close(codesCh)
+
+	counts := make(map[codes.Code]int)
+	for code := range codesCh {
+		counts[code]++
+	}
+	assert.Equal(t, map[codes.Code]int{codes.OK: 1, codes.AlreadyExists: workers - 1}, counts)
+}
+
+func TestUserService_Login_RecordsFailedAttempt(t *testing.T) {
+	// Arrange
+	mockRepo := new(MockUserRepository)
+	auditRepo := new(MockAuditRepository)
+	jwtService := newTestJWTService()
+	service := NewUserService(mockRepo, jwtService).WithAudit(audit.NewRecorder(auditRepo, nil, logger.Discard()))
+
+	ctx := context.Background()
+	hash, err := jwtService.HashPassword("password123")
+	require.NoError(t, err)
+	user := &models.User{ID: 3, Email: "test@example.com", PasswordHash: hash, Role: "user", IsActive: true}
+	mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil)
+
+	var recorded *models.AuditEvent
+	auditRepo.On("Create", ctx, mock.AnythingOfType("*models.AuditEvent")).Run(func(args mock.Arguments) {
+		recorded = args.Get(1).(*models.AuditEvent)
+	}).Return(nil)
+
+	// Act
+	resp, err := service.Login(ctx, &pb.LoginRequest{Email: user.Email, Password: "wrong"})
+
+	// Assert
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	require.NotNil(t, recorded)
+	assert.Equal(t, audit.ActionLoginFailed, recorded.Action)
+	assert.Equal(t, audit.ReasonInvalidPassword, recorded.Reason)
+	assert.Nil(t, recorded.ActorID)
+	require.NotNil(t, recorded.TargetID)
+	assert.Equal(t, user.ID, *recorded.TargetID)
+}
+
+func TestUserService_ListAuditEvents(t *testing.T) {
+	// Arrange
+	auditRepo := new(MockAuditRepository)
+	service := NewUserService(new(MockUserRepository), newTestJWTService()).
+		WithAudit(audit.NewRecorder(auditRepo, nil, logger.Discard()))
+
+	userCtx := context.WithValue(context.Background(), "user_role", "user")
+	auditorCtx := context.WithValue(context.Background(), "user_role", "auditor")
+
+	actorID := uint(1)
+	events := []*models.AuditEvent{
+		{ID: 12, Action: audit.ActionUserCreate, ActorID: &actorID, Changes: `[{"field":"role","old":"","new":"user"}]`},
+		{ID: 11, Action: audit.ActionLogin},
+		{ID: 10, Action: audit.ActionLogin},
+	}
+	auditRepo.On("List", auditorCtx, repository.AuditFilter{Limit: 3, BeforeID: 20}).Return(events, nil)
+
+	// Act
+	_, deniedErr := service.ListAuditEvents(userCtx, &pb.ListAuditEventsRequest{})
+	resp, err := service.ListAuditEvents(auditorCtx, &pb.ListAuditEventsRequest{PageSize: 2, PageToken: "20"})
+	_, tokenErr := service.ListAuditEvents(auditorCtx, &pb.ListAuditEventsRequest{PageToken: "abc"})
+
+	// Assert
+	assert.Equal(t, codes.PermissionDenied, status.Code(deniedErr))
+	assert.Equal(t, codes.InvalidArgument, status.Code(tokenErr))
+	require.NoError(t, err)
+	require.Len(t, resp.Events, 2)
+	assert.Equal(t, "11", resp.NextPageToken)
+	assert.Equal(t, int32(1), resp.Events[0].ActorId)
+	require.Len(t, resp.Events[0].Changes, 1)
+	assert.Equal(t, "user", resp.Events[0].Changes[0].NewValue)
+
+	auditRepo.AssertExpectations(t)
+}
+
+func TestUserService_UpdateUser(t *testing.T) {
+	repo := repository.NewMemoryUserRepository(nil)
+	auditRepo := new(MockAuditRepository)
+	service := NewUserService(repo, newTestJWTService()).WithAudit(audit.NewRecorder(auditRepo, nil, logger.Discard()))
+
+	user := &models.User{Name: "Ann", Email: "ann@example.com", PasswordHash: "hash", Role: "user", IsActive: true}
+	require.NoError(t, repo.Create(context.Background(), user))
+
+	adminCtx := context.WithValue(context.Background(), "user_role", "admin")
+	var recorded []*models.AuditEvent
+	auditRepo.On("Create", mock.Anything, mock.AnythingOfType("*models.AuditEvent")).Run(func(args mock.Arguments) {
+		recorded = append(recorded, args.Get(1).(*models.AuditEvent))
+	}).Return(nil)
+
+	got, err := service.GetUser(adminCtx, &pb.GetUserRequest{Id: int32(user.ID)})
+	require.NoError(t, err)
+	staleETag := got.User.Etag
+
+	// Первый администратор меняет роль по актуальной версии
+	role := "admin"
+	resp, err := service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: int32(user.ID), Role: &role, Etag: staleETag})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", resp.User.Role)
+	assert.NotEqual(t, staleETag, resp.User.Etag)
+	require.Len(t, recorded, 1)
+	assert.Equal(t, audit.ActionUserRoleChange, recorded[0].Action)
+
+	// Второй администратор отправляет изменение по устаревшей версии
+	name := "Anna"
+	ifMatchCtx := metadata.NewIncomingContext(adminCtx, metadata.Pairs(etag.IfMatchMetadataKey, `"`+staleETag+`"`))
+	_, err = service.UpdateUser(ifMatchCtx, &pb.UpdateUserRequest{Id: int32(user.ID), Name: &name})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	assert.True(t, etag.IsMismatch(err))
+	assert.Len(t, recorded, 1)
+
+	stored, err := repo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", stored.Name)
+	assert.Equal(t, "admin", stored.Role)
+
+	t.Run("пользователь меняет только свои имя и email", func(t *testing.T) {
+		selfCtx := context.WithValue(context.WithValue(context.Background(), "user_role", "user"), "user_id", user.ID)
+		email := "Anna@Example.com"
+		resp, err := service.UpdateUser(selfCtx, &pb.UpdateUserRequest{Id: int32(user.ID), Name: &name, Email: &email})
+		require.NoError(t, err)
+		assert.Equal(t, "anna@example.com", resp.User.Email)
+
+		active := false
+		_, err = service.UpdateUser(selfCtx, &pb.UpdateUserRequest{Id: int32(user.ID), IsActive: &active})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+		_, err = service.UpdateUser(selfCtx, &pb.UpdateUserRequest{Id: int32(user.ID) + 1, Name: &name})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestUserService_Events(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	txManager := repository.NewTxManager(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	service := NewUserService(repository.NewUserRepository(db), newTestJWTService()).
+		WithTx(txManager).
+		WithEvents(events.NewOutbox(outboxRepo))
+	adminCtx := context.WithValue(context.Background(), "user_role", "admin")
+
+	registered, err := service.Register(context.Background(), &pb.RegisterRequest{Name: "Ann", Email: "ann@example.com", Password: "password123"})
+	require.NoError(t, err)
+	id := registered.User.Id
+
+	name := "Anna"
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: id, Name: &name})
+	require.NoError(t, err)
+	active := false
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: id, IsActive: &active})
+	require.NoError(t, err)
+
+	// Без изменений событие не записывается
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: id, Name: &name})
+	require.NoError(t, err)
+
+	_, err = service.DeleteUser(context.WithValue(context.Background(), "user_role", "user"), &pb.DeleteUserRequest{Id: id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = service.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: id})
+	require.NoError(t, err)
+	_, err = service.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	publisher := events.NewMemoryPublisher()
+	_, err = events.NewRelay(outboxRepo, publisher, 10, 0, logger.Discard()).Flush(context.Background())
+	require.NoError(t, err)
+
+	var types []string
+	for _, msg := range publisher.Messages() {
+		types = append(types, msg.Type)
+		assert.Equal(t, id, msg.Event.GetUserId())
+	}
+	assert.Equal(t, []string{events.TypeUserCreated, events.TypeUserUpdated, events.TypeUserDeactivated, events.TypeUserDeleted}, types)
+}
+
+func TestUserService_Webhooks(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	webhookRepo := repository.NewWebhookRepository(db)
+	service := NewUserService(repository.NewUserRepository(db), newTestJWTService()).
+		WithTx(repository.NewTxManager(db)).
+		WithWebhooks(webhookRepo, false)
+	adminCtx := context.WithValue(context.Background(), "user_role", "admin")
+	userCtx := context.WithValue(context.Background(), "user_role", "user")
+
+	_, err = service.CreateWebhook(userCtx, &pb.CreateWebhookRequest{Url: "https://example.com/hook", EventTypes: []string{events.TypeUserCreated}})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = service.CreateWebhook(adminCtx, &pb.CreateWebhookRequest{Url: "ftp://example.com/hook", EventTypes: []string{events.TypeUserCreated}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = service.CreateWebhook(adminCtx, &pb.CreateWebhookRequest{Url: "https://example.com/hook", EventTypes: []string{"user.unknown"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = service.CreateWebhook(adminCtx, &pb.CreateWebhookRequest{Url: "https://example.com/hook", EventTypes: []string{events.TypeUserCreated}, Secret: "short"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	created, err := service.CreateWebhook(adminCtx, &pb.CreateWebhookRequest{
+		Url:        "https://example.com/hook",
+		EventTypes: []string{events.TypeUserCreated, events.TypeUserCreated, events.TypeUserDeleted},
+	})
+	require.NoError(t, err)
+	assert.Len(t, created.Secret, 64, "секрет возвращается при создании")
+	assert.Equal(t, []string{events.TypeUserCreated, events.TypeUserDeleted}, created.EventTypes)
+	assert.True(t, created.Enabled)
+
+	list, err := service.ListWebhooks(adminCtx, &pb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, list.Webhooks, 1)
+	assert.Empty(t, list.Webhooks[0].Secret, "секрет не возвращается в списке")
+
+	updated, err := service.UpdateWebhook(adminCtx, &pb.UpdateWebhookRequest{Id: created.Id, RotateSecret: true})
+	require.NoError(t, err)
+	assert.Len(t, updated.Secret, 64)
+	assert.NotEqual(t, created.Secret, updated.Secret)
+	_, err = service.UpdateWebhook(adminCtx, &pb.UpdateWebhookRequest{Id: created.Id + 1, RotateSecret: true})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// Доставка, исчерпавшая попытки, ставится в очередь заново
+	delivery := &models.WebhookDelivery{
+		SubscriptionID: uint(created.Id),
+		EventID:        "event-1",
+		EventType:      events.TypeUserCreated,
+		Payload:        []byte(`{}`),
+		Status:         models.WebhookDeliveryFailed,
+		Attempts:       10,
+		NextAttemptAt:  time.Now(),
+	}
+	require.NoError(t, webhookRepo.CreateDelivery(context.Background(), delivery))
+
+	deliveries, err := service.ListWebhookDeliveries(adminCtx, &pb.ListWebhookDeliveriesRequest{WebhookId: created.Id, Status: models.WebhookDeliveryFailed})
+	require.NoError(t, err)
+	require.Len(t, deliveries.Deliveries, 1)
+	_, err = service.ListWebhookDeliveries(adminCtx, &pb.ListWebhookDeliveriesRequest{WebhookId: created.Id, Status: "lost"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	redelivered, err := service.RedeliverWebhook(adminCtx, &pb.RedeliverWebhookRequest{DeliveryId: deliveries.Deliveries[0].Id})
+	require.NoError(t, err)
+	assert.Equal(t, models.WebhookDeliveryPending, redelivered.Status)
+	assert.Zero(t, redelivered.Attempts)
+
+	enabled := false
+	_, err = service.UpdateWebhook(adminCtx, &pb.UpdateWebhookRequest{Id: created.Id, Enabled: &enabled})
+	require.NoError(t, err)
+	_, err = service.RedeliverWebhook(adminCtx, &pb.RedeliverWebhookRequest{DeliveryId: deliveries.Deliveries[0].Id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	_, err = service.DeleteWebhook(adminCtx, &pb.DeleteWebhookRequest{Id: created.Id})
+	require.NoError(t, err)
+	_, err = service.ListWebhookDeliveries(adminCtx, &pb.ListWebhookDeliveriesRequest{WebhookId: created.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = NewUserService(repository.NewUserRepository(db), newTestJWTService()).ListWebhooks(adminCtx, &pb.Empty{})
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestUserService_DeletedUsers(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	userRepo := repository.NewUserRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	service := NewUserService(userRepo, newTestJWTService()).
+		WithTx(repository.NewTxManager(db)).
+		WithEvents(events.NewOutbox(outboxRepo)).
+		WithAudit(audit.NewRecorder(auditRepo, nil, logger.Discard()))
+	adminCtx := context.WithValue(context.Background(), "user_role", "admin")
+	userCtx := context.WithValue(context.Background(), "user_role", "user")
+
+	var ids []int32
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		registered, err := service.Register(context.Background(), &pb.RegisterRequest{Name: "User", Email: email, Password: "password123"})
+		require.NoError(t, err)
+		_, err = service.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: registered.User.Id})
+		require.NoError(t, err)
+		ids = append(ids, registered.User.Id)
+	}
+
+	_, err = service.ListDeletedUsers(userCtx, &pb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	deleted, err := service.ListDeletedUsers(adminCtx, &pb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, deleted.Users, 2)
+	assert.Equal(t, "ann@example.com", deleted.Users[0].User.Email)
+	assert.NotZero(t, deleted.Users[0].DeletedAt)
+
+	_, err = service.RestoreUser(userCtx, &pb.RestoreUserRequest{Id: ids[0]})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	restored, err := service.RestoreUser(adminCtx, &pb.RestoreUserRequest{Id: ids[0]})
+	require.NoError(t, err)
+	assert.Equal(t, "ann@example.com", restored.User.Email)
+	_, err = service.RestoreUser(adminCtx, &pb.RestoreUserRequest{Id: ids[0]})
+	assert.Equal(t, codes.NotFound, status.Code(err), "пользователь уже восстановлен")
+
+	// Email удаленного пользователя занят новым пользователем
+	_, err = service.Register(context.Background(), &pb.RegisterRequest{Name: "Bob", Email: "BOB@example.com", Password: "password123"})
+	require.NoError(t, err)
+	_, err = service.RestoreUser(adminCtx, &pb.RestoreUserRequest{Id: ids[1]})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+
+	_, err = service.PurgeUser(adminCtx, &pb.PurgeUserRequest{Id: ids[0]})
+	assert.Equal(t, codes.NotFound, status.Code(err), "неудаленного пользователя нельзя удалить окончательно")
+	_, err = service.PurgeUser(userCtx, &pb.PurgeUserRequest{Id: ids[1]})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = service.PurgeUser(adminCtx, &pb.PurgeUserRequest{Id: ids[1]})
+	require.NoError(t, err)
+	deleted, err = service.ListDeletedUsers(adminCtx, &pb.Empty{})
+	require.NoError(t, err)
+	assert.Empty(t, deleted.Users)
+
+	// Запись аудита об окончательном удалении не содержит email пользователя
+	purgeEvents, err := auditRepo.List(context.Background(), repository.AuditFilter{Action: audit.ActionUserPurge, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, purgeEvents, 1)
+	assert.Equal(t, uint(ids[1]), *purgeEvents[0].TargetID)
+	assert.Equal(t, models.ErasedEmail(uint(ids[1])), purgeEvents[0].TargetEmail)
+
+	publisher := events.NewMemoryPublisher()
+	_, err = events.NewRelay(outboxRepo, publisher, 10, 0, logger.Discard()).Flush(context.Background())
+	require.NoError(t, err)
+	var restoredEvents int
+	for _, msg := range publisher.Messages() {
+		if msg.Type == events.TypeUserRestored {
+			restoredEvents++
+			assert.Equal(t, ids[0], msg.Event.GetRestored().GetUser().GetId())
+		}
+	}
+	assert.Equal(t, 1, restoredEvents)
+}
+
+func TestDeletedUsersRetention(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryUserRepository(nil)
+	user := &models.User{Name: "Ann", Email: "ann@example.com", PasswordHash: "hash"}
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.Delete(ctx, user.ID))
+
+	retention := NewDeletedUsersRetention(repo, 0, logger.Discard())
+	purged, err := retention.Cleanup(ctx, time.Now().Add(48*time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, purged, "нулевой срок отключает удаление")
+
+	retention.Update(24 * time.Hour)
+	purged, err = retention.Cleanup(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, purged, "срок хранения не истек")
+
+	purged, err = retention.Cleanup(ctx, time.Now().Add(25*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, err = repo.GetDeleted(ctx, user.ID)
+	assert.ErrorIs(t, err, repository.ErrNotFound)
+}
+
+func TestUserService_PrivacyExportAndErase(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	userRepo := repository.NewUserRepository(db)
+	oauthRepo := repository.NewOAuthRepository(db)
+	recorder := audit.NewRecorder(repository.NewAuditRepository(db), nil, logger.Discard())
+	service := NewUserService(userRepo, newTestJWTService()).
+		WithAudit(recorder).
+		WithTx(repository.NewTxManager(db)).
+		WithOAuth(oauthRepo)
+	ctx := context.Background()
+
+	var ids []uint
+	for _, email := range []string{"ann@example.com", "bob@example.com"} {
+		registered, err := service.Register(ctx, &pb.RegisterRequest{Name: "User", Email: email, Password: "password123"})
+		require.NoError(t, err)
+		_, err = service.Login(ctx, &pb.LoginRequest{Email: email, Password: "password123"})
+		require.NoError(t, err)
+		ids = append(ids, uint(registered.User.Id))
+	}
+	require.NoError(t, oauthRepo.SaveAuthorizationCode(ctx, &models.OAuthAuthorizationCode{
+		CodeHash:  "code",
+		ClientID:  "grafana",
+		UserID:    ids[0],
+		Scope:     "openid email",
+		AuthTime:  time.Now(),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	annCtx := context.WithValue(context.WithValue(ctx, "user_id", ids[0]), "user_role", "user")
+	adminCtx := context.WithValue(context.WithValue(ctx, "user_id", uint(100)), "user_role", "admin")
+
+	_, err = service.ExportMyData(ctx, &pb.ExportMyDataRequest{})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = service.ExportMyData(annCtx, &pb.ExportMyDataRequest{Format: "xml"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	body, err := service.ExportMyData(annCtx, &pb.ExportMyDataRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "application/json", body.ContentType)
+	var export privacy.Export
+	require.NoError(t, json.Unmarshal(body.Data, &export))
+	assert.Equal(t, "ann@example.com", export.Profile.Email)
+	assert.Len(t, export.Sessions, 1)
+	require.Len(t, export.Identities, 2)
+	assert.Equal(t, "grafana", export.Identities[1].Subject)
+	for _, event := range export.AuditEvents {
+		assert.Equal(t, ids[0], *event.TargetID, "в выгрузке только события пользователя")
+	}
+
+	body, err = service.ExportMyData(annCtx, &pb.ExportMyDataRequest{Format: "zip"})
+	require.NoError(t, err)
+	assert.Equal(t, "application/zip", body.ContentType)
+	archive, err := zip.NewReader(bytes.NewReader(body.Data), int64(len(body.Data)))
+	require.NoError(t, err)
+	var files []string
+	for _, file := range archive.File {
+		files = append(files, file.Name)
+	}
+	assert.Equal(t, []string{"profile.json", "sessions.json", "identities.json", "audit_events.json"}, files)
+
+	// Удалить данные может сам пользователь или администратор
+	_, err = service.EraseUser(annCtx, &pb.EraseUserRequest{Id: int32(ids[1])})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	erased, err := service.EraseUser(annCtx, &pb.EraseUserRequest{Id: int32(ids[0])})
+	require.NoError(t, err)
+	assert.NotZero(t, erased.AuditEventId)
+	assert.NotZero(t, erased.ErasedAt)
+	_, err = service.EraseUser(adminCtx, &pb.EraseUserRequest{Id: int32(ids[0])})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	user, err := userRepo.GetByID(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, models.ErasedName, user.Name)
+	assert.Equal(t, models.ErasedEmail(ids[0]), user.Email)
+	assert.False(t, user.IsActive)
+	authCodes, err := oauthRepo.ListAuthorizationCodes(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Empty(t, authCodes)
+
+	_, err = service.Login(ctx, &pb.LoginRequest{Email: "ann@example.com", Password: "password123"})
+	assert.Error(t, err)
+	email := "ann@example.com"
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: int32(ids[0]), Email: &email})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "обезличенного пользователя нельзя изменить")
+
+	// Удаленного пользователя тоже можно обезличить
+	_, err = service.DeleteUser(adminCtx, &pb.DeleteUserRequest{Id: int32(ids[1])})
+	require.NoError(t, err)
+	_, err = service.EraseUser(adminCtx, &pb.EraseUserRequest{Id: int32(ids[1])})
+	require.NoError(t, err)
+
+	// Записи аудита сохраняются, цепочка не нарушена, email инициатора заменен заглушкой
+	events, err := recorder.List(ctx, repository.AuditFilter{Action: audit.ActionUserErase})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.ErasedEmail(ids[0]), events[1].ActorEmail)
+	result, err := recorder.Verify(ctx)
+	require.NoError(t, err)
+	assert.True(t, result.Valid)
+}
+
+func TestUserService_EraseScrubsEventsAndWebhooks(t *testing.T) {
+	db, err := database.Connect("sqlite://:memory:", logger.Discard())
+	require.NoError(t, err)
+	require.NoError(t, database.Migrate(db))
+	t.Cleanup(func() { _ = database.Close(db) })
+
+	ctx := context.Background()
+	outboxRepo := repository.NewOutboxRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	subscription := &models.WebhookSubscription{URL: "https://example.com/hook", Secret: "secret", Enabled: true}
+	subscription.SetTypes([]string{events.TypeUserCreated, events.TypeUserUpdated, events.TypeUserErased})
+	require.NoError(t, webhookRepo.CreateSubscription(ctx, subscription))
+	service := NewUserService(repository.NewUserRepository(db), newTestJWTService()).
+		WithTx(repository.NewTxManager(db)).
+		WithEvents(events.NewOutbox(outboxRepo)).
+		WithWebhooks(webhookRepo, false)
+	relay := events.NewRelay(outboxRepo, events.Multi(events.NewMemoryPublisher(), webhook.NewPublisher(webhookRepo)), 10, 0, logger.Discard())
+	adminCtx := context.WithValue(context.WithValue(ctx, "user_id", uint(100)), "user_role", "admin")
+
+	ann, err := service.Register(ctx, &pb.RegisterRequest{Name: "Ann Smith", Email: "ann.smith@example.com", Password: "password123"})
+	require.NoError(t, err)
+	_, err = service.Register(ctx, &pb.RegisterRequest{Name: "Bob Jones", Email: "bob.jones@example.com", Password: "password123"})
+	require.NoError(t, err)
+	name := "Ann Smithson"
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: ann.User.Id, Name: &name})
+	require.NoError(t, err)
+
+	// Часть событий опубликована и доставки созданы, последнее изменение еще ждет публикации
+	_, err = relay.Flush(ctx)
+	require.NoError(t, err)
+	name = "Ann Smith-Jones"
+	_, err = service.UpdateUser(adminCtx, &pb.UpdateUserRequest{Id: ann.User.Id, Name: &name})
+	require.NoError(t, err)
+
+	_, err = service.EraseUser(adminCtx, &pb.EraseUserRequest{Id: ann.User.Id})
+	require.NoError(t, err)
+	_, err = relay.Flush(ctx)
+	require.NoError(t, err)
+
+	// Ни одно событие и ни одна доставка не содержат удаленных данных
+	var payloads [][]byte
+	require.NoError(t, db.Model(&models.OutboxEvent{}).Pluck("payload", &payloads).Error)
+	var deliveries [][]byte
+	require.NoError(t, db.Model(&models.WebhookDelivery{}).Pluck("payload", &deliveries).Error)
+	payloads = append(payloads, deliveries...)
+	var bobFound bool
+	for _, payload := range payloads {
+		for _, pii := range []string{"ann.smith@example.com", "Ann Smith"} {
+			assert.NotContains(t, string(payload), pii)
+		}
+		bobFound = bobFound || bytes.Contains(payload, []byte("bob.jones@example.com"))
+	}
+	assert.True(t, bobFound, "данные других пользователей сохраняются")
+
+	// Событие об обезличивании публикуется и доставляется
+	var erased []string
+	require.NoError(t, db.Model(&models.WebhookDelivery{}).Where("user_id = ?", ann.User.Id).Pluck("event_type", &erased).Error)
+	assert.Equal(t, []string{events.TypeUserErased}, erased)
+}
@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"k8s-go-grpc-react/internal/audit"
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+	"k8s-go-grpc-react/internal/webhook"
+	pb "k8s-go-grpc-react/proto"
+)
+
+// errWebhookDisabled подписка отключена, доставка по ней не выполняется
+var errWebhookDisabled = errors.New("подписка webhook отключена")
+
+const (
+	// defaultDeliveryPageSize размер страницы журнала доставок по умолчанию
+	defaultDeliveryPageSize = 50
+	// maxDeliveryPageSize наибольший размер страницы журнала доставок
+	maxDeliveryPageSize = 500
+)
+
+// checkWebhookAccess проверяет, что webhook включены и вызывающий - администратор.
+// Возвращает ошибку с уже записанной метрикой.
+func (s *UserService) checkWebhookAccess(ctx context.Context, method string, start time.Time) error {
+	if !hasRole(ctx, "admin") {
+		s.recordMetrics(method, "permission_denied", time.Since(start))
+		return status.Error(codes.PermissionDenied, "Подписки на webhook доступны только администраторам")
+	}
+	if s.webhooks == nil {
+		s.recordMetrics(method, "unimplemented", time.Since(start))
+		return status.Error(codes.Unimplemented, "Webhook не включены")
+	}
+	return nil
+}
+
+// CreateWebhook создает подписку на события пользователей (только для админов)
+func (s *UserService) CreateWebhook(ctx context.Context, req *pb.CreateWebhookRequest) (*pb.Webhook, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("CreateWebhook", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "CreateWebhook", start); err != nil {
+		return nil, err
+	}
+
+	if err := webhook.ValidateURL(req.Url, s.webhooksPrivate); err != nil {
+		s.recordMetrics("CreateWebhook", "invalid_argument", time.Since(start))
+		return nil, status.Errorf(codes.InvalidArgument, "Неверный url: %v", err)
+	}
+	types, err := webhook.NormalizeTypes(req.EventTypes, events.Types)
+	if err != nil {
+		s.recordMetrics("CreateWebhook", "invalid_argument", time.Since(start))
+		return nil, status.Errorf(codes.InvalidArgument, "Неверные event_types: %v", err)
+	}
+
+	secret := req.Secret
+	if secret == "" {
+		if secret, err = webhook.GenerateSecret(); err != nil {
+			s.recordMetrics("CreateWebhook", "internal_error", time.Since(start))
+			return nil, status.Error(codes.Internal, "Ошибка при генерации секрета")
+		}
+	} else if len(secret) < webhook.MinSecretLength {
+		s.recordMetrics("CreateWebhook", "invalid_argument", time.Since(start))
+		return nil, status.Errorf(codes.InvalidArgument, "Секрет должен быть не короче %d символов", webhook.MinSecretLength)
+	}
+
+	var subscription *models.WebhookSubscription
+	err = s.withinTx(ctx, func(ctx context.Context) error {
+		subscription = &models.WebhookSubscription{URL: req.Url, Secret: secret, Enabled: true}
+		subscription.SetTypes(types)
+		if err := s.webhooks.CreateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		event := &models.AuditEvent{Action: audit.ActionWebhookCreate}
+		event.SetChanges(audit.WebhookChanges(nil, subscription))
+		s.recordAudit(ctx, event)
+		return nil
+	})
+	if err != nil {
+		return nil, s.repositoryError("CreateWebhook", start, err, "Ошибка при создании подписки")
+	}
+
+	// Секрет показывается один раз, потом его можно только сменить
+	result := webhookToProto(subscription)
+	result.Secret = subscription.Secret
+	return result, nil
+}
+
+// ListWebhooks возвращает подписки без секретов (только для админов)
+func (s *UserService) ListWebhooks(ctx context.Context, req *pb.Empty) (*pb.ListWebhooksResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("ListWebhooks", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "ListWebhooks", start); err != nil {
+		return nil, err
+	}
+
+	subscriptions, err := s.webhooks.ListSubscriptions(ctx)
+	if err != nil {
+		return nil, s.repositoryError("ListWebhooks", start, err, "Ошибка при получении подписок")
+	}
+
+	resp := &pb.ListWebhooksResponse{Webhooks: make([]*pb.Webhook, len(subscriptions))}
+	for i, subscription := range subscriptions {
+		resp.Webhooks[i] = webhookToProto(subscription)
+	}
+	return resp, nil
+}
+
+// UpdateWebhook изменяет подписку (только для админов). Включение подписки,
+// отключенной после неудачных доставок, сбрасывает счетчик ошибок.
+func (s *UserService) UpdateWebhook(ctx context.Context, req *pb.UpdateWebhookRequest) (*pb.Webhook, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("UpdateWebhook", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "UpdateWebhook", start); err != nil {
+		return nil, err
+	}
+
+	if req.Url != nil {
+		if err := webhook.ValidateURL(*req.Url, s.webhooksPrivate); err != nil {
+			s.recordMetrics("UpdateWebhook", "invalid_argument", time.Since(start))
+			return nil, status.Errorf(codes.InvalidArgument, "Неверный url: %v", err)
+		}
+	}
+	var types []string
+	if len(req.EventTypes) > 0 {
+		var err error
+		if types, err = webhook.NormalizeTypes(req.EventTypes, events.Types); err != nil {
+			s.recordMetrics("UpdateWebhook", "invalid_argument", time.Since(start))
+			return nil, status.Errorf(codes.InvalidArgument, "Неверные event_types: %v", err)
+		}
+	}
+	var secret string
+	if req.RotateSecret {
+		var err error
+		if secret, err = webhook.GenerateSecret(); err != nil {
+			s.recordMetrics("UpdateWebhook", "internal_error", time.Since(start))
+			return nil, status.Error(codes.Internal, "Ошибка при генерации секрета")
+		}
+	}
+
+	var subscription *models.WebhookSubscription
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		subscription, err = s.webhooks.GetSubscription(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+
+		before := *subscription
+		if req.Url != nil {
+			subscription.URL = *req.Url
+		}
+		if types != nil {
+			subscription.SetTypes(types)
+		}
+		if req.Enabled != nil {
+			subscription.Enabled = *req.Enabled
+			if *req.Enabled {
+				subscription.ConsecutiveFailures = 0
+				subscription.DisabledAt = nil
+				subscription.DisabledReason = ""
+			}
+		}
+		if secret != "" {
+			subscription.Secret = secret
+		}
+
+		changes := audit.WebhookChanges(&before, subscription)
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := s.webhooks.UpdateSubscription(ctx, subscription); err != nil {
+			return err
+		}
+
+		event := &models.AuditEvent{Action: audit.ActionWebhookUpdate}
+		event.SetChanges(append([]models.AuditChange{webhookIDChange(subscription.ID)}, changes...))
+		s.recordAudit(ctx, event)
+		return nil
+	})
+	if err != nil {
+		return nil, s.repositoryError("UpdateWebhook", start, err, "Ошибка при изменении подписки")
+	}
+
+	result := webhookToProto(subscription)
+	if secret != "" {
+		result.Secret = secret
+	}
+	return result, nil
+}
+
+// DeleteWebhook удаляет подписку и журнал ее доставок (только для админов)
+func (s *UserService) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.Empty, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("DeleteWebhook", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "DeleteWebhook", start); err != nil {
+		return nil, err
+	}
+
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		subscription, err := s.webhooks.GetSubscription(ctx, uint(req.Id))
+		if err != nil {
+			return err
+		}
+		if err := s.webhooks.DeleteSubscription(ctx, subscription.ID); err != nil {
+			return err
+		}
+
+		event := &models.AuditEvent{Action: audit.ActionWebhookDelete}
+		event.SetChanges(audit.WebhookChanges(subscription, nil))
+		s.recordAudit(ctx, event)
+		return nil
+	})
+	if err != nil {
+		return nil, s.repositoryError("DeleteWebhook", start, err, "Ошибка при удалении подписки")
+	}
+	return &pb.Empty{}, nil
+}
+
+// ListWebhookDeliveries возвращает журнал доставок подписки (только для админов)
+func (s *UserService) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("ListWebhookDeliveries", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "ListWebhookDeliveries", start); err != nil {
+		return nil, err
+	}
+
+	if req.PageSize < 0 || req.WebhookId <= 0 {
+		s.recordMetrics("ListWebhookDeliveries", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "webhook_id должен быть больше нуля, page_size не может быть отрицательным")
+	}
+	switch req.Status {
+	case "", models.WebhookDeliveryPending, models.WebhookDeliverySucceeded, models.WebhookDeliveryFailed:
+	default:
+		s.recordMetrics("ListWebhookDeliveries", "invalid_argument", time.Since(start))
+		return nil, status.Error(codes.InvalidArgument, "status должен быть pending, succeeded или failed")
+	}
+
+	pageSize := int(req.PageSize)
+	switch {
+	case pageSize == 0:
+		pageSize = defaultDeliveryPageSize
+	case pageSize > maxDeliveryPageSize:
+		pageSize = maxDeliveryPageSize
+	}
+
+	filter := repository.WebhookDeliveryFilter{
+		SubscriptionID: uint(req.WebhookId),
+		Status:         req.Status,
+		Limit:          pageSize + 1, // Лишняя запись показывает, что есть следующая страница
+	}
+	if req.PageToken != "" {
+		beforeID, err := strconv.ParseUint(req.PageToken, 10, 64)
+		if err != nil || beforeID == 0 {
+			s.recordMetrics("ListWebhookDeliveries", "invalid_argument", time.Since(start))
+			return nil, status.Error(codes.InvalidArgument, "Неверный page_token")
+		}
+		filter.BeforeID = uint(beforeID)
+	}
+
+	if _, err := s.webhooks.GetSubscription(ctx, uint(req.WebhookId)); err != nil {
+		return nil, s.repositoryError("ListWebhookDeliveries", start, err, "Ошибка при получении подписки")
+	}
+	deliveries, err := s.webhooks.ListDeliveries(ctx, filter)
+	if err != nil {
+		return nil, s.repositoryError("ListWebhookDeliveries", start, err, "Ошибка при получении журнала доставок")
+	}
+
+	resp := &pb.ListWebhookDeliveriesResponse{}
+	if len(deliveries) > pageSize {
+		deliveries = deliveries[:pageSize]
+		resp.NextPageToken = strconv.FormatUint(uint64(deliveries[pageSize-1].ID), 10)
+	}
+	resp.Deliveries = make([]*pb.WebhookDelivery, len(deliveries))
+	for i, delivery := range deliveries {
+		resp.Deliveries[i] = webhookDeliveryToProto(delivery)
+	}
+	return resp, nil
+}
+
+// RedeliverWebhook ставит доставку в очередь заново с полным числом попыток
+// (только для админов). Получатель узнает повтор по тому же X-Webhook-Id.
+func (s *UserService) RedeliverWebhook(ctx context.Context, req *pb.RedeliverWebhookRequest) (*pb.WebhookDelivery, error) {
+	start := time.Now()
+	defer func() {
+		s.recordMetrics("RedeliverWebhook", "success", time.Since(start))
+	}()
+
+	if err := s.checkWebhookAccess(ctx, "RedeliverWebhook", start); err != nil {
+		return nil, err
+	}
+
+	var delivery *models.WebhookDelivery
+	err := s.withinTx(ctx, func(ctx context.Context) error {
+		var err error
+		delivery, err = s.webhooks.GetDelivery(ctx, uint(req.DeliveryId))
+		if err != nil {
+			return err
+		}
+		subscription, err := s.webhooks.GetSubscription(ctx, delivery.SubscriptionID)
+		if err != nil {
+			return err
+		}
+		if !subscription.Enabled {
+			return errWebhookDisabled
+		}
+
+		delivery.Status = models.WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now()
+		delivery.DeliveredAt = nil
+		delivery.UpdatedAt = time.Now()
+		if err := s.webhooks.UpdateDelivery(ctx, delivery); err != nil {
+			return err
+		}
+
+		event := &models.AuditEvent{Action: audit.ActionWebhookRedeliver}
+		event.SetChanges([]models.AuditChange{
+			webhookIDChange(subscription.ID),
+			{Field: "delivery_id", New: strconv.FormatUint(uint64(delivery.ID), 10)},
+		})
+		s.recordAudit(ctx, event)
+		return nil
+	})
+	if errors.Is(err, errWebhookDisabled) {
+		s.recordMetrics("RedeliverWebhook", "failed_precondition", time.Since(start))
+		return nil, status.Error(codes.FailedPrecondition, "Подписка отключена, включите ее перед повторной доставкой")
+	}
+	if err != nil {
+		return nil, s.repositoryError("RedeliverWebhook", start, err, "Ошибка при повторной доставке")
+	}
+	return webhookDeliveryToProto(delivery), nil
+}
+
+// webhookIDChange указывает в записи аудита подписку, к которой относится действие
+func webhookIDChange(id uint) models.AuditChange {
+	value := strconv.FormatUint(uint64(id), 10)
+	return models.AuditChange{Field: "id", Old: value, New: value}
+}
+
+// webhookToProto конвертирует подписку в protobuf без секрета
+func webhookToProto(subscription *models.WebhookSubscription) *pb.Webhook {
+	result := &pb.Webhook{
+		Id:                  int32(subscription.ID),
+		Url:                 subscription.URL,
+		EventTypes:          subscription.Types(),
+		Enabled:             subscription.Enabled,
+		ConsecutiveFailures: int32(subscription.ConsecutiveFailures),
+		CreatedAt:           subscription.CreatedAt.Unix(),
+		DisabledReason:      subscription.DisabledReason,
+	}
+	if subscription.DisabledAt != nil {
+		result.DisabledAt = subscription.DisabledAt.Unix()
+	}
+	return result
+}
+
+// webhookDeliveryToProto конвертирует доставку в protobuf
+func webhookDeliveryToProto(delivery *models.WebhookDelivery) *pb.WebhookDelivery {
+	result := &pb.WebhookDelivery{
+		Id:             int64(delivery.ID),
+		WebhookId:      int32(delivery.SubscriptionID),
+		EventId:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       int32(delivery.Attempts),
+		CreatedAt:      delivery.CreatedAt.Unix(),
+		ResponseStatus: int32(delivery.ResponseStatus),
+		LastError:      delivery.LastError,
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		result.NextAttemptAt = delivery.NextAttemptAt.Unix()
+	}
+	if delivery.LastAttemptAt != nil {
+		result.LastAttemptAt = delivery.LastAttemptAt.Unix()
+	}
+	if delivery.DeliveredAt != nil {
+		result.DeliveredAt = delivery.DeliveredAt.Unix()
+	}
+	return result
+}
@@ -0,0 +1,62 @@
+// Package shutdown содержит общие шаги корректной остановки процессов.
+package shutdown
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+// Readiness состояние готовности процесса принимать трафик
+type Readiness struct {
+	notReady atomic.Bool
+}
+
+// NewReadiness создает Readiness в состоянии готовности
+func NewReadiness() *Readiness {
+	return &Readiness{}
+}
+
+// SetNotReady переводит процесс в состояние остановки. Повторно готовность не включается.
+func (r *Readiness) SetNotReady() {
+	r.notReady.Store(true)
+}
+
+// Ready возвращает true, пока остановка не началась
+func (r *Readiness) Ready() bool {
+	return !r.notReady.Load()
+}
+
+// Handler обработчик readiness probe: 200, пока процесс готов, затем 503
+func (r *Readiness) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !r.Ready() {
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("OK"))
+	})
+}
+
+// StopGRPC ждет завершения активных вызовов через GracefulStop и
+// принудительно закрывает соединения, если ctx истекает раньше. Обработчики,
+// не реагирующие на отмену контекста, после этого не ожидаются.
+// Возвращает true, если потребовалась принудительная остановка.
+func StopGRPC(ctx context.Context, server *grpc.Server) bool {
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return false
+	case <-ctx.Done():
+		server.Stop()
+		return true
+	}
+}
@@ -0,0 +1,41 @@
+package tlsutil
+
+import (
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// DefaultReloadInterval интервал проверки файлов сертификатов на изменения.
+// Kubernetes обновляет смонтированные секреты с задержкой около минуты,
+// поэтому более частая проверка не имеет смысла.
+const DefaultReloadInterval = 30 * time.Second
+
+// ServerCredentials возвращает транспортные credentials gRPC сервера.
+// Если TLS не настроен, возвращаются insecure credentials и nil Reloader.
+func ServerCredentials(opts ServerOptions) (credentials.TransportCredentials, *Reloader, error) {
+	if !opts.Enabled() {
+		return insecure.NewCredentials(), nil, nil
+	}
+
+	cfg, reloader, err := NewServerConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials.NewTLS(cfg), reloader, nil
+}
+
+// ClientCredentials возвращает транспортные credentials для подключения к gRPC серверу.
+// Если TLS не включен, возвращаются insecure credentials и nil Reloader.
+func ClientCredentials(opts ClientOptions) (credentials.TransportCredentials, *Reloader, error) {
+	if !opts.Enabled {
+		return insecure.NewCredentials(), nil, nil
+	}
+
+	cfg, reloader, err := NewClientConfig(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	return credentials.NewTLS(cfg), reloader, nil
+}
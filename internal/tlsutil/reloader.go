@@ -0,0 +1,155 @@
+package tlsutil
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader хранит сертификат и CA в памяти и перечитывает их,
+// когда файлы на диске меняются (например, при ротации cert-manager)
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu       sync.RWMutex
+	loaded   bool
+	cert     *tls.Certificate
+	caPool   *x509.CertPool
+	certData []byte // Сертификат и ключ, по ним определяется изменение файлов
+	caData   []byte
+}
+
+// NewReloader создает Reloader и сразу загружает файлы.
+// Любой из путей может быть пустым: сертификат и CA загружаются независимо.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	if (certFile == "") != (keyFile == "") {
+		return nil, errors.New("сертификат и ключ должны быть заданы вместе")
+	}
+
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload перечитывает файлы и возвращает true, если их содержимое изменилось
+func (r *Reloader) Reload() (bool, error) {
+	var (
+		certData, keyData, caData []byte
+		cert                      *tls.Certificate
+		caPool                    *x509.CertPool
+		err                       error
+	)
+
+	if r.certFile != "" {
+		if certData, err = os.ReadFile(r.certFile); err != nil {
+			return false, fmt.Errorf("ошибка чтения сертификата: %w", err)
+		}
+		if keyData, err = os.ReadFile(r.keyFile); err != nil {
+			return false, fmt.Errorf("ошибка чтения ключа: %w", err)
+		}
+	}
+	if r.caFile != "" {
+		if caData, err = os.ReadFile(r.caFile); err != nil {
+			return false, fmt.Errorf("ошибка чтения CA: %w", err)
+		}
+	}
+
+	pairData := append(append([]byte{}, certData...), keyData...)
+
+	r.mu.RLock()
+	unchanged := r.loaded && bytes.Equal(pairData, r.certData) && bytes.Equal(caData, r.caData)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	if certData != nil {
+		pair, err := tls.X509KeyPair(certData, keyData)
+		if err != nil {
+			return false, fmt.Errorf("ошибка разбора сертификата: %w", err)
+		}
+		cert = &pair
+	}
+	if caData != nil {
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(caData) {
+			return false, fmt.Errorf("файл CA %s не содержит сертификатов", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.loaded = true
+	r.cert = cert
+	r.caPool = caPool
+	r.certData = pairData
+	r.caData = caData
+	r.mu.Unlock()
+
+	return true, nil
+}
+
+// Watch периодически проверяет файлы до отмены контекста.
+// onReload вызывается после успешной перезагрузки, onError - при ошибке;
+// при ошибке продолжают использоваться ранее загруженные сертификаты.
+func (r *Reloader) Watch(ctx context.Context, interval time.Duration, onReload func(), onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			changed, err := r.Reload()
+			switch {
+			case err != nil && onError != nil:
+				onError(err)
+			case changed && onReload != nil:
+				onReload()
+			}
+		}
+	}
+}
+
+// Certificate возвращает текущий сертификат
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert
+}
+
+// CAPool возвращает текущий пул доверенных CA
+func (r *Reloader) CAPool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.caPool
+}
+
+// getCertificate реализует tls.Config.GetCertificate
+func (r *Reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cert := r.Certificate()
+	if cert == nil {
+		return nil, errors.New("сертификат сервера не загружен")
+	}
+	return cert, nil
+}
+
+// getClientCertificate реализует tls.Config.GetClientCertificate
+func (r *Reloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	cert := r.Certificate()
+	if cert == nil {
+		// Пустой сертификат означает, что клиент не предъявляет сертификат
+		return &tls.Certificate{}, nil
+	}
+	return cert, nil
+}
@@ -0,0 +1,71 @@
+package tlsutil
+
+import (
+	"context"
+	"crypto/x509"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// SANAuthorizer разрешает вызовы gRPC только сервисам, чей клиентский
+// сертификат содержит один из разрешенных SAN (DNS имя, URI, например
+// SPIFFE ID, или email)
+type SANAuthorizer struct {
+	allowed map[string]bool
+}
+
+// NewSANAuthorizer создает авторизатор по списку разрешенных SAN
+func NewSANAuthorizer(allowedSANs []string) *SANAuthorizer {
+	allowed := make(map[string]bool, len(allowedSANs))
+	for _, san := range allowedSANs {
+		if san = strings.TrimSpace(san); san != "" {
+			allowed[san] = true
+		}
+	}
+	return &SANAuthorizer{allowed: allowed}
+}
+
+// UnaryInterceptor возвращает unary interceptor для проверки SAN клиентского сертификата
+func (a *SANAuthorizer) UnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := a.authorize(ctx); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authorize проверяет проверенный клиентский сертификат из контекста соединения
+func (a *SANAuthorizer) authorize(ctx context.Context) error {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "Информация о соединении не найдена")
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return status.Error(codes.Unauthenticated, "Клиентский сертификат не предоставлен")
+	}
+
+	for _, san := range certificateSANs(tlsInfo.State.VerifiedChains[0][0]) {
+		if a.allowed[san] {
+			return nil
+		}
+	}
+
+	return status.Error(codes.PermissionDenied, "Сервис не авторизован для вызова")
+}
+
+// certificateSANs возвращает все SAN сертификата в строковом виде
+func certificateSANs(cert *x509.Certificate) []string {
+	sans := make([]string, 0, len(cert.DNSNames)+len(cert.URIs)+len(cert.EmailAddresses))
+	sans = append(sans, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	return sans
+}
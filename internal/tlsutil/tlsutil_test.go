@@ -0,0 +1,181 @@
+package tlsutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCA генерирует самоподписанный CA и выпускает им сертификаты
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+func (ca *testCA) issue(t *testing.T, serial int64, dnsName, uri string, usage x509.ExtKeyUsage) (certPEM, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{dnsName},
+	}
+	if uri != "" {
+		u, err := url.Parse(uri)
+		require.NoError(t, err)
+		tmpl.URIs = []*url.URL{u}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+// handshake выполняет TLS рукопожатие через net.Pipe и возвращает состояние соединения сервера
+func handshake(t *testing.T, serverCfg, clientCfg *tls.Config) (tls.ConnectionState, error) {
+	serverConn, clientConn := net.Pipe()
+	defer serverConn.Close()
+	defer clientConn.Close()
+
+	server := tls.Server(serverConn, serverCfg)
+	client := tls.Client(clientConn, clientCfg)
+
+	errCh := make(chan error, 1)
+	go func() { errCh <- client.Handshake() }()
+
+	serverErr := server.Handshake()
+	if serverErr != nil {
+		clientConn.Close()
+	}
+	clientErr := <-errCh
+
+	if serverErr != nil {
+		return tls.ConnectionState{}, serverErr
+	}
+	return server.ConnectionState(), clientErr
+}
+
+func TestMutualTLSAndReload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.crt", ca.pem)
+
+	serverCert, serverKey := ca.issue(t, 2, "grpc-server", "", x509.ExtKeyUsageServerAuth)
+	clientCert, clientKey := ca.issue(t, 3, "http-gateway", "spiffe://cluster.local/ns/default/sa/http-gateway", x509.ExtKeyUsageClientAuth)
+
+	serverCfg, serverReloader, err := NewServerConfig(ServerOptions{
+		CertFile:     writeFile(t, dir, "server.crt", serverCert),
+		KeyFile:      writeFile(t, dir, "server.key", serverKey),
+		ClientCAFile: caFile,
+		ClientAuth:   ClientAuthRequire,
+	})
+	require.NoError(t, err)
+
+	clientCertFile := writeFile(t, dir, "client.crt", clientCert)
+	clientKeyFile := writeFile(t, dir, "client.key", clientKey)
+	clientCfg, clientReloader, err := NewClientConfig(ClientOptions{
+		Enabled:    true,
+		CAFile:     caFile,
+		CertFile:   clientCertFile,
+		KeyFile:    clientKeyFile,
+		ServerName: "grpc-server",
+	})
+	require.NoError(t, err)
+
+	state, err := handshake(t, serverCfg, clientCfg)
+	require.NoError(t, err)
+	require.NotEmpty(t, state.VerifiedChains)
+	assert.Contains(t, certificateSANs(state.VerifiedChains[0][0]), "spiffe://cluster.local/ns/default/sa/http-gateway")
+
+	// Без изменений файлов перезагрузка ничего не меняет
+	changed, err := serverReloader.Reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// Ротация клиентского сертификата подхватывается без пересоздания конфигурации
+	rotatedCert, rotatedKey := ca.issue(t, 4, "http-gateway-rotated", "", x509.ExtKeyUsageClientAuth)
+	writeFile(t, dir, "client.crt", rotatedCert)
+	writeFile(t, dir, "client.key", rotatedKey)
+
+	changed, err = clientReloader.Reload()
+	require.NoError(t, err)
+	assert.True(t, changed)
+
+	state, err = handshake(t, serverCfg, clientCfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"http-gateway-rotated"}, certificateSANs(state.VerifiedChains[0][0]))
+}
+
+func TestClientRejectsUntrustedServer(t *testing.T) {
+	dir := t.TempDir()
+	trusted := newTestCA(t)
+	untrusted := newTestCA(t)
+
+	serverCert, serverKey := untrusted.issue(t, 2, "grpc-server", "", x509.ExtKeyUsageServerAuth)
+	serverCfg, _, err := NewServerConfig(ServerOptions{
+		CertFile: writeFile(t, dir, "server.crt", serverCert),
+		KeyFile:  writeFile(t, dir, "server.key", serverKey),
+	})
+	require.NoError(t, err)
+
+	clientCfg, _, err := NewClientConfig(ClientOptions{
+		Enabled:    true,
+		CAFile:     writeFile(t, dir, "ca.crt", trusted.pem),
+		ServerName: "grpc-server",
+	})
+	require.NoError(t, err)
+
+	_, err = handshake(t, serverCfg, clientCfg)
+	assert.Error(t, err)
+}
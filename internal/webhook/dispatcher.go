@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"k8s-go-grpc-react/internal/logger"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+const (
+	// DefaultDispatchInterval период проверки ожидающих доставок
+	DefaultDispatchInterval = 5 * time.Second
+	// dispatchBatchSize наибольшее число доставок, выбираемых за раз
+	dispatchBatchSize = 50
+	// dispatchWorkers число одновременных запросов, чтобы медленный адрес не задерживал остальные
+	dispatchWorkers = 8
+	// retryBaseDelay пауза перед второй попыткой; удваивается с каждой попыткой
+	retryBaseDelay = 30 * time.Second
+	// maxRetryDelay наибольшая пауза между попытками
+	maxRetryDelay = time.Hour
+	// maxErrorBody сколько байт тела ответа с ошибкой сохраняется в журнале доставок
+	maxErrorBody = 512
+	// maxLastError ограничение длины текста ошибки, как в модели
+	maxLastError = 1024
+	// cleanupInterval период удаления завершенных доставок
+	cleanupInterval = time.Hour
+	// userAgent заголовок User-Agent запросов доставки
+	userAgent = "k8s-go-grpc-react-webhooks/1.0"
+)
+
+// Options параметры доставки
+type Options struct {
+	// Timeout предельное время одного запроса
+	Timeout time.Duration
+	// MaxAttempts число попыток доставки, после которого доставка считается неудачной
+	MaxAttempts int
+	// DisableAfterFailures число неудачных попыток подряд по подписке, после
+	// которого она отключается; 0 - не отключать
+	DisableAfterFailures int
+	// Retention срок хранения завершенных доставок, 0 - бессрочно
+	Retention time.Duration
+	// AllowPrivateNetworks разрешает доставку на адреса внутренней сети,
+	// например при локальной разработке
+	AllowPrivateNetworks bool
+}
+
+// Dispatcher отправляет ожидающие доставки. Несколько экземпляров сервера могут
+// работать одновременно: выбранная доставка откладывается на время запроса,
+// и другие экземпляры ее не берут.
+type Dispatcher struct {
+	repo      repository.WebhookRepository
+	client    *http.Client
+	opts      Options
+	log       *slog.Logger
+	retention atomic.Int64
+
+	deliveries *prometheus.CounterVec
+	disabled   prometheus.Counter
+}
+
+// NewDispatcher создает Dispatcher
+func NewDispatcher(repo repository.WebhookRepository, opts Options, log *slog.Logger) *Dispatcher {
+	d := &Dispatcher{
+		repo: repo,
+		client: &http.Client{
+			Transport: newTransport(opts.AllowPrivateNetworks),
+			Timeout:   opts.Timeout,
+			// Перенаправление считается ошибкой: подпись относится к адресу подписки
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		opts: opts,
+		log:  logger.Component(log, "webhook"),
+		deliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "webhook_delivery_attempts_total",
+			Help: "Количество попыток доставки webhook по результату",
+		}, []string{"result"}),
+		disabled: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "webhook_subscriptions_disabled_total",
+			Help: "Количество подписок webhook, отключенных из-за неудачных доставок",
+		}),
+	}
+	d.UpdateRetention(opts.Retention)
+	return d
+}
+
+// newTransport создает транспорт запросов доставки. Без allowPrivateNetworks
+// соединения с внутренними адресами запрещаются после разрешения имени, а прокси
+// из окружения не используется: через него адрес соединения не проверить.
+func newTransport(allowPrivateNetworks bool) http.RoundTripper {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !allowPrivateNetworks {
+		transport.Proxy = nil
+		transport.DialContext = (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+			Control:   dialControl,
+		}).DialContext
+	}
+	return transport
+}
+
+// Collectors возвращает метрики доставки для регистрации в Prometheus
+func (d *Dispatcher) Collectors() []prometheus.Collector {
+	return []prometheus.Collector{d.deliveries, d.disabled}
+}
+
+// UpdateRetention атомарно заменяет срок хранения завершенных доставок
+func (d *Dispatcher) UpdateRetention(retention time.Duration) {
+	d.retention.Store(int64(retention))
+}
+
+// Run отправляет доставки каждые interval до отмены ctx
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		if _, err := d.Flush(ctx); err != nil && ctx.Err() == nil {
+			d.log.Warn("Ошибка отправки webhook", logger.Err(err))
+		}
+		if time.Since(lastCleanup) >= cleanupInterval {
+			if _, err := d.Cleanup(ctx, time.Now()); err != nil && ctx.Err() == nil {
+				d.log.Warn("Ошибка удаления завершенных доставок webhook", logger.Err(err))
+			}
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Flush отправляет все доставки, срок попытки которых наступил, и возвращает
+// количество успешных
+func (d *Dispatcher) Flush(ctx context.Context) (int, error) {
+	var succeeded atomic.Int64
+	for {
+		// Доставка не выбирается повторно, пока идет запрос, с запасом на сохранение результата
+		deliveries, err := d.repo.ClaimDueDeliveries(ctx, time.Now(), 2*d.opts.Timeout+time.Minute, dispatchBatchSize)
+		if err != nil {
+			return int(succeeded.Load()), err
+		}
+		if len(deliveries) == 0 {
+			return int(succeeded.Load()), nil
+		}
+
+		subscriptions := make(map[uint]*models.WebhookSubscription)
+		lookupErrors := make(map[uint]error)
+		for _, delivery := range deliveries {
+			if _, ok := subscriptions[delivery.SubscriptionID]; ok {
+				continue
+			}
+			if _, ok := lookupErrors[delivery.SubscriptionID]; ok {
+				continue
+			}
+			subscription, err := d.repo.GetSubscription(ctx, delivery.SubscriptionID)
+			if err != nil {
+				lookupErrors[delivery.SubscriptionID] = err
+				continue
+			}
+			subscriptions[delivery.SubscriptionID] = subscription
+		}
+
+		var wg sync.WaitGroup
+		sem := make(chan struct{}, dispatchWorkers)
+		for _, delivery := range deliveries {
+			if err, ok := lookupErrors[delivery.SubscriptionID]; ok {
+				d.skip(ctx, delivery, err)
+				continue
+			}
+			wg.Add(1)
+			sem <- struct{}{}
+			go func(delivery *models.WebhookDelivery) {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if d.attempt(ctx, subscriptions[delivery.SubscriptionID], delivery) {
+					succeeded.Add(1)
+				}
+			}(delivery)
+		}
+		wg.Wait()
+
+		if len(deliveries) < dispatchBatchSize {
+			return int(succeeded.Load()), nil
+		}
+	}
+}
+
+// skip пропускает доставку, подписку которой не удалось получить, не прерывая
+// отправку остальных. Доставка по удаленной подписке завершается неудачей; при
+// другой ошибке она будет выбрана снова, когда истечет срок ее резервирования.
+func (d *Dispatcher) skip(ctx context.Context, delivery *models.WebhookDelivery, err error) {
+	log := d.log.With(
+		"subscription_id", delivery.SubscriptionID,
+		"delivery_id", delivery.ID,
+		"event_id", delivery.EventID,
+	)
+	if !errors.Is(err, repository.ErrNotFound) {
+		log.Warn("Подписка webhook не получена, доставка отложена", logger.Err(err))
+		return
+	}
+
+	d.deliveries.WithLabelValues("failure").Inc()
+	delivery.Status = models.WebhookDeliveryFailed
+	delivery.LastError = truncate(err.Error(), maxLastError)
+	delivery.UpdatedAt = time.Now()
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Error("Результат доставки webhook не сохранен", logger.Err(err))
+		return
+	}
+	log.Warn("Подписка webhook не найдена, доставка завершена неудачей")
+}
+
+// attempt выполняет одну попытку доставки и сохраняет результат
+func (d *Dispatcher) attempt(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery) bool {
+	now := time.Now()
+	statusCode, err := d.send(ctx, subscription, delivery, now)
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = statusCode
+	delivery.UpdatedAt = now
+	log := d.log.With(
+		"subscription_id", subscription.ID,
+		"delivery_id", delivery.ID,
+		"event_id", delivery.EventID,
+		"attempts", delivery.Attempts,
+	)
+
+	if err == nil {
+		d.deliveries.WithLabelValues("success").Inc()
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+		delivery.LastError = ""
+		if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+			log.Error("Результат доставки webhook не сохранен", logger.Err(err))
+		}
+		if subscription.ConsecutiveFailures > 0 {
+			if err := d.repo.ResetSubscriptionFailures(ctx, subscription.ID); err != nil {
+				log.Error("Счетчик ошибок подписки webhook не сброшен", logger.Err(err))
+			}
+		}
+		return true
+	}
+
+	d.deliveries.WithLabelValues("failure").Inc()
+	delivery.LastError = truncate(err.Error(), maxLastError)
+	if delivery.Attempts >= d.opts.MaxAttempts {
+		delivery.Status = models.WebhookDeliveryFailed
+		log.Warn("Доставка webhook не удалась, попытки исчерпаны", logger.Err(err))
+	} else {
+		delivery.NextAttemptAt = now.Add(RetryDelay(delivery.Attempts))
+		log.Info("Доставка webhook не удалась, будет повторена",
+			"next_attempt_at", delivery.NextAttemptAt, logger.Err(err))
+	}
+	if err := d.repo.UpdateDelivery(ctx, delivery); err != nil {
+		log.Error("Результат доставки webhook не сохранен", logger.Err(err))
+	}
+
+	reason := fmt.Sprintf("%d неудачных попыток доставки подряд, последняя: %s", d.opts.DisableAfterFailures, delivery.LastError)
+	disabled, err := d.repo.RecordSubscriptionFailure(ctx, subscription.ID, d.opts.DisableAfterFailures, truncate(reason, 255), now)
+	if err != nil {
+		log.Error("Неудачная доставка webhook не учтена", logger.Err(err))
+	}
+	if disabled {
+		d.disabled.Inc()
+		log.Warn("Подписка webhook отключена: адрес не принимает события", "url", subscription.URL)
+	}
+	return false
+}
+
+// send отправляет запрос и возвращает HTTP статус ответа. Успешны только ответы 2xx.
+func (d *Dispatcher) send(ctx context.Context, subscription *models.WebhookSubscription, delivery *models.WebhookDelivery, now time.Time) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("ошибка создания запроса: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderID, delivery.EventID)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(now.Unix(), 10))
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, now, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("ответ %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return resp.StatusCode, nil
+}
+
+// Cleanup удаляет завершенные доставки, созданные раньше now минус срок хранения
+func (d *Dispatcher) Cleanup(ctx context.Context, now time.Time) (int64, error) {
+	retention := time.Duration(d.retention.Load())
+	if retention <= 0 {
+		return 0, nil
+	}
+
+	deleted, err := d.repo.DeleteDeliveriesBefore(ctx, now.Add(-retention))
+	if err != nil {
+		return 0, err
+	}
+	if deleted > 0 {
+		d.log.Info("Удалены завершенные доставки webhook", "deleted", deleted, "retention", retention.String())
+	}
+	return deleted, nil
+}
+
+// RetryDelay возвращает паузу после неудачной попытки attempt: 30 секунд,
+// 1, 2, 4 минуты и так далее до часа
+func RetryDelay(attempt int) time.Duration {
+	if attempt > 8 {
+		return maxRetryDelay
+	}
+	return min(retryBaseDelay<<max(attempt-1, 0), maxRetryDelay)
+}
+
+// truncate обрезает строку до n байт. Тело ответа может быть не в UTF-8,
+// а PostgreSQL принимает в текстовые поля только корректный UTF-8.
+func truncate(s string, n int) string {
+	if len(s) > n {
+		s = s[:n]
+	}
+	return strings.ToValidUTF8(s, "")
+}
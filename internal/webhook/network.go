@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+// errPrivateNetwork адрес во внутренней сети: доставка туда позволила бы через
+// подписку обращаться к внутренним сервисам и метаданным облака (SSRF)
+var errPrivateNetwork = errors.New("адреса внутренней сети недоступны для доставки")
+
+// reservedPrefixes диапазоны, не покрытые проверками netip.Addr: "эта" сеть,
+// CGNAT, служебные адреса IETF, сеть тестирования производительности и
+// зарезервированный блок, а также устаревшие site-local адреса IPv6
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("fec0::/10"),
+}
+
+// internalSuffixes окончания имен, которые разрешаются только внутри сети или кластера
+var internalSuffixes = []string{".localhost", ".local", ".internal", ".svc", ".cluster.local"}
+
+// publicAddr сообщает, что адрес доступен для доставки: не петлевой, не частный
+// (RFC 1918, fc00::/7), не link-local (169.254.0.0/16 с метаданными облака, fe80::/10),
+// не групповой и не зарезервированный
+func publicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsUnspecified() || addr.IsLoopback() || addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// checkHost проверяет хост адреса подписки: IP адрес должен быть публичным, а имя -
+// полным доменным именем вне внутренних зон. Имя может указывать на внутренний
+// адрес и после проверки, поэтому адрес соединения проверяется еще раз в dialControl.
+func checkHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !publicAddr(addr) {
+			return errPrivateNetwork
+		}
+		return nil
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if name == "localhost" || !strings.Contains(name, ".") {
+		return errPrivateNetwork
+	}
+	for _, suffix := range internalSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return errPrivateNetwork
+		}
+	}
+	return nil
+}
+
+// dialControl запрещает соединения с внутренними адресами. Вызывается после
+// разрешения имени, поэтому защищает и от имен, которые стали указывать на
+// внутренний адрес после создания подписки (DNS rebinding).
+func dialControl(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("адрес соединения %q не разобран: %w", address, err)
+	}
+	if !publicAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errPrivateNetwork, addrPort.Addr())
+	}
+	return nil
+}
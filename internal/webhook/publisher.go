@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+
+	"k8s-go-grpc-react/internal/events"
+	"k8s-go-grpc-react/internal/models"
+	"k8s-go-grpc-react/internal/repository"
+)
+
+// payloadFormat кодирование тела запроса: имена полей как в REST API
+var payloadFormat = protojson.MarshalOptions{UseProtoNames: true}
+
+// Publisher создает доставки по подпискам на событие. Подключается к Relay
+// вместе с другими публикациями через events.Multi. Relay публикует событие вне
+// транзакции и повторяет его, пока не отметит опубликованным, поэтому Publish
+// может вызываться для одного события несколько раз (доставка не реже одного раза).
+// Повторных доставок при этом не появляется: уникальный индекс по подписке и
+// событию вместе с ON CONFLICT DO NOTHING в CreateDelivery пропускает уже созданные.
+type Publisher struct {
+	repo repository.WebhookRepository
+}
+
+// NewPublisher создает Publisher
+func NewPublisher(repo repository.WebhookRepository) *Publisher {
+	return &Publisher{repo: repo}
+}
+
+// Publish создает доставки события по включенным подпискам на его тип
+func (p *Publisher) Publish(ctx context.Context, msg events.Message) error {
+	subscriptions, err := p.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, subscription := range subscriptions {
+		if !subscription.Enabled || !subscription.Subscribed(msg.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = payloadFormat.Marshal(msg.Event); err != nil {
+				return fmt.Errorf("ошибка кодирования события %s: %w", msg.ID, err)
+			}
+		}
+
+		err := p.repo.CreateDelivery(ctx, &models.WebhookDelivery{
+			SubscriptionID: subscription.ID,
+			EventID:        msg.ID,
+			EventType:      msg.Type,
+			UserID:         uint(msg.Event.GetUserId()),
+			Payload:        payload,
+			Anonymous:      events.Anonymous(msg.Event),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close ничего не делает
+func (p *Publisher) Close() error {
+	return nil
+}
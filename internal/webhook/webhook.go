@@ -0,0 +1,120 @@
+// Package webhook доставляет события пользователей внешним системам по HTTP.
+// Администратор создает подписку с адресом, типами событий и секретом; Publisher
+// получает события из outbox (см. internal/events) и создает доставки по
+// подпискам, Dispatcher отправляет их POST запросами с HMAC-SHA256 подписью,
+// повторяет неудачные с экспоненциальной паузой и отключает подписку, если
+// адрес раз за разом не принимает события.
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Заголовки запроса доставки
+const (
+	// HeaderID идентификатор события, одинаковый во всех попытках доставки;
+	// получатель отбрасывает повторы по нему
+	HeaderID = "X-Webhook-Id"
+	// HeaderEvent тип события, например user.created
+	HeaderEvent = "X-Webhook-Event"
+	// HeaderTimestamp Unix время отправки, входит в подпись
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	// HeaderSignature подпись "sha256=<hex>" от "<timestamp>.<тело запроса>"
+	HeaderSignature = "X-Webhook-Signature"
+
+	// signaturePrefix алгоритм подписи в заголовке
+	signaturePrefix = "sha256="
+	// secretBytes длина генерируемого секрета в байтах
+	secretBytes = 32
+	// MinSecretLength наименьшая длина секрета, заданного администратором
+	MinSecretLength = 16
+)
+
+// Sign возвращает значение заголовка HeaderSignature. Время входит в подпись,
+// чтобы перехваченный запрос нельзя было повторить позже.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp.Unix(), 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify проверяет подпись запроса на стороне получателя: подпись должна совпадать,
+// а время отправки отличаться от now не больше чем на tolerance
+func Verify(secret, signature, timestamp string, body []byte, now time.Time, tolerance time.Duration) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	sent := time.Unix(unix, 0)
+	if now.Sub(sent) > tolerance || sent.Sub(now) > tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, sent, body)))
+}
+
+// GenerateSecret создает случайный секрет подписки
+func GenerateSecret() (string, error) {
+	b := make([]byte, secretBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ValidateURL проверяет адрес подписки: абсолютный http или https адрес без учетных
+// данных. Если allowPrivateNetworks не задан, хост не может быть адресом или именем
+// внутренней сети.
+func ValidateURL(raw string, allowPrivateNetworks bool) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return errors.New("адрес не разобран")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("адрес должен начинаться с http:// или https://")
+	}
+	if u.Host == "" {
+		return errors.New("в адресе не указан хост")
+	}
+	if u.User != nil {
+		return errors.New("адрес не должен содержать учетные данные, для проверки отправителя используется подпись")
+	}
+	if len(raw) > 2048 {
+		return errors.New("адрес длиннее 2048 символов")
+	}
+	if !allowPrivateNetworks {
+		return checkHost(u.Hostname())
+	}
+	return nil
+}
+
+// NormalizeTypes проверяет типы событий по списку known и убирает повторы
+func NormalizeTypes(types, known []string) ([]string, error) {
+	if len(types) == 0 {
+		return nil, errors.New("не указаны типы событий")
+	}
+	seen := make(map[string]bool, len(types))
+	var result []string
+	for _, eventType := range types {
+		eventType = strings.TrimSpace(eventType)
+		if !slices.Contains(known, eventType) {
+			return nil, fmt.Errorf("неизвестный тип события %q, допустимы: %s", eventType, strings.Join(known, ", "))
+		}
+		if !seen[eventType] {
+			seen[eventType] = true
+			result = append(result, eventType)
+		}
+	}
+	return result, nil
+}
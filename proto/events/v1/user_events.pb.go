@@ -0,0 +1,773 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: proto/events/v1/user_events.proto
+
+// Схема событий жизненного цикла пользователей для других сервисов.
+// Несовместимые изменения выпускаются в новом пакете (user.events.v2),
+// в пределах v1 поля только добавляются.
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Состояние пользователя после изменения
+type UserSnapshot struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Email         string                 `protobuf:"bytes,3,opt,name=email,proto3" json:"email,omitempty"`
+	Role          string                 `protobuf:"bytes,4,opt,name=role,proto3" json:"role,omitempty"`
+	IsActive      bool                   `protobuf:"varint,5,opt,name=is_active,json=isActive,proto3" json:"is_active,omitempty"`
+	Version       int64                  `protobuf:"varint,6,opt,name=version,proto3" json:"version,omitempty"` // Совпадает с etag пользователя
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserSnapshot) Reset() {
+	*x = UserSnapshot{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserSnapshot) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserSnapshot) ProtoMessage() {}
+
+func (x *UserSnapshot) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserSnapshot.ProtoReflect.Descriptor instead.
+func (*UserSnapshot) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *UserSnapshot) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *UserSnapshot) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *UserSnapshot) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *UserSnapshot) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *UserSnapshot) GetIsActive() bool {
+	if x != nil {
+		return x.IsActive
+	}
+	return false
+}
+
+func (x *UserSnapshot) GetVersion() int64 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+// Изменение поля пользователя
+type FieldChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Field         string                 `protobuf:"bytes,1,opt,name=field,proto3" json:"field,omitempty"`
+	OldValue      string                 `protobuf:"bytes,2,opt,name=old_value,json=oldValue,proto3" json:"old_value,omitempty"`
+	NewValue      string                 `protobuf:"bytes,3,opt,name=new_value,json=newValue,proto3" json:"new_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FieldChange) Reset() {
+	*x = FieldChange{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FieldChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FieldChange) ProtoMessage() {}
+
+func (x *FieldChange) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FieldChange.ProtoReflect.Descriptor instead.
+func (*FieldChange) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *FieldChange) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *FieldChange) GetOldValue() string {
+	if x != nil {
+		return x.OldValue
+	}
+	return ""
+}
+
+func (x *FieldChange) GetNewValue() string {
+	if x != nil {
+		return x.NewValue
+	}
+	return ""
+}
+
+// Пользователь создан: регистрация или создание администратором
+type UserCreated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserSnapshot          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserCreated) Reset() {
+	*x = UserCreated{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserCreated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserCreated) ProtoMessage() {}
+
+func (x *UserCreated) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserCreated.ProtoReflect.Descriptor instead.
+func (*UserCreated) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UserCreated) GetUser() *UserSnapshot {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+// Пользователь изменен
+type UserUpdated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserSnapshot          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Changes       []*FieldChange         `protobuf:"bytes,2,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserUpdated) Reset() {
+	*x = UserUpdated{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserUpdated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserUpdated) ProtoMessage() {}
+
+func (x *UserUpdated) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserUpdated.ProtoReflect.Descriptor instead.
+func (*UserUpdated) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *UserUpdated) GetUser() *UserSnapshot {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserUpdated) GetChanges() []*FieldChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+// Пользователь заблокирован
+type UserDeactivated struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserSnapshot          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	Changes       []*FieldChange         `protobuf:"bytes,2,rep,name=changes,proto3" json:"changes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserDeactivated) Reset() {
+	*x = UserDeactivated{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserDeactivated) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDeactivated) ProtoMessage() {}
+
+func (x *UserDeactivated) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDeactivated.ProtoReflect.Descriptor instead.
+func (*UserDeactivated) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *UserDeactivated) GetUser() *UserSnapshot {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+func (x *UserDeactivated) GetChanges() []*FieldChange {
+	if x != nil {
+		return x.Changes
+	}
+	return nil
+}
+
+// Пользователь удален
+type UserDeleted struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserDeleted) Reset() {
+	*x = UserDeleted{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserDeleted) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserDeleted) ProtoMessage() {}
+
+func (x *UserDeleted) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserDeleted.ProtoReflect.Descriptor instead.
+func (*UserDeleted) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UserDeleted) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// Удаленный пользователь восстановлен
+type UserRestored struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	User          *UserSnapshot          `protobuf:"bytes,1,opt,name=user,proto3" json:"user,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserRestored) Reset() {
+	*x = UserRestored{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserRestored) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserRestored) ProtoMessage() {}
+
+func (x *UserRestored) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserRestored.ProtoReflect.Descriptor instead.
+func (*UserRestored) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UserRestored) GetUser() *UserSnapshot {
+	if x != nil {
+		return x.User
+	}
+	return nil
+}
+
+// Персональные данные пользователя удалены по его запросу. Получатели удаляют
+// имя, email и другие данные пользователя из своих копий.
+type UserErased struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int32                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserErased) Reset() {
+	*x = UserErased{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserErased) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserErased) ProtoMessage() {}
+
+func (x *UserErased) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserErased.ProtoReflect.Descriptor instead.
+func (*UserErased) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserErased) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+// Конверт события. Доставка "хотя бы один раз": получатель отбрасывает повторы по id.
+type UserEvent struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	Id         string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`     // Ключ идемпотентности, UUID
+	Type       string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"` // Например, user.created
+	OccurredAt *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	UserId     int32                  `protobuf:"varint,4,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`         // Ключ упорядочивания: события одного пользователя публикуются по порядку
+	RequestId  string                 `protobuf:"bytes,5,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"` // Идентификатор запроса, вызвавшего изменение
+	// Types that are valid to be assigned to Payload:
+	//
+	//	*UserEvent_Created
+	//	*UserEvent_Updated
+	//	*UserEvent_Deactivated
+	//	*UserEvent_Deleted
+	//	*UserEvent_Restored
+	//	*UserEvent_Erased
+	Payload       isUserEvent_Payload `protobuf_oneof:"payload"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserEvent) Reset() {
+	*x = UserEvent{}
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserEvent) ProtoMessage() {}
+
+func (x *UserEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_events_v1_user_events_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserEvent.ProtoReflect.Descriptor instead.
+func (*UserEvent) Descriptor() ([]byte, []int) {
+	return file_proto_events_v1_user_events_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *UserEvent) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UserEvent) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *UserEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *UserEvent) GetUserId() int32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UserEvent) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *UserEvent) GetPayload() isUserEvent_Payload {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *UserEvent) GetCreated() *UserCreated {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Created); ok {
+			return x.Created
+		}
+	}
+	return nil
+}
+
+func (x *UserEvent) GetUpdated() *UserUpdated {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Updated); ok {
+			return x.Updated
+		}
+	}
+	return nil
+}
+
+func (x *UserEvent) GetDeactivated() *UserDeactivated {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Deactivated); ok {
+			return x.Deactivated
+		}
+	}
+	return nil
+}
+
+func (x *UserEvent) GetDeleted() *UserDeleted {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Deleted); ok {
+			return x.Deleted
+		}
+	}
+	return nil
+}
+
+func (x *UserEvent) GetRestored() *UserRestored {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Restored); ok {
+			return x.Restored
+		}
+	}
+	return nil
+}
+
+func (x *UserEvent) GetErased() *UserErased {
+	if x != nil {
+		if x, ok := x.Payload.(*UserEvent_Erased); ok {
+			return x.Erased
+		}
+	}
+	return nil
+}
+
+type isUserEvent_Payload interface {
+	isUserEvent_Payload()
+}
+
+type UserEvent_Created struct {
+	Created *UserCreated `protobuf:"bytes,10,opt,name=created,proto3,oneof"`
+}
+
+type UserEvent_Updated struct {
+	Updated *UserUpdated `protobuf:"bytes,11,opt,name=updated,proto3,oneof"`
+}
+
+type UserEvent_Deactivated struct {
+	Deactivated *UserDeactivated `protobuf:"bytes,12,opt,name=deactivated,proto3,oneof"`
+}
+
+type UserEvent_Deleted struct {
+	Deleted *UserDeleted `protobuf:"bytes,13,opt,name=deleted,proto3,oneof"`
+}
+
+type UserEvent_Restored struct {
+	Restored *UserRestored `protobuf:"bytes,14,opt,name=restored,proto3,oneof"`
+}
+
+type UserEvent_Erased struct {
+	Erased *UserErased `protobuf:"bytes,15,opt,name=erased,proto3,oneof"`
+}
+
+func (*UserEvent_Created) isUserEvent_Payload() {}
+
+func (*UserEvent_Updated) isUserEvent_Payload() {}
+
+func (*UserEvent_Deactivated) isUserEvent_Payload() {}
+
+func (*UserEvent_Deleted) isUserEvent_Payload() {}
+
+func (*UserEvent_Restored) isUserEvent_Payload() {}
+
+func (*UserEvent_Erased) isUserEvent_Payload() {}
+
+var File_proto_events_v1_user_events_proto protoreflect.FileDescriptor
+
+const file_proto_events_v1_user_events_proto_rawDesc = "" +
+	"\n" +
+	"!proto/events/v1/user_events.proto\x12\x0euser.events.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x93\x01\n" +
+	"\fUserSnapshot\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x14\n" +
+	"\x05email\x18\x03 \x01(\tR\x05email\x12\x12\n" +
+	"\x04role\x18\x04 \x01(\tR\x04role\x12\x1b\n" +
+	"\tis_active\x18\x05 \x01(\bR\bisActive\x12\x18\n" +
+	"\aversion\x18\x06 \x01(\x03R\aversion\"]\n" +
+	"\vFieldChange\x12\x14\n" +
+	"\x05field\x18\x01 \x01(\tR\x05field\x12\x1b\n" +
+	"\told_value\x18\x02 \x01(\tR\boldValue\x12\x1b\n" +
+	"\tnew_value\x18\x03 \x01(\tR\bnewValue\"?\n" +
+	"\vUserCreated\x120\n" +
+	"\x04user\x18\x01 \x01(\v2\x1c.user.events.v1.UserSnapshotR\x04user\"v\n" +
+	"\vUserUpdated\x120\n" +
+	"\x04user\x18\x01 \x01(\v2\x1c.user.events.v1.UserSnapshotR\x04user\x125\n" +
+	"\achanges\x18\x02 \x03(\v2\x1b.user.events.v1.FieldChangeR\achanges\"z\n" +
+	"\x0fUserDeactivated\x120\n" +
+	"\x04user\x18\x01 \x01(\v2\x1c.user.events.v1.UserSnapshotR\x04user\x125\n" +
+	"\achanges\x18\x02 \x03(\v2\x1b.user.events.v1.FieldChangeR\achanges\"&\n" +
+	"\vUserDeleted\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x05R\x06userId\"@\n" +
+	"\fUserRestored\x120\n" +
+	"\x04user\x18\x01 \x01(\v2\x1c.user.events.v1.UserSnapshotR\x04user\"%\n" +
+	"\n" +
+	"UserErased\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x05R\x06userId\"\x91\x04\n" +
+	"\tUserEvent\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12;\n" +
+	"\voccurred_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12\x17\n" +
+	"\auser_id\x18\x04 \x01(\x05R\x06userId\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x05 \x01(\tR\trequestId\x127\n" +
+	"\acreated\x18\n" +
+	" \x01(\v2\x1b.user.events.v1.UserCreatedH\x00R\acreated\x127\n" +
+	"\aupdated\x18\v \x01(\v2\x1b.user.events.v1.UserUpdatedH\x00R\aupdated\x12C\n" +
+	"\vdeactivated\x18\f \x01(\v2\x1f.user.events.v1.UserDeactivatedH\x00R\vdeactivated\x127\n" +
+	"\adeleted\x18\r \x01(\v2\x1b.user.events.v1.UserDeletedH\x00R\adeleted\x12:\n" +
+	"\brestored\x18\x0e \x01(\v2\x1c.user.events.v1.UserRestoredH\x00R\brestored\x124\n" +
+	"\x06erased\x18\x0f \x01(\v2\x1a.user.events.v1.UserErasedH\x00R\x06erasedB\t\n" +
+	"\apayloadB,Z*k8s-go-grpc-react/proto/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_proto_events_v1_user_events_proto_rawDescOnce sync.Once
+	file_proto_events_v1_user_events_proto_rawDescData []byte
+)
+
+func file_proto_events_v1_user_events_proto_rawDescGZIP() []byte {
+	file_proto_events_v1_user_events_proto_rawDescOnce.Do(func() {
+		file_proto_events_v1_user_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_proto_events_v1_user_events_proto_rawDesc), len(file_proto_events_v1_user_events_proto_rawDesc)))
+	})
+	return file_proto_events_v1_user_events_proto_rawDescData
+}
+
+var file_proto_events_v1_user_events_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_proto_events_v1_user_events_proto_goTypes = []any{
+	(*UserSnapshot)(nil),          // 0: user.events.v1.UserSnapshot
+	(*FieldChange)(nil),           // 1: user.events.v1.FieldChange
+	(*UserCreated)(nil),           // 2: user.events.v1.UserCreated
+	(*UserUpdated)(nil),           // 3: user.events.v1.UserUpdated
+	(*UserDeactivated)(nil),       // 4: user.events.v1.UserDeactivated
+	(*UserDeleted)(nil),           // 5: user.events.v1.UserDeleted
+	(*UserRestored)(nil),          // 6: user.events.v1.UserRestored
+	(*UserErased)(nil),            // 7: user.events.v1.UserErased
+	(*UserEvent)(nil),             // 8: user.events.v1.UserEvent
+	(*timestamppb.Timestamp)(nil), // 9: google.protobuf.Timestamp
+}
+var file_proto_events_v1_user_events_proto_depIdxs = []int32{
+	0,  // 0: user.events.v1.UserCreated.user:type_name -> user.events.v1.UserSnapshot
+	0,  // 1: user.events.v1.UserUpdated.user:type_name -> user.events.v1.UserSnapshot
+	1,  // 2: user.events.v1.UserUpdated.changes:type_name -> user.events.v1.FieldChange
+	0,  // 3: user.events.v1.UserDeactivated.user:type_name -> user.events.v1.UserSnapshot
+	1,  // 4: user.events.v1.UserDeactivated.changes:type_name -> user.events.v1.FieldChange
+	0,  // 5: user.events.v1.UserRestored.user:type_name -> user.events.v1.UserSnapshot
+	9,  // 6: user.events.v1.UserEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	2,  // 7: user.events.v1.UserEvent.created:type_name -> user.events.v1.UserCreated
+	3,  // 8: user.events.v1.UserEvent.updated:type_name -> user.events.v1.UserUpdated
+	4,  // 9: user.events.v1.UserEvent.deactivated:type_name -> user.events.v1.UserDeactivated
+	5,  // 10: user.events.v1.UserEvent.deleted:type_name -> user.events.v1.UserDeleted
+	6,  // 11: user.events.v1.UserEvent.restored:type_name -> user.events.v1.UserRestored
+	7,  // 12: user.events.v1.UserEvent.erased:type_name -> user.events.v1.UserErased
+	13, // [13:13] is the sub-list for method output_type
+	13, // [13:13] is the sub-list for method input_type
+	13, // [13:13] is the sub-list for extension type_name
+	13, // [13:13] is the sub-list for extension extendee
+	0,  // [0:13] is the sub-list for field type_name
+}
+
+func init() { file_proto_events_v1_user_events_proto_init() }
+func file_proto_events_v1_user_events_proto_init() {
+	if File_proto_events_v1_user_events_proto != nil {
+		return
+	}
+	file_proto_events_v1_user_events_proto_msgTypes[8].OneofWrappers = []any{
+		(*UserEvent_Created)(nil),
+		(*UserEvent_Updated)(nil),
+		(*UserEvent_Deactivated)(nil),
+		(*UserEvent_Deleted)(nil),
+		(*UserEvent_Restored)(nil),
+		(*UserEvent_Erased)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_events_v1_user_events_proto_rawDesc), len(file_proto_events_v1_user_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_proto_events_v1_user_events_proto_goTypes,
+		DependencyIndexes: file_proto_events_v1_user_events_proto_depIdxs,
+		MessageInfos:      file_proto_events_v1_user_events_proto_msgTypes,
+	}.Build()
+	File_proto_events_v1_user_events_proto = out.File
+	file_proto_events_v1_user_events_proto_goTypes = nil
+	file_proto_events_v1_user_events_proto_depIdxs = nil
+}
@@ -12,4 +12,13 @@ mkdir -p proto
     --grpc-gateway_out=. --grpc-gateway_opt=paths=source_relative \
     proto/user.proto
 
+# Схема событий пользователей для других сервисов (только сообщения)
+/opt/homebrew/bin/protoc \
+    --proto_path=. \
+    --go_out=. --go_opt=paths=source_relative \
+    proto/events/v1/user_events.proto
+
+# Обновляем спецификацию OpenAPI, иначе тест расхождения с proto упадет
+go generate ./internal/openapi
+
 echo "Protobuf файлы успешно сгенерированы!" 
\ No newline at end of file
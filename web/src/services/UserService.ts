@@ -76,9 +76,16 @@ class UserService {
         // Если не удалось получить JSON, используем базовое сообщение
       }
       
+      // Идентификатор запроса показывается пользователю, чтобы поддержка нашла запрос в логах
+      const requestId = response.headers.get('X-Request-ID') || undefined;
+      if (requestId) {
+        errorMessage = `${errorMessage} (ID запроса: ${requestId})`;
+      }
+
       const apiError: ApiError = {
         message: errorMessage,
-        status: response.status
+        status: response.status,
+        requestId
       };
       throw apiError;
     }
@@ -87,11 +94,13 @@ class UserService {
 
   // Авторизация
   async register(request: RegisterRequest): Promise<AuthResponse> {
+    // Ответ устанавливает cookie сессии для входа во внутренние приложения через OIDC
     const response = await fetch(`${API_BASE}/auth/register`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
       },
+      credentials: 'include',
       body: JSON.stringify(request),
     });
     
@@ -108,11 +117,13 @@ class UserService {
   }
 
   async login(request: LoginRequest): Promise<AuthResponse> {
+    // Ответ устанавливает cookie сессии для входа во внутренние приложения через OIDC
     const response = await fetch(`${API_BASE}/auth/login`, {
       method: 'POST',
       headers: {
         'Content-Type': 'application/json',
       },
+      credentials: 'include',
       body: JSON.stringify(request),
     });
     
@@ -129,6 +140,8 @@ class UserService {
   }
 
   logout(): void {
+    // Cookie сессии удаляет сервер; ошибка не мешает выйти локально
+    fetch(`${API_BASE}/auth/logout`, { method: 'POST', credentials: 'include' }).catch(() => undefined);
     localStorage.removeItem('authToken');
     localStorage.removeItem('currentUser');
     localStorage.removeItem('loginTime');
@@ -5,6 +5,7 @@ export interface User {
   role: string;
   is_active: boolean;
   created_at: number;
+  etag?: string; // Версия для условного изменения (заголовок If-Match)
 }
 
 export interface CreateUserRequest {
@@ -50,4 +51,5 @@ export interface ListUsersResponse {
 export interface ApiError {
   message: string;
   status?: number;
+  requestId?: string;
 } 
\ No newline at end of file